@@ -12,9 +12,9 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -27,6 +27,9 @@ import (
 // 全局错误日志记录器
 var errorLogger *log.Logger
 
+// 当前使用的上报目标，随采集数据一起上报
+var activeEndpoint atomic.Pointer[collector.ReportEndpoint]
+
 const maxRegisterRetries = 3
 const registerRetryInterval = 5 * time.Second
 
@@ -65,7 +68,16 @@ func main() {
 	if err != nil {
 		errorLogger.Printf("无法加载配置文件，使用默认配置: %v\n", err)
 		// 创建默认配置
+		on := true
 		agentConfig = &config.AgentConfig{
+			Collection: config.CollectionConfig{
+				Enabled: config.EnabledCollector{
+					CPU:     &on,
+					Memory:  &on,
+					Disk:    &on,
+					Network: &on,
+				},
+			},
 			Security: config.SecurityConfig{
 				Encryption: config.EncryptionConfig{
 					Enabled:   false,
@@ -90,22 +102,84 @@ func main() {
 	// systemCollector := collector.NewSystemCollector()
 	systemCollector := collector.NewParallelCollector(
 		collector.WithMountPoints(agentConfig.Collection.Disk.MountPoints),
+		collector.WithFilesystemOptions(collector.FilesystemOptions{
+			IncludeFSTypes:  agentConfig.Collection.Disk.IncludeFSTypes,
+			ExcludeFSTypes:  agentConfig.Collection.Disk.ExcludeFSTypes,
+			IncludeMounts:   agentConfig.Collection.Disk.IncludeMounts,
+			ExcludeMounts:   agentConfig.Collection.Disk.ExcludeMounts,
+			IncludeInactive: agentConfig.Collection.Disk.IncludeInactive,
+		}),
 		collector.WithInterfaces(agentConfig.Collection.Network.Interfaces),
+		collector.WithDiskDevices(agentConfig.Collection.Disk.Devices),
+		collector.WithProcRoot(agentConfig.Collection.ProcRoot),
+		collector.WithSysRoot(agentConfig.Collection.SysRoot),
+		collector.WithInventoryInterval(time.Duration(agentConfig.Collection.Hardware.Interval)*time.Second),
+		collector.WithProcesses(collector.ProcessOptions{
+			Enabled:      agentConfig.Collection.Enabled.Processes,
+			CollectAll:   agentConfig.Collection.Process.CollectAll,
+			Targets:      agentConfig.Collection.Process.TargetProcesses,
+			MaxProcesses: agentConfig.Collection.Process.MaxProcesses,
+			IncludeArgs:  agentConfig.Collection.Process.IncludeArgs,
+		}),
+		collector.WithCgroups(collector.CgroupOptions{
+			Enabled:        agentConfig.Collection.Enabled.Cgroups,
+			Root:           agentConfig.Collection.Cgroup.Root,
+			MaxDepth:       agentConfig.Collection.Cgroup.MaxDepth,
+			MaxCgroups:     agentConfig.Collection.Cgroup.MaxCgroups,
+			Include:        agentConfig.Collection.Cgroup.Include,
+			RuntimeSockets: agentConfig.Collection.Cgroup.RuntimeSockets,
+		}),
+		collector.WithTimeSync(agentConfig.Collection.Enabled.TimeSync),
+		collector.WithSystemd(collector.SystemdOptions{
+			Enabled:       agentConfig.Collection.Enabled.Systemd,
+			Units:         agentConfig.Collection.Systemd.Units,
+			IncludeFailed: agentConfig.Collection.Systemd.IncludeFailed,
+		}),
+		collector.WithListeners(agentConfig.Collection.Enabled.Listeners),
+		collector.WithSensors(agentConfig.Collection.Enabled.Sensors),
+		collector.WithPlugins(buildPluginConfigs(agentConfig.Collection.Plugins)),
+		collector.WithScrape(buildScrapeOptions(agentConfig.Collection.Scrape)),
+		collector.WithProbes(buildProbeConfigs(agentConfig.Collection.Probes)),
+		collector.WithLogWatch(buildLogWatchOptions(agentConfig.Collection.Logs)),
+		collector.WithCertificates(collector.CertificateOptions{
+			Paths:     agentConfig.Collection.Certificates.Paths,
+			Endpoints: agentConfig.Collection.Certificates.Endpoints,
+			Interval:  time.Duration(agentConfig.Collection.Certificates.Interval) * time.Millisecond,
+			Timeout:   time.Duration(agentConfig.Collection.Certificates.Timeout) * time.Millisecond,
+		}),
+		collector.WithIntegrity(collector.IntegrityOptions{
+			Paths:     agentConfig.Collection.Integrity.Paths,
+			Interval:  time.Duration(agentConfig.Collection.Integrity.Interval) * time.Millisecond,
+			StateFile: agentConfig.Collection.Integrity.StateFile,
+		}),
+		collector.WithCollectorSettings(buildCollectorSettings(&agentConfig.Collection)),
 	)
 	log.Println("系统指标收集器初始化完成(并行收集模式)")
-	log.Printf("监控磁盘挂载点: %v", agentConfig.Collection.Disk.MountPoints)
+	if len(agentConfig.Collection.Disk.MountPoints) == 0 {
+		log.Printf("监控磁盘挂载点: 自动发现")
+	} else {
+		log.Printf("监控磁盘挂载点: %v", agentConfig.Collection.Disk.MountPoints)
+	}
 	log.Printf("监控网络接口: %v", agentConfig.Collection.Network.Interfaces)
+	log.Printf("进程采集: %v, 重点监控进程: %v", agentConfig.Collection.Enabled.Processes, agentConfig.Collection.Process.TargetProcesses)
+	for _, name := range systemCollector.Registry().Names() {
+		if settings, ok := systemCollector.Registry().Settings(name); ok {
+			log.Printf("子收集器 %s: 启用=%v, 间隔=%v, 超时=%v", name, settings.Enabled, settings.Interval, settings.Timeout)
+		}
+	}
 
 	// 如果不是调试模式，则初始化上报模块
 	var metricsReporter reporter.Reporter
+	var httpReporter *reporter.HTTPReporter
+	var wsChannel *reporter.WebSocketChannel
 	var serverURL string
 	var nodeID string
 	var agentToken string
 
 	if !*debug {
 		// 初始化数据上报模块
-		// var serverURL string // 移除内部声明
-		// 优先使用命令行参数，否则使用配置文件
+		// 优先使用命令行参数，否则使用配置文件中按优先级排列的聚合服务器和主控服务器
+		var endpoints []reporter.Endpoint
 		if *serverAddr != "localhost:8080" {
 			// 检查serverAddr是否已包含协议前缀
 			if strings.HasPrefix(*serverAddr, "http://") || strings.HasPrefix(*serverAddr, "https://") {
@@ -113,16 +187,13 @@ func main() {
 			} else {
 				serverURL = "http://" + *serverAddr
 			}
-		} else if agentConfig.Aggregator.Enabled && agentConfig.Aggregator.URL != "" {
-			// 如果启用了聚合服务器，优先使用聚合服务器地址
-			serverURL = agentConfig.Aggregator.URL
-			agentToken = agentConfig.Aggregator.AuthToken // 获取用于注册的 token
-		} else if agentConfig.Server.URL != "" {
-			serverURL = agentConfig.Server.URL
-			// agentToken = agentConfig.Server.Token // 如果直连主控，理论上也需要token，配置文件里目前没有
+			endpoints = []reporter.Endpoint{{URL: serverURL, Kind: reporter.EndpointServer}}
 		} else {
-			serverURL = "http://localhost:8080"
-			// agentToken 保持空
+			endpoints = reportEndpoints(agentConfig)
+			serverURL = endpoints[0].URL
+			if agentConfig.Aggregator.Enabled {
+				agentToken = agentConfig.Aggregator.AuthToken // 获取用于注册的 token
+			}
 		}
 
 		// 获取主机名作为节点ID
@@ -141,14 +212,82 @@ func main() {
 		}
 
 		// 创建HTTP上报器并附加安全配置
-		httpReporter := reporter.NewHTTPReporter(
-			serverURL,
-			nodeID,
+		reporterOptions := []func(*reporter.HTTPReporter){
 			reporter.WithRetryCount(agentConfig.Server.RetryCount),
-			reporter.WithRetryInterval(time.Duration(agentConfig.Server.RetryInterval)*time.Second),
-			reporter.WithTimeout(time.Duration(getAppropriateTimeout(agentConfig, serverURL))*time.Second),
+			reporter.WithRetryInterval(time.Duration(agentConfig.Server.RetryInterval) * time.Second),
+			reporter.WithMaxRetryInterval(time.Duration(agentConfig.Server.MaxRetryInterval) * time.Second),
+			reporter.WithCircuitBreaker(agentConfig.Server.CircuitBreaker.FailureThreshold,
+				time.Duration(agentConfig.Server.CircuitBreaker.Cooldown)*time.Second),
+			reporter.WithAsync(agentConfig.Server.QueueSize),
+			reporter.WithTimeout(time.Duration(getAppropriateTimeout(agentConfig, serverURL)) * time.Second),
+			reporter.WithEndpoints(endpoints),
+			reporter.WithHealthCheckInterval(time.Duration(agentConfig.Server.HealthCheckInterval) * time.Second),
+			reporter.WithEndpointChange(func(ep reporter.Endpoint, priority int) {
+				activeEndpoint.Store(&collector.ReportEndpoint{URL: ep.URL, Kind: ep.Kind, Priority: priority})
+				// 切换到的聚合服务器可能尚未注册该节点
+				if ep.Kind == reporter.EndpointAggregator && priority > 0 && agentToken != "" {
+					go func() {
+						if err := registerAgentWithAggregator(ep.URL, nodeID, agentToken, systemCollector.HardwareInventory()); err != nil {
+							errorLogger.Printf("向聚合服务器 %s 注册失败: %v", ep.URL, err)
+						}
+					}()
+				}
+			}),
 			reporter.WithSecurityConfig(&agentConfig.Security),
-		)
+		}
+		if agentConfig.Spool.Enabled {
+			spool, err := reporter.OpenSpool(reporter.SpoolOptions{
+				Dir:         agentConfig.Spool.Dir,
+				MaxBytes:    int64(agentConfig.Spool.MaxSize) * 1024 * 1024,
+				MaxAge:      time.Duration(agentConfig.Spool.MaxAge) * time.Second,
+				ReplayBatch: agentConfig.Spool.ReplayBatch,
+			})
+			if err != nil {
+				errorLogger.Printf("打开本地缓冲失败，上报失败的数据将被丢弃: %v", err)
+			} else {
+				reporterOptions = append(reporterOptions, reporter.WithSpool(spool))
+				log.Printf("本地缓冲已启用，目录: %s，待补发数据: %d 条", agentConfig.Spool.Dir, spool.Len())
+			}
+		}
+		if agentConfig.Batch.Enabled {
+			reporterOptions = append(reporterOptions, reporter.WithBatch(reporter.BatchOptions{
+				MaxSamples: agentConfig.Batch.MaxSamples,
+				MaxDelay:   time.Duration(agentConfig.Batch.MaxDelay) * time.Millisecond,
+				Delta:      agentConfig.Batch.Delta,
+			}))
+			log.Printf("批量上报已启用，每批最多 %d 个样本，最长等待 %d 毫秒，增量编码: %v",
+				agentConfig.Batch.MaxSamples, agentConfig.Batch.MaxDelay, agentConfig.Batch.Delta)
+		}
+		// 启用WebSocket通道时优先通过与主控服务器的长连接上报，连接不可用时仍通过HTTP上报
+		if agentConfig.Server.WebSocket.Enabled {
+			wsServer := ""
+			for _, ep := range endpoints {
+				if ep.Kind == reporter.EndpointServer {
+					wsServer = ep.URL
+					break
+				}
+			}
+			ws, err := reporter.NewWebSocketChannel(wsServer, nodeID, reporter.WebSocketOptions{
+				AuthToken:    agentConfig.Server.Token,
+				Security:     &agentConfig.Security,
+				PingInterval: time.Duration(agentConfig.Server.WebSocket.PingInterval) * time.Second,
+				WriteTimeout: time.Duration(agentConfig.Server.Timeout) * time.Second,
+				AckTimeout:   time.Duration(agentConfig.Server.Timeout) * time.Second,
+				OnConfig: func(nodeConfig map[string]interface{}) {
+					applyProcessMonitoring(nodeConfig, systemCollector)
+					applyProbes(nodeConfig, systemCollector)
+					applySystemdUnits(nodeConfig, systemCollector)
+				},
+			})
+			if err != nil {
+				errorLogger.Printf("创建WebSocket上报通道失败，使用HTTP上报: %v", err)
+			} else {
+				wsChannel = ws
+				reporterOptions = append(reporterOptions, reporter.WithWebSocket(wsChannel))
+				log.Printf("WebSocket上报通道已启用，主控服务器: %s", wsServer)
+			}
+		}
+		httpReporter = reporter.NewHTTPReporter(serverURL, nodeID, reporterOptions...)
 
 		// 如果启用了聚合服务器，设置认证令牌 (这个是用于上报指标的，注册时用 agentToken)
 		// if agentConfig.Aggregator.Enabled && agentConfig.Aggregator.AuthToken != "" {
@@ -156,6 +295,9 @@ func main() {
 		// }
 
 		metricsReporter = httpReporter
+		for i, ep := range endpoints {
+			log.Printf("上报目标 %d: %s (%s)", i, ep.URL, ep.Kind)
+		}
 		log.Printf("数据上报模块初始化完成，目标服务器: %s\n", serverURL)
 
 		// 日志安全配置状态
@@ -173,10 +315,10 @@ func main() {
 
 		// --- 添加注册逻辑 ---
 		registrationSuccessful := false
-		if agentConfig.Aggregator.Enabled {
+		if endpoints[0].Kind == reporter.EndpointAggregator {
 			if agentToken != "" {
 				log.Printf("聚合服务器已启用，开始注册节点 %s 到 %s...", nodeID, serverURL)
-				err := attemptRegistration(serverURL, nodeID, agentToken)
+				err := attemptRegistration(serverURL, nodeID, agentToken, systemCollector.HardwareInventory())
 				if err != nil {
 					errorLogger.Printf("向聚合服务器注册失败 (重试 %d 次后): %v", maxRegisterRetries, err)
 					log.Printf("警告: 向聚合服务器注册失败，上报请求可能被拒绝。错误: %v", err)
@@ -196,6 +338,18 @@ func main() {
 		log.Printf("节点注册状态: %v", registrationSuccessful) // Log final registration status
 		// --- 注册逻辑结束 ---
 
+		// 拉取主控端下发的节点配置（需要节点令牌）
+		if servers := serverURLs(agentConfig); agentConfig.Server.Token != "" && len(servers) > 0 {
+			nodeConfig, err := fetchNodeConfiguration(servers[0], agentConfig.Server.Token)
+			if err != nil {
+				errorLogger.Printf("拉取节点配置失败，继续使用本地配置: %v", err)
+			} else {
+				applyProcessMonitoring(nodeConfig, systemCollector)
+				applyProbes(nodeConfig, systemCollector)
+				applySystemdUnits(nodeConfig, systemCollector)
+			}
+		}
+
 	} else {
 		log.Println("调试模式启用，将只打印收集的数据而不上报")
 		// 调试模式也需要 nodeID
@@ -232,6 +386,24 @@ func main() {
 
 	log.Println("节点代理正在关闭...")
 	ticker.Stop()
+
+	// 发送上报队列和批量上报中尚未发送的数据
+	if httpReporter != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := httpReporter.Close(ctx); err != nil {
+			errorLogger.Printf("发送剩余的上报数据失败: %v", err)
+		}
+		cancel()
+	}
+
+	// 剩余数据发送后再断开WebSocket连接
+	if wsChannel != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := wsChannel.Close(ctx); err != nil {
+			errorLogger.Printf("关闭WebSocket上报通道失败: %v", err)
+		}
+		cancel()
+	}
 	log.Println("节点代理已安全退出")
 
 	// 关闭错误日志文件
@@ -241,7 +413,7 @@ func main() {
 }
 
 // attemptRegistration 尝试向聚合服务器注册 Agent，带重试逻辑
-func attemptRegistration(aggregatorURL, nodeID, token string) error {
+func attemptRegistration(aggregatorURL, nodeID, token string, inventory *collector.HardwareInventory) error {
 	var lastErr error
 	for i := 0; i <= maxRegisterRetries; i++ {
 		if i > 0 {
@@ -249,7 +421,7 @@ func attemptRegistration(aggregatorURL, nodeID, token string) error {
 			time.Sleep(registerRetryInterval)
 		}
 		log.Printf("尝试注册 (第 %d 次)...", i+1)
-		err := registerAgentWithAggregator(aggregatorURL, nodeID, token)
+		err := registerAgentWithAggregator(aggregatorURL, nodeID, token, inventory)
 		if err == nil {
 			log.Printf("注册成功 (第 %d 次尝试)", i+1)
 			return nil // 成功
@@ -260,13 +432,16 @@ func attemptRegistration(aggregatorURL, nodeID, token string) error {
 	return fmt.Errorf("注册失败，已重试 %d 次: %w", maxRegisterRetries, lastErr)
 }
 
-// registerAgentWithAggregator 执行单次注册尝试
-func registerAgentWithAggregator(aggregatorURL, nodeID, token string) error {
+// registerAgentWithAggregator 执行单次注册尝试，注册时附带硬件清单
+func registerAgentWithAggregator(aggregatorURL, nodeID, token string, inventory *collector.HardwareInventory) error {
 	registerURL := fmt.Sprintf("%s/api/v1/nodes/register", aggregatorURL)
-	payload := map[string]string{
+	payload := map[string]interface{}{
 		"node_id": nodeID,
 		"token":   token,
 	}
+	if inventory != nil {
+		payload["inventory"] = inventory
+	}
 	bodyBytes, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("序列化注册负载失败: %w", err)
@@ -302,6 +477,276 @@ func registerAgentWithAggregator(aggregatorURL, nodeID, token string) error {
 	return nil // 注册成功
 }
 
+// fetchNodeConfiguration 从主控端获取节点配置
+func fetchNodeConfiguration(serverURL, token string) (map[string]interface{}, error) {
+	configURL := fmt.Sprintf("%s/api/v1/nodes/configuration", strings.TrimRight(serverURL, "/"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", configURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建配置请求失败: %w", err)
+	}
+
+	// 主控端直接使用Authorization头部的值作为节点令牌
+	req.Header.Set("Authorization", token)
+	req.Header.Set("User-Agent", "SysLens-Agent/Config")
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送配置请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置响应失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("配置请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Status string                 `json:"status"`
+		Data   map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("解析配置响应失败: %w", err)
+	}
+
+	return result.Data, nil
+}
+
+// buildCollectorSettings 根据采集配置生成各子收集器的运行参数
+func buildCollectorSettings(cfg *config.CollectionConfig) map[string]collector.CollectorSettings {
+	// 兼容enabled中的开关；进程、cgroup和传感器由各自的开关控制，
+	// 其中进程采集可由主控端在运行时开启，因此子收集器始终保持启用
+	enabled := map[string]bool{
+		collector.CollectorCPU:          enabledByDefault(cfg.Enabled.CPU),
+		collector.CollectorMemory:       enabledByDefault(cfg.Enabled.Memory),
+		collector.CollectorPressure:     true,
+		collector.CollectorDisk:         enabledByDefault(cfg.Enabled.Disk),
+		collector.CollectorDiskIO:       enabledByDefault(cfg.Enabled.Disk),
+		collector.CollectorNetwork:      enabledByDefault(cfg.Enabled.Network),
+		collector.CollectorProcesses:    true,
+		collector.CollectorCgroups:      true,
+		collector.CollectorSystemd:      true,
+		collector.CollectorSensors:      true,
+		collector.CollectorTimeSync:     true,
+		collector.CollectorHardware:     true,
+		collector.CollectorCustom:       true,
+		collector.CollectorScrape:       true,
+		collector.CollectorProbes:       true,
+		collector.CollectorLogs:         true,
+		collector.CollectorCertificates: true,
+		collector.CollectorIntegrity:    true,
+	}
+
+	settings := make(map[string]collector.CollectorSettings, len(enabled))
+	for name, on := range enabled {
+		settings[name] = collector.CollectorSettings{Enabled: on}
+	}
+
+	for name, c := range cfg.Collectors {
+		s, known := settings[name]
+		if !known {
+			log.Printf("警告: 配置中存在未知的子收集器 '%s'，已忽略", name)
+			continue
+		}
+		if c.Enabled != nil {
+			s.Enabled = *c.Enabled
+		}
+		s.Interval = time.Duration(c.Interval) * time.Millisecond
+		s.Timeout = time.Duration(c.Timeout) * time.Millisecond
+		settings[name] = s
+	}
+
+	return settings
+}
+
+// enabledByDefault 返回默认开启的采集项开关，未配置时视为开启
+func enabledByDefault(flag *bool) bool {
+	return flag == nil || *flag
+}
+
+// buildPluginConfigs 将插件配置转换为收集器的插件参数
+func buildPluginConfigs(plugins []config.PluginConfig) []collector.PluginConfig {
+	result := make([]collector.PluginConfig, 0, len(plugins))
+	for _, p := range plugins {
+		result = append(result, collector.PluginConfig{
+			Name:     p.Name,
+			Command:  p.Command,
+			Args:     p.Args,
+			Format:   p.Format,
+			Interval: time.Duration(p.Interval) * time.Millisecond,
+			Timeout:  time.Duration(p.Timeout) * time.Millisecond,
+			Labels:   p.Labels,
+			Env:      p.Env,
+			Dir:      p.Dir,
+		})
+	}
+	return result
+}
+
+// buildScrapeOptions 将Prometheus抓取配置转换为收集器的抓取选项
+func buildScrapeOptions(cfg config.ScrapeConfig) collector.ScrapeOptions {
+	opts := collector.ScrapeOptions{
+		TextfileDir:    cfg.TextfileDir,
+		IncludeMetrics: cfg.IncludeMetrics,
+		ExcludeMetrics: cfg.ExcludeMetrics,
+		MaxTotalSeries: cfg.MaxTotalSeries,
+	}
+	for _, t := range cfg.Targets {
+		opts.Targets = append(opts.Targets, collector.ScrapeTarget{
+			Name:      t.Name,
+			URL:       t.URL,
+			Interval:  time.Duration(t.Interval) * time.Millisecond,
+			Timeout:   time.Duration(t.Timeout) * time.Millisecond,
+			MaxSeries: t.MaxSeries,
+			Labels:    t.Labels,
+		})
+	}
+	return opts
+}
+
+// buildLogWatchOptions 将日志监控配置转换为收集器的日志监控选项
+func buildLogWatchOptions(cfg config.LogWatchConfig) collector.LogWatchOptions {
+	opts := collector.LogWatchOptions{StateFile: cfg.StateFile}
+	for _, f := range cfg.Files {
+		watch := collector.LogWatchConfig{
+			Name:       f.Name,
+			Path:       f.Path,
+			MaxSamples: f.MaxSamples,
+			Labels:     f.Labels,
+		}
+		for _, p := range f.Patterns {
+			watch.Patterns = append(watch.Patterns, collector.LogPattern{Name: p.Name, Regex: p.Regex})
+		}
+		opts.Files = append(opts.Files, watch)
+	}
+	return opts
+}
+
+// buildProbeConfigs 将探测配置转换为收集器的探测参数
+func buildProbeConfigs(probes []config.ProbeConfig) []collector.ProbeConfig {
+	result := make([]collector.ProbeConfig, 0, len(probes))
+	for _, p := range probes {
+		result = append(result, collector.ProbeConfig{
+			Name:               p.Name,
+			Type:               p.Type,
+			Target:             p.Target,
+			Interval:           time.Duration(p.Interval) * time.Millisecond,
+			Timeout:            time.Duration(p.Timeout) * time.Millisecond,
+			Labels:             p.Labels,
+			Method:             p.Method,
+			Headers:            p.Headers,
+			ExpectedStatus:     p.ExpectedStatus,
+			BodyRegex:          p.BodyRegex,
+			TLSSkipVerify:      p.TLSSkipVerify,
+			TLSExpiryThreshold: time.Duration(p.TLSExpiryDays) * 24 * time.Hour,
+			RecordType:         p.RecordType,
+			DNSServer:          p.DNSServer,
+			ExpectedAnswers:    p.ExpectedAnswers,
+			PingCount:          p.PingCount,
+		})
+	}
+	return result
+}
+
+// applyProbes 应用主控端下发的探测配置，字段与本地配置的probes相同
+func applyProbes(nodeConfig map[string]interface{}, c *collector.ParallelCollector) {
+	raw, ok := nodeConfig["probes"]
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		errorLogger.Printf("解析主控端探测配置失败: %v", err)
+		return
+	}
+	var probes []config.ProbeConfig
+	if err := json.Unmarshal(data, &probes); err != nil {
+		errorLogger.Printf("解析主控端探测配置失败: %v", err)
+		return
+	}
+
+	c.SetRemoteProbes(buildProbeConfigs(probes))
+	log.Printf("已应用主控端下发的探测配置: %d 个探测", len(probes))
+}
+
+// applySystemdUnits 应用主控端下发的systemd单元，包括节点配置中的systemd_units及所属固定服务声明的单元
+func applySystemdUnits(nodeConfig map[string]interface{}, c *collector.ParallelCollector) {
+	var units []string
+	addUnits := func(raw interface{}) {
+		list, ok := raw.([]interface{})
+		if !ok {
+			return
+		}
+		for _, item := range list {
+			if unit, ok := item.(string); ok && unit != "" {
+				units = append(units, unit)
+			}
+		}
+	}
+
+	addUnits(nodeConfig["systemd_units"])
+	if service, ok := nodeConfig["service"].(map[string]interface{}); ok {
+		addUnits(service["systemd_units"])
+	}
+	if len(units) == 0 {
+		return
+	}
+
+	c.SetSystemdUnits(units)
+	log.Printf("已应用主控端下发的systemd单元: %v", units)
+}
+
+// applyProcessMonitoring 应用主控端下发的进程监控配置
+// 主控端配置只能开启进程采集和追加重点监控进程，本地已开启的采集不会被关闭
+func applyProcessMonitoring(nodeConfig map[string]interface{}, c *collector.ParallelCollector) {
+	monitoring, ok := nodeConfig["process_monitoring"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	opts := c.ProcessOptions()
+
+	if enabled, ok := monitoring["enabled"].(bool); ok && enabled {
+		opts.Enabled = true
+	}
+
+	if includeArgs, ok := monitoring["include_args"].(bool); ok && includeArgs {
+		opts.IncludeArgs = true
+	}
+
+	if processes, ok := monitoring["processes"].([]interface{}); ok {
+		for _, p := range processes {
+			name, ok := p.(string)
+			if !ok || name == "" {
+				continue
+			}
+			exists := false
+			for _, target := range opts.Targets {
+				if target == name {
+					exists = true
+					break
+				}
+			}
+			if !exists {
+				opts.Targets = append(opts.Targets, name)
+			}
+		}
+	}
+
+	c.SetProcessOptions(opts)
+	log.Printf("已应用主控端进程监控配置: 启用=%v, 重点监控进程=%v, 包含参数=%v",
+		opts.Enabled, opts.Targets, opts.IncludeArgs)
+}
+
 // loadConfig 从文件加载配置并支持环境变量替换
 func loadConfig(path string) (*config.AgentConfig, error) {
 	data, err := ioutil.ReadFile(path)
@@ -347,9 +792,9 @@ func loadConfig(path string) (*config.AgentConfig, error) {
 
 // ensureDefaultConfig 确保关键配置项有合理的默认值
 func ensureDefaultConfig(cfg *config.AgentConfig) {
-	// 确保磁盘挂载点配置
-	if len(cfg.Collection.Disk.MountPoints) == 0 {
-		cfg.Collection.Disk.MountPoints = []string{"/"}
+	// 确保磁盘挂载点配置（空切片表示自动发现所有分区）
+	if cfg.Collection.Disk.MountPoints == nil {
+		cfg.Collection.Disk.MountPoints = []string{}
 	}
 
 	// 确保网络接口配置（空切片表示所有接口）
@@ -357,6 +802,17 @@ func ensureDefaultConfig(cfg *config.AgentConfig) {
 		cfg.Collection.Network.Interfaces = []string{}
 	}
 
+	// 监控日志文件时保存读取位置，代理重启后不会重复或遗漏统计
+	if len(cfg.Collection.Logs.Files) > 0 && cfg.Collection.Logs.StateFile == "" {
+		cfg.Collection.Logs.StateFile = "data/agent/log_offsets.json"
+	}
+	if len(cfg.Collection.Integrity.Paths) > 0 && cfg.Collection.Integrity.StateFile == "" {
+		cfg.Collection.Integrity.StateFile = "data/agent/integrity_baseline.json"
+	}
+	if cfg.Spool.Enabled && cfg.Spool.Dir == "" {
+		cfg.Spool.Dir = "data/agent/spool"
+	}
+
 	// 确保采集间隔合理
 	if cfg.Collection.Interval <= 0 {
 		cfg.Collection.Interval = 500 // 默认500毫秒
@@ -371,6 +827,17 @@ func ensureDefaultConfig(cfg *config.AgentConfig) {
 		cfg.Server.RetryInterval = 1
 	}
 
+	if cfg.Server.HealthCheckInterval <= 0 {
+		cfg.Server.HealthCheckInterval = 10
+	}
+
+	if cfg.Server.MaxRetryInterval <= 0 {
+		cfg.Server.MaxRetryInterval = 60
+	}
+	if cfg.Server.MaxRetryInterval < cfg.Server.RetryInterval {
+		cfg.Server.MaxRetryInterval = cfg.Server.RetryInterval
+	}
+
 	// 确保超时时间合理
 	if cfg.Server.Timeout <= 0 {
 		cfg.Server.Timeout = 10
@@ -395,37 +862,172 @@ func collectAndReport(collector collector.Collector, reporter reporter.Reporter,
 
 	if debugMode {
 		// 调试模式，只打印关键指标
-		log.Printf("CPU使用率: %.2f%%\n", stats.CPU["usage"])
+		log.Printf("CPU使用率: %.2f%%, user: %.2f%%, system: %.2f%%, iowait: %.2f%%, steal: %.2f%%\n",
+			stats.CPU["usage"], stats.CPU["user"], stats.CPU["system"], stats.CPU["iowait"], stats.CPU["steal"])
+		log.Printf("上下文切换: %.0f/s, 中断: %.0f/s, CPU核心数: %d\n",
+			stats.CPU["ctx_switches_per_sec"], stats.CPU["interrupts_per_sec"], len(stats.PerCPU))
 		log.Printf("内存使用率: %.2f%%\n", stats.Memory.UsedPercent)
 
+		// 硬件清单（仅在首次采集和硬件变化时存在）
+		if inv := stats.Inventory; inv != nil {
+			log.Printf("硬件清单: %s %s, CPU: %s (%d插槽/%d核/%d线程), NUMA节点: %d, 块设备: %d, 网卡: %d, 摘要: %.12s\n",
+				inv.System.Vendor, inv.System.Product, inv.CPU.Model, inv.CPU.Sockets, inv.CPU.PhysicalCores,
+				inv.CPU.LogicalCores, len(inv.NUMANodes), len(inv.BlockDevices), len(inv.NICs), inv.Fingerprint)
+		}
+
+		// 资源压力信息
+		for resource, pressure := range stats.Pressure {
+			log.Printf("  - 资源压力 %s: some avg10=%.2f, full avg10=%.2f\n",
+				resource, pressure.SomeAvg10, pressure.FullAvg10)
+		}
+		if stats.VMStat != nil {
+			log.Printf("主缺页: %.1f/s, 换入: %.1f/s, 换出: %.1f/s, OOM Kill: %d\n",
+				stats.VMStat.PgMajFaultRate, stats.VMStat.PswpInRate, stats.VMStat.PswpOutRate, stats.VMStat.OOMKill)
+		}
+
 		// 磁盘信息
 		log.Printf("收集到 %d 个磁盘分区信息\n", len(stats.Disk))
 		for mountPoint, diskInfo := range stats.Disk {
-			log.Printf("  - 挂载点: %s, 使用率: %.2f%%, 总空间: %.2f GB\n",
+			if !diskInfo.Mounted {
+				log.Printf("  - 挂载点: %s, 未挂载\n", mountPoint)
+				continue
+			}
+			log.Printf("  - 挂载点: %s, 使用率: %.2f%%, 总空间: %.2f GB, inode使用率: %.2f%%\n",
 				mountPoint,
 				diskInfo.UsedPercent,
-				float64(diskInfo.Total)/(1024*1024*1024))
+				float64(diskInfo.Total)/(1024*1024*1024),
+				diskInfo.InodesUsedPercent)
+		}
+
+		// 磁盘I/O信息
+		for device, ioInfo := range stats.DiskIO {
+			log.Printf("  - 设备: %s, 读: %.1f IOPS %.2f MB/s, 写: %.1f IOPS %.2f MB/s, 等待: %.2f ms, 利用率: %.2f%%\n",
+				device,
+				ioInfo.ReadIOPS, float64(ioInfo.ReadBytesRate)/(1024*1024),
+				ioInfo.WriteIOPS, float64(ioInfo.WriteBytesRate)/(1024*1024),
+				ioInfo.Await, ioInfo.Utilization)
 		}
 
 		// 网络信息
 		log.Printf("收集到 %d 个网络接口信息\n", len(stats.Network.Interfaces))
 		for iface, netInfo := range stats.Network.Interfaces {
-			log.Printf("  - 接口: %s, 上传速度: %.2f KB/s, 下载速度: %.2f KB/s\n",
+			log.Printf("  - 接口: %s, 上传速度: %.2f KB/s, 下载速度: %.2f KB/s, 错误: %.1f/s, 丢包: %.1f/s\n",
 				iface,
 				float64(netInfo.UploadSpeed)/1024,
-				float64(netInfo.DownloadSpeed)/1024)
+				float64(netInfo.DownloadSpeed)/1024,
+				netInfo.ErrorsRate,
+				netInfo.DropsRate)
 		}
 
 		log.Printf("TCP连接数: %d, UDP连接数: %d\n",
 			stats.Network.TCPConnCount, stats.Network.UDPConnCount)
+		if len(stats.Network.TCPStates) > 0 {
+			log.Printf("TCP连接状态: ESTABLISHED=%d, TIME_WAIT=%d, CLOSE_WAIT=%d, SYN_RECV=%d\n",
+				stats.Network.TCPStates["ESTABLISHED"], stats.Network.TCPStates["TIME_WAIT"],
+				stats.Network.TCPStates["CLOSE_WAIT"], stats.Network.TCPStates["SYN_RECV"])
+		}
+		if stats.Network.TCP != nil {
+			log.Printf("TCP重传: %.1f/s (%.2f%%), 监听队列溢出: %d\n",
+				stats.Network.TCP.RetransRate, stats.Network.TCP.RetransPercent, stats.Network.TCP.ListenOverflows)
+		}
 		log.Printf("IP地址: 公网IPv4=%v, 内网IPv4=%v\n",
 			stats.Network.PublicIPv4, stats.Network.PrivateIPv4)
+
+		// cgroup信息
+		if len(stats.Cgroups) > 0 {
+			log.Printf("收集到 %d 个cgroup信息\n", len(stats.Cgroups))
+			for _, cg := range stats.Cgroups {
+				if cg.Labels["type"] != "container" {
+					continue
+				}
+				log.Printf("  - 容器: %s, CPU: %.2f%%, 限流: %.2f%%, 内存: %.2f MB\n",
+					cg.Name, cg.CPUPercent, cg.ThrottledPercent, float64(cg.MemoryCurrent)/(1024*1024))
+			}
+		}
+
+		// systemd单元
+		for _, unit := range stats.Systemd {
+			log.Printf("  - systemd单元 %s: %s/%s, 重启次数: %d, 内存: %.2f MB\n",
+				unit.Name, unit.ActiveState, unit.SubState, unit.NRestarts, float64(unit.MemoryCurrent)/(1024*1024))
+		}
+
+		// 传感器信息
+		for _, sensor := range stats.Sensors {
+			unit := "°C"
+			if sensor.Kind == "fan" {
+				unit = "RPM"
+			}
+			log.Printf("  - 传感器 %s/%s: %.1f%s, 临界值: %.1f, 告警: %v\n",
+				sensor.Chip, sensor.Sensor, sensor.Value, unit, sensor.Critical, sensor.Alarm)
+		}
+
+		// 时间同步状态
+		if ts := stats.TimeSync; ts != nil {
+			offset := "未知"
+			if ts.OffsetMs != nil {
+				offset = fmt.Sprintf("%.3f ms", *ts.OffsetMs)
+			}
+			log.Printf("  - 时间同步(%s): 已同步=%v, 偏差=%s, 服务器=%s, 层级=%d\n",
+				ts.Source, ts.Synchronized, offset, ts.Server, ts.Stratum)
+		}
+
+		// 自定义插件结果
+		for name, result := range stats.Custom {
+			log.Printf("  - 插件 %s: 状态=%s, 指标数=%d, 耗时=%.1fms %s\n",
+				name, result.Status, len(result.Metrics), result.DurationMs, result.Error)
+		}
+
+		// 主动探测结果
+		for name, result := range stats.Probes {
+			log.Printf("  - 探测 %s(%s %s): 成功=%v, 耗时=%.1fms %s\n",
+				name, result.Type, result.Target, result.Success, result.LatencyMs, result.Error)
+		}
+
+		// 日志文件的模式计数
+		for name, logStats := range stats.Logs {
+			log.Printf("  - 日志 %s: 行数=%d, 匹配=%v, 样本数=%d %s\n",
+				name, logStats.Lines, logStats.Counts, len(logStats.Samples), logStats.Error)
+		}
+
+		// 证书信息
+		for _, cert := range stats.Certificates {
+			log.Printf("  - 证书 %s[%d]: %s, 剩余 %.1f 天 %s\n",
+				cert.Path, cert.Index, cert.Subject, cert.DaysLeft, cert.Error)
+		}
+
+		// 文件完整性监控
+		if stats.Integrity != nil {
+			log.Printf("  - 文件完整性: 文件数=%d, 新增=%d, 删除=%d, 修改=%d, 错误=%d\n",
+				stats.Integrity.Files, stats.Integrity.Added, stats.Integrity.Removed,
+				stats.Integrity.Modified, stats.Integrity.Errors)
+		}
+
+		// 监听端口及事件
+		if len(stats.Listeners) > 0 {
+			log.Printf("监听端口数: %d\n", len(stats.Listeners))
+		}
+		for _, event := range stats.Events {
+			log.Printf("  - 事件[%s/%s]: %s\n", event.Severity, event.Type, event.Message)
+		}
+
+		// 进程信息
+		if stats.Processes != nil {
+			log.Printf("进程总数: %d\n", stats.Processes.Total)
+			for _, proc := range stats.Processes.TopCPU {
+				log.Printf("  - 进程: %s(%d), CPU: %.2f%%, 内存: %.2f MB\n",
+					proc.Name, proc.PID, proc.CPUPercent, float64(proc.MemoryRSS)/(1024*1024))
+			}
+			for name, procs := range stats.Processes.Targets {
+				log.Printf("  - 重点进程 %s: %d 个实例\n", name, len(procs))
+			}
+		}
 		return
 	}
 
 	// 上报指标
 	if reporter != nil {
 		log.Println("开始上报系统指标...")
+		stats.ReportEndpoint = activeEndpoint.Load()
 		err = reporter.Report(stats)
 		if err != nil {
 			// 详细记录上报失败信息
@@ -444,42 +1046,51 @@ func collectAndReport(collector collector.Collector, reporter reporter.Reporter,
 			// 在标准日志中也输出简要信息
 			log.Printf("上报失败: %v - 详细错误已记录到错误日志", err)
 			log.Println("将继续采集数据，即使上报失败")
-
-			// 保存失败数据到本地缓存文件
-			saveFailedReportData(stats, collectTime)
 		} else {
-			log.Printf("系统指标上报成功 [时间点: %s]\n", collectTime)
+			log.Printf("系统指标已提交上报 [时间点: %s]\n", collectTime)
 		}
 	}
 }
 
-// saveFailedReportData 将上报失败的数据保存到本地文件
-func saveFailedReportData(stats *collector.SystemStats, timestamp string) {
-	// 创建缓存目录
-	cacheDir := "tmp/failed_reports"
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		errorLogger.Printf("创建缓存目录失败: %v", err)
-		return
+// reportEndpoints 返回按优先级排列的上报目标
+// 启用聚合服务器时聚合服务器在前，全部不可用时直接上报到主控服务器
+func reportEndpoints(agentConfig *config.AgentConfig) []reporter.Endpoint {
+	var endpoints []reporter.Endpoint
+	if agentConfig.Aggregator.Enabled {
+		urls := agentConfig.Aggregator.URLs
+		if len(urls) == 0 && agentConfig.Aggregator.URL != "" {
+			urls = []string{agentConfig.Aggregator.URL}
+		}
+		for _, url := range urls {
+			endpoints = append(endpoints, reporter.Endpoint{
+				URL:     url,
+				Kind:    reporter.EndpointAggregator,
+				Timeout: time.Duration(agentConfig.Aggregator.Timeout) * time.Second,
+			})
+		}
 	}
-
-	// 生成文件名，使用时间戳确保唯一性
-	safeTime := strings.ReplaceAll(timestamp, ":", "-")
-	filename := filepath.Join(cacheDir, fmt.Sprintf("metrics_%s.json", safeTime))
-
-	// 序列化数据
-	data, err := json.MarshalIndent(stats, "", "  ")
-	if err != nil {
-		errorLogger.Printf("序列化失败数据失败: %v", err)
-		return
+	for _, url := range serverURLs(agentConfig) {
+		endpoints = append(endpoints, reporter.Endpoint{
+			URL:     url,
+			Kind:    reporter.EndpointServer,
+			Timeout: time.Duration(agentConfig.Server.Timeout) * time.Second,
+		})
 	}
-
-	// 写入文件
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		errorLogger.Printf("保存失败数据到文件失败: %v", err)
-		return
+	if len(endpoints) == 0 {
+		endpoints = append(endpoints, reporter.Endpoint{URL: "http://localhost:8080", Kind: reporter.EndpointServer})
 	}
+	return endpoints
+}
 
-	errorLogger.Printf("已保存上报失败的数据到: %s", filename)
+// serverURLs 返回按优先级排列的主控服务器地址
+func serverURLs(agentConfig *config.AgentConfig) []string {
+	if len(agentConfig.Server.URLs) > 0 {
+		return agentConfig.Server.URLs
+	}
+	if agentConfig.Server.URL != "" {
+		return []string{agentConfig.Server.URL}
+	}
+	return nil
 }
 
 // getAppropriateTimeout 获取合适的超时时间
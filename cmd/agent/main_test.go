@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/syslens/syslens-api/internal/agent/collector"
+	"github.com/syslens/syslens-api/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestBuildCollectorSettings(t *testing.T) {
+	// 未配置enabled时基础采集项默认开启
+	settings := buildCollectorSettings(&config.CollectionConfig{})
+	for _, name := range []string{
+		collector.CollectorCPU, collector.CollectorMemory, collector.CollectorDisk,
+		collector.CollectorDiskIO, collector.CollectorNetwork,
+	} {
+		if !settings[name].Enabled {
+			t.Errorf("子收集器 %s 未配置时应默认开启", name)
+		}
+	}
+
+	// 显式关闭的采集项及collectors中的覆盖生效
+	var cfg config.CollectionConfig
+	data := []byte("enabled:\n  disk: false\ncollectors:\n  cpu:\n    enabled: false\n    interval: 5000\n")
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("解析配置失败: %v", err)
+	}
+	settings = buildCollectorSettings(&cfg)
+	if settings[collector.CollectorDisk].Enabled || settings[collector.CollectorDiskIO].Enabled {
+		t.Error("显式关闭的磁盘采集不应开启")
+	}
+	if cpu := settings[collector.CollectorCPU]; cpu.Enabled || cpu.Interval.Milliseconds() != 5000 {
+		t.Errorf("CPU子收集器设置异常: %+v", cpu)
+	}
+	if !settings[collector.CollectorMemory].Enabled || !settings[collector.CollectorNetwork].Enabled {
+		t.Error("未配置的基础采集项应保持开启")
+	}
+}
@@ -248,10 +248,14 @@ func main() {
 	// 应用安全配置
 	metricsHandler.WithSecurityConfig(&serverConfig.Security)
 
+	// 应用时钟偏差检测配置
+	metricsHandler.WithClockSkewConfig(&serverConfig.ClockSkew)
+
 	// 如果PostgreSQL连接成功，设置节点仓库
 	if postgresDB != nil {
 		nodeRepo := repository.NewPostgresNodeRepository(postgresDB)
 		metricsHandler.WithNodeRepository(nodeRepo)
+		metricsHandler.WithServiceRepository(repository.NewPostgresServiceRepository(postgresDB))
 	}
 
 	// 初始化zap日志记录器 (修改部分)
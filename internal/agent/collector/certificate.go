@@ -0,0 +1,340 @@
+package collector
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 证书来源
+const (
+	CertificateSourceFile     = "file"
+	CertificateSourceEndpoint = "endpoint"
+)
+
+const (
+	defaultCertificateInterval = time.Hour
+	defaultCertificateTimeout  = 5 * time.Second
+	// 单次扫描最多读取的文件数，避免通配符匹配到过大的目录树
+	maxCertificateFiles = 1000
+	// 单个证书文件的最大字节数
+	maxCertificateFileSize = 1024 * 1024
+)
+
+// CertificateOptions 证书扫描选项
+type CertificateOptions struct {
+	// 证书文件路径，支持通配符，"**"匹配任意层目录（如/etc/ssl/**/*.pem）
+	Paths []string
+	// TLS端点，格式为host:port
+	Endpoints []string
+	// 扫描间隔，剩余天数在每个采集周期按当前时间重新计算
+	Interval time.Duration
+	// 单个端点的连接超时
+	Timeout time.Duration
+}
+
+// CertificateInfo 单个证书的信息
+type CertificateInfo struct {
+	// 来源：file或endpoint
+	Source string `json:"source"`
+	// 文件路径或host:port
+	Path string `json:"path"`
+	// 证书在文件或证书链中的位置，0为第一个（端点的叶子证书）
+	Index        int       `json:"index"`
+	Subject      string    `json:"subject,omitempty"`
+	Issuer       string    `json:"issuer,omitempty"`
+	SANs         []string  `json:"sans,omitempty"`
+	SerialNumber string    `json:"serial_number,omitempty"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+	// 距离过期的天数，已过期时为负数
+	DaysLeft float64 `json:"days_left"`
+	IsCA     bool    `json:"is_ca"`
+	// SHA-256指纹
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// 读取文件或连接端点失败的原因，此时证书字段为空
+	Error string `json:"error,omitempty"`
+}
+
+// CertificateCollector 证书过期扫描收集器
+// 扫描在后台按间隔执行，Collect只返回最近一次扫描到的证书
+type CertificateCollector struct {
+	mu      sync.Mutex
+	options CertificateOptions
+
+	running bool
+	lastRun time.Time
+	certs   []CertificateInfo
+	scanned bool
+}
+
+// NewCertificateCollector 创建新的证书扫描收集器
+func NewCertificateCollector(opts CertificateOptions) *CertificateCollector {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultCertificateInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultCertificateTimeout
+	}
+	return &CertificateCollector{options: opts}
+}
+
+// Collect 在到期时启动后台扫描，并返回最近一次扫描到的证书，未配置路径和端点时返回nil
+func (cc *CertificateCollector) Collect(now time.Time) []CertificateInfo {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if len(cc.options.Paths) == 0 && len(cc.options.Endpoints) == 0 {
+		return nil
+	}
+
+	if !cc.running && (!cc.scanned || now.Sub(cc.lastRun) >= cc.options.Interval) {
+		cc.running = true
+		cc.lastRun = now
+		go func() {
+			certs := scanCertificates(context.Background(), cc.options)
+
+			cc.mu.Lock()
+			cc.running = false
+			cc.scanned = true
+			cc.certs = certs
+			cc.mu.Unlock()
+		}()
+	}
+
+	if !cc.scanned {
+		return nil
+	}
+
+	certs := make([]CertificateInfo, len(cc.certs))
+	copy(certs, cc.certs)
+	for i := range certs {
+		if certs[i].Error == "" {
+			certs[i].DaysLeft = certDaysLeft(certs[i].NotAfter, now)
+		}
+	}
+	return certs
+}
+
+// scanCertificates 扫描所有配置的证书文件和TLS端点
+func scanCertificates(ctx context.Context, opts CertificateOptions) []CertificateInfo {
+	now := time.Now()
+	var certs []CertificateInfo
+
+	// 同一证书可能通过符号链接出现多次（如/etc/ssl/certs），只保留第一次出现的位置
+	seen := make(map[string]bool)
+	for _, file := range expandCertificatePaths(opts.Paths) {
+		for _, cert := range readCertificateFile(file, now) {
+			if cert.Fingerprint != "" {
+				if seen[cert.Fingerprint] {
+					continue
+				}
+				seen[cert.Fingerprint] = true
+			}
+			certs = append(certs, cert)
+		}
+	}
+
+	for _, endpoint := range opts.Endpoints {
+		certs = append(certs, fetchEndpointCertificates(ctx, endpoint, opts.Timeout, now)...)
+	}
+
+	return certs
+}
+
+// expandCertificatePaths 展开通配符并返回排序后的文件列表
+func expandCertificatePaths(patterns []string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := globCertificateFiles(pattern)
+		if err != nil {
+			log.Printf("展开证书路径 %s 失败: %v", pattern, err)
+			continue
+		}
+		for _, file := range matches {
+			if seen[file] {
+				continue
+			}
+			if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			seen[file] = true
+			files = append(files, file)
+			if len(files) >= maxCertificateFiles {
+				log.Printf("证书文件数超过上限%d，其余文件已忽略", maxCertificateFiles)
+				sort.Strings(files)
+				return files
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// globCertificateFiles 展开单个通配符，"**"匹配零层或多层目录，"**"之前的部分不支持通配符
+func globCertificateFiles(pattern string) ([]string, error) {
+	root, rest, found := strings.Cut(pattern, "**")
+	if !found {
+		return filepath.Glob(pattern)
+	}
+
+	root = filepath.Clean(root)
+	rest = strings.TrimPrefix(rest, string(filepath.Separator))
+	if rest == "" {
+		rest = "*"
+	}
+	if _, err := filepath.Match(rest, ""); err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 跳过无权限读取的目录
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		// rest可以匹配相对路径的任意后缀
+		parts := strings.Split(rel, string(filepath.Separator))
+		for i := range parts {
+			if ok, _ := filepath.Match(rest, filepath.Join(parts[i:]...)); ok {
+				matches = append(matches, path)
+				break
+			}
+		}
+		if len(matches) >= maxCertificateFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// readCertificateFile 读取PEM或DER格式的证书文件，文件中的私钥等其他内容被忽略
+func readCertificateFile(file string, now time.Time) []CertificateInfo {
+	f, err := os.Open(file)
+	if err != nil {
+		return []CertificateInfo{{Source: CertificateSourceFile, Path: file, Error: err.Error()}}
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxCertificateFileSize))
+	if err != nil {
+		return []CertificateInfo{{Source: CertificateSourceFile, Path: file, Error: err.Error()}}
+	}
+
+	var certs []CertificateInfo
+	rest := data
+	foundPEM := false
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		foundPEM = true
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			certs = append(certs, CertificateInfo{Source: CertificateSourceFile, Path: file, Index: len(certs), Error: "解析证书失败: " + err.Error()})
+			continue
+		}
+		certs = append(certs, newCertificateInfo(CertificateSourceFile, file, len(certs), cert, now))
+	}
+
+	// 没有PEM块时按DER格式解析，不是证书的文件（如单独的私钥）直接忽略
+	if !foundPEM {
+		if cert, err := x509.ParseCertificate(data); err == nil {
+			certs = append(certs, newCertificateInfo(CertificateSourceFile, file, 0, cert, now))
+		}
+	}
+
+	return certs
+}
+
+// fetchEndpointCertificates 连接TLS端点并返回服务端发送的证书链
+// 不校验证书，过期或自签名的证书同样需要上报
+func fetchEndpointCertificates(ctx context.Context, endpoint string, timeout time.Duration, now time.Time) []CertificateInfo {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return []CertificateInfo{{Source: CertificateSourceEndpoint, Path: endpoint, Error: err.Error()}}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: true,
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return []CertificateInfo{{Source: CertificateSourceEndpoint, Path: endpoint, Error: fmt.Sprintf("连接失败: %v", err)}}
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	certs := make([]CertificateInfo, 0, len(state.PeerCertificates))
+	for i, cert := range state.PeerCertificates {
+		certs = append(certs, newCertificateInfo(CertificateSourceEndpoint, endpoint, i, cert, now))
+	}
+	return certs
+}
+
+// newCertificateInfo 提取证书信息
+func newCertificateInfo(source, path string, index int, cert *x509.Certificate, now time.Time) CertificateInfo {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+	return CertificateInfo{
+		Source:       source,
+		Path:         path,
+		Index:        index,
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SANs:         sans,
+		SerialNumber: cert.SerialNumber.Text(16),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		DaysLeft:     certDaysLeft(cert.NotAfter, now),
+		IsCA:         cert.IsCA,
+		Fingerprint:  hex.EncodeToString(fingerprint[:]),
+	}
+}
+
+// certDaysLeft 计算距离过期的天数
+func certDaysLeft(notAfter, now time.Time) float64 {
+	return notAfter.Sub(now).Hours() / 24
+}
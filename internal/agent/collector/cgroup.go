@@ -0,0 +1,480 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认遍历的cgroup层级深度
+	defaultCgroupMaxDepth = 5
+	// 默认最多上报的cgroup数量
+	defaultMaxCgroups = 256
+	// 容器名称缓存的刷新间隔
+	containerNameRefreshInterval = 30 * time.Second
+	// 出现未知容器时强制刷新缓存的最小间隔
+	containerNameForceRefreshInterval = 5 * time.Second
+)
+
+// 默认尝试的容器运行时套接字（Docker及兼容Docker API的Podman）
+var defaultRuntimeSockets = []string{"/var/run/docker.sock", "/run/podman/podman.sock"}
+
+// 从cgroup目录名中提取容器ID，兼容docker、containerd、cri-o和podman的命名方式
+var containerIDPattern = regexp.MustCompile(`^(?:(docker|cri-containerd|crio|libpod)-)?([0-9a-f]{64})(?:\.scope)?$`)
+
+// CgroupOptions cgroup采集选项
+type CgroupOptions struct {
+	// 是否启用cgroup采集
+	Enabled bool
+	// cgroup v2挂载点
+	Root string
+	// 遍历的最大层级深度
+	MaxDepth int
+	// 最多上报的cgroup数量
+	MaxCgroups int
+	// 仅采集匹配这些路径模式的cgroup（相对于Root，为空表示不限制）
+	Include []string
+	// 用于解析容器名称的运行时套接字
+	RuntimeSockets []string
+}
+
+// CgroupStats 包含单个cgroup的资源使用信息
+type CgroupStats struct {
+	Path   string            `json:"path"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+
+	// CPU使用情况，使用率以单核为100%
+	CPUUsageUsec     uint64  `json:"cpu_usage_usec"`
+	CPUUserUsec      uint64  `json:"cpu_user_usec"`
+	CPUSystemUsec    uint64  `json:"cpu_system_usec"`
+	CPUPercent       float64 `json:"cpu_percent"`
+	NrPeriods        uint64  `json:"nr_periods"`
+	NrThrottled      uint64  `json:"nr_throttled"`
+	ThrottledUsec    uint64  `json:"throttled_usec"`
+	ThrottledPercent float64 `json:"throttled_percent"`
+
+	// 内存使用情况，MemoryMax为0表示不限制
+	MemoryCurrent     uint64  `json:"memory_current"`
+	MemoryMax         uint64  `json:"memory_max"`
+	MemoryUsedPercent float64 `json:"memory_used_percent"`
+	MemoryOOMEvents   uint64  `json:"memory_oom_events"`
+	MemoryOOMKills    uint64  `json:"memory_oom_kills"`
+
+	// 块设备I/O（所有设备之和）
+	IOReadBytes      uint64  `json:"io_read_bytes"`
+	IOWriteBytes     uint64  `json:"io_write_bytes"`
+	IOReadOps        uint64  `json:"io_read_ops"`
+	IOWriteOps       uint64  `json:"io_write_ops"`
+	IOReadBytesRate  uint64  `json:"io_read_bytes_per_sec"`
+	IOWriteBytesRate uint64  `json:"io_write_bytes_per_sec"`
+	IOReadIOPS       float64 `json:"io_read_iops"`
+	IOWriteIOPS      float64 `json:"io_write_iops"`
+
+	// 进程数，PidsMax为0表示不限制
+	PidsCurrent uint64 `json:"pids_current"`
+	PidsMax     uint64 `json:"pids_max"`
+}
+
+// containerInfo 容器运行时返回的容器信息
+type containerInfo struct {
+	name    string
+	image   string
+	runtime string
+}
+
+// CgroupCollector cgroup v2指标收集器
+type CgroupCollector struct {
+	mu      sync.Mutex
+	options CgroupOptions
+
+	// 上次采集的计数器，用于计算区间内的速率
+	lastStats   map[string]CgroupStats
+	lastCollect time.Time
+
+	// 容器ID到容器信息的缓存
+	containers        map[string]containerInfo
+	containersRefresh time.Time
+
+	// 每个容器运行时套接字复用一个HTTP客户端
+	runtimeClients map[string]*http.Client
+
+	// 非cgroup v2环境及超出数量上限只提示一次
+	warnedUnsupported bool
+	warnedLimit       bool
+}
+
+// NewCgroupCollector 创建新的cgroup收集器
+func NewCgroupCollector(opts CgroupOptions) *CgroupCollector {
+	return &CgroupCollector{
+		options:        normalizeCgroupOptions(opts),
+		lastStats:      make(map[string]CgroupStats),
+		containers:     make(map[string]containerInfo),
+		runtimeClients: make(map[string]*http.Client),
+	}
+}
+
+// normalizeCgroupOptions 为cgroup采集选项补充默认值
+func normalizeCgroupOptions(opts CgroupOptions) CgroupOptions {
+	if opts.Root == "" {
+		opts.Root = "/sys/fs/cgroup"
+	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = defaultCgroupMaxDepth
+	}
+	if opts.MaxCgroups <= 0 {
+		opts.MaxCgroups = defaultMaxCgroups
+	}
+	if len(opts.RuntimeSockets) == 0 {
+		opts.RuntimeSockets = defaultRuntimeSockets
+	}
+	return opts
+}
+
+// Collect 采集各cgroup的资源使用情况，未启用或非cgroup v2环境时返回nil
+func (cc *CgroupCollector) Collect() ([]CgroupStats, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if !cc.options.Enabled {
+		return nil, nil
+	}
+
+	// cgroup v2的根目录下存在cgroup.controllers文件
+	if _, err := os.Stat(filepath.Join(cc.options.Root, "cgroup.controllers")); err != nil {
+		if !cc.warnedUnsupported {
+			log.Printf("未检测到cgroup v2(%s)，跳过cgroup采集", cc.options.Root)
+			cc.warnedUnsupported = true
+		}
+		return nil, nil
+	}
+
+	paths, err := cc.discoverCgroups()
+	if err != nil {
+		return nil, fmt.Errorf("遍历cgroup目录失败: %w", err)
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(cc.lastCollect).Seconds()
+	current := make(map[string]CgroupStats, len(paths))
+	result := make([]CgroupStats, 0, len(paths))
+	refreshed := false
+
+	for _, relPath := range paths {
+		stats := readCgroupStats(filepath.Join(cc.options.Root, relPath))
+		stats.Path = "/" + relPath
+		stats.Name = path.Base(stats.Path)
+		stats.Labels = map[string]string{"cgroup": stats.Path}
+
+		// 标记容器和systemd单元
+		if matches := containerIDPattern.FindStringSubmatch(stats.Name); matches != nil {
+			containerID := matches[2]
+			stats.Labels["type"] = "container"
+			stats.Labels["container_id"] = containerID[:12]
+
+			info, ok := cc.containers[containerID]
+			if !ok && !refreshed {
+				// 出现未知容器时刷新一次缓存
+				cc.refreshContainers(now, true)
+				refreshed = true
+				info, ok = cc.containers[containerID]
+			}
+			if ok {
+				stats.Name = info.name
+				stats.Labels["container_name"] = info.name
+				stats.Labels["image"] = info.image
+				stats.Labels["runtime"] = info.runtime
+			} else {
+				stats.Name = containerID[:12]
+				if matches[1] != "" {
+					stats.Labels["runtime"] = matches[1]
+				}
+			}
+		} else if unitType := systemdUnitType(stats.Name); unitType != "" {
+			stats.Labels["type"] = unitType
+			stats.Labels["unit"] = stats.Name
+		} else {
+			stats.Labels["type"] = "cgroup"
+		}
+
+		if prev, exists := cc.lastStats[stats.Path]; exists && elapsed > 0 {
+			calculateCgroupRates(&stats, prev, elapsed)
+		}
+
+		current[stats.Path] = stats
+		result = append(result, stats)
+	}
+
+	// 定期刷新容器名称缓存，清理已删除的容器
+	if !refreshed {
+		cc.refreshContainers(now, false)
+	}
+
+	// 保存当前采集结果，用于下次计算速率
+	cc.lastStats = current
+	cc.lastCollect = now
+
+	return result, nil
+}
+
+// discoverCgroups 遍历cgroup目录，返回相对于根目录的路径列表
+func (cc *CgroupCollector) discoverCgroups() ([]string, error) {
+	var paths []string
+
+	var walk func(dir string, depth int) error
+	walk = func(dir string, depth int) error {
+		entries, err := os.ReadDir(filepath.Join(cc.options.Root, dir))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			relPath := path.Join(dir, entry.Name())
+			if len(cc.options.Include) == 0 || matchAnyMountGlob(cc.options.Include, relPath) {
+				paths = append(paths, relPath)
+			}
+			if depth < cc.options.MaxDepth {
+				// 子目录可能在遍历过程中被删除，忽略单个目录的错误
+				_ = walk(relPath, depth+1)
+			}
+		}
+		return nil
+	}
+
+	if err := walk("", 1); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+	if len(paths) > cc.options.MaxCgroups {
+		if !cc.warnedLimit {
+			log.Printf("警告: cgroup数量(%d)超过上限(%d)，超出部分将被忽略", len(paths), cc.options.MaxCgroups)
+			cc.warnedLimit = true
+		}
+		paths = paths[:cc.options.MaxCgroups]
+	}
+
+	return paths, nil
+}
+
+// systemdUnitType 根据cgroup目录名判断systemd单元类型
+func systemdUnitType(name string) string {
+	for _, suffix := range []string{".slice", ".service", ".scope"} {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimPrefix(suffix, ".")
+		}
+	}
+	return ""
+}
+
+// readCgroupStats 读取单个cgroup的各项统计文件，控制器未启用的文件会被跳过
+func readCgroupStats(dir string) CgroupStats {
+	var stats CgroupStats
+
+	if values, err := readKeyValueFile(filepath.Join(dir, "cpu.stat")); err == nil {
+		stats.CPUUsageUsec = values["usage_usec"]
+		stats.CPUUserUsec = values["user_usec"]
+		stats.CPUSystemUsec = values["system_usec"]
+		stats.NrPeriods = values["nr_periods"]
+		stats.NrThrottled = values["nr_throttled"]
+		stats.ThrottledUsec = values["throttled_usec"]
+	}
+
+	stats.MemoryCurrent, _ = readCgroupValue(filepath.Join(dir, "memory.current"))
+	stats.MemoryMax, _ = readCgroupValue(filepath.Join(dir, "memory.max"))
+	if stats.MemoryMax > 0 {
+		stats.MemoryUsedPercent = float64(stats.MemoryCurrent) / float64(stats.MemoryMax) * 100
+	}
+
+	if values, err := readKeyValueFile(filepath.Join(dir, "memory.events")); err == nil {
+		stats.MemoryOOMEvents = values["oom"]
+		stats.MemoryOOMKills = values["oom_kill"]
+	}
+
+	stats.IOReadBytes, stats.IOWriteBytes, stats.IOReadOps, stats.IOWriteOps, _ = readCgroupIOStat(filepath.Join(dir, "io.stat"))
+
+	stats.PidsCurrent, _ = readCgroupValue(filepath.Join(dir, "pids.current"))
+	stats.PidsMax, _ = readCgroupValue(filepath.Join(dir, "pids.max"))
+
+	return stats
+}
+
+// calculateCgroupRates 根据两次采集的计数器差值计算CPU使用率、限流比例及I/O速率
+func calculateCgroupRates(stats *CgroupStats, prev CgroupStats, elapsed float64) {
+	if stats.CPUUsageUsec >= prev.CPUUsageUsec {
+		stats.CPUPercent = float64(stats.CPUUsageUsec-prev.CPUUsageUsec) / (elapsed * 1e6) * 100
+	}
+	if stats.NrPeriods > prev.NrPeriods && stats.NrThrottled >= prev.NrThrottled {
+		stats.ThrottledPercent = float64(stats.NrThrottled-prev.NrThrottled) / float64(stats.NrPeriods-prev.NrPeriods) * 100
+	}
+	stats.IOReadBytesRate = uint64(counterRate(prev.IOReadBytes, stats.IOReadBytes, elapsed))
+	stats.IOWriteBytesRate = uint64(counterRate(prev.IOWriteBytes, stats.IOWriteBytes, elapsed))
+	stats.IOReadIOPS = counterRate(prev.IOReadOps, stats.IOReadOps, elapsed)
+	stats.IOWriteIOPS = counterRate(prev.IOWriteOps, stats.IOWriteOps, elapsed)
+}
+
+// readCgroupValue 读取只包含单个数值的cgroup文件，"max"表示不限制，返回0
+func readCgroupValue(file string) (uint64, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// readKeyValueFile 读取"key value"格式的cgroup文件，如cpu.stat和memory.events
+func readKeyValueFile(file string) (map[string]uint64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values, scanner.Err()
+}
+
+// readCgroupIOStat 读取io.stat并汇总所有设备的读写字节数和次数，格式如下:
+//
+//	8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0
+func readCgroupIOStat(file string) (rbytes, wbytes, rios, wios uint64, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbytes":
+				rbytes += n
+			case "wbytes":
+				wbytes += n
+			case "rios":
+				rios += n
+			case "wios":
+				wios += n
+			}
+		}
+	}
+	return rbytes, wbytes, rios, wios, scanner.Err()
+}
+
+// refreshContainers 通过容器运行时套接字刷新容器名称缓存
+func (cc *CgroupCollector) refreshContainers(now time.Time, force bool) {
+	minInterval := containerNameRefreshInterval
+	if force {
+		minInterval = containerNameForceRefreshInterval
+	}
+	if now.Sub(cc.containersRefresh) < minInterval {
+		return
+	}
+	cc.containersRefresh = now
+
+	containers := make(map[string]containerInfo)
+	for _, socket := range cc.options.RuntimeSockets {
+		if _, err := os.Stat(socket); err != nil {
+			continue
+		}
+		if err := listRuntimeContainers(cc.runtimeClient(socket), socket, containers); err != nil {
+			log.Printf("通过 %s 获取容器列表失败: %v", socket, err)
+		}
+	}
+	cc.containers = containers
+}
+
+// runtimeClient 返回指定容器运行时套接字的HTTP客户端，首次使用时创建并在之后的刷新中复用
+func (cc *CgroupCollector) runtimeClient(socket string) *http.Client {
+	if client, ok := cc.runtimeClients[socket]; ok {
+		return client
+	}
+	client := &http.Client{
+		Timeout: 2 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+			MaxIdleConns:    1,
+			IdleConnTimeout: 2 * containerNameRefreshInterval,
+		},
+	}
+	cc.runtimeClients[socket] = client
+	return client
+}
+
+// listRuntimeContainers 调用兼容Docker Engine API的套接字获取运行中的容器
+func listRuntimeContainers(client *http.Client, socket string, containers map[string]containerInfo) error {
+	resp, err := client.Get("http://localhost/containers/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("容器运行时返回状态码 %d", resp.StatusCode)
+	}
+
+	var list []struct {
+		ID    string   `json:"Id"`
+		Names []string `json:"Names"`
+		Image string   `json:"Image"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return fmt.Errorf("解析容器列表失败: %w", err)
+	}
+
+	runtime := strings.TrimSuffix(filepath.Base(socket), ".sock")
+	for _, c := range list {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		containers[c.ID] = containerInfo{name: name, image: c.Image, runtime: runtime}
+	}
+	return nil
+}
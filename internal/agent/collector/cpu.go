@@ -0,0 +1,249 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// CPUTimeStats 包含CPU时间占比(%)
+type CPUTimeStats struct {
+	Usage   float64 `json:"usage"`
+	User    float64 `json:"user"`
+	System  float64 `json:"system"`
+	Idle    float64 `json:"idle"`
+	Nice    float64 `json:"nice"`
+	Iowait  float64 `json:"iowait"`
+	Irq     float64 `json:"irq"`
+	Softirq float64 `json:"softirq"`
+	Steal   float64 `json:"steal"`
+	Guest   float64 `json:"guest"`
+}
+
+// cpuSample 单次采集的CPU累计时间及内核计数器
+type cpuSample struct {
+	total      cpu.TimesStat
+	perCPU     []cpu.TimesStat
+	ctxt       uint64
+	intr       uint64
+	hasKernel  bool
+	sampleTime time.Time
+}
+
+// cpuResult CPU采集结果
+type cpuResult struct {
+	total        CPUTimeStats
+	perCPU       map[string]CPUTimeStats
+	ctxSwitches  float64
+	interrupts   float64
+	hasRateStats bool
+}
+
+// applyTo 将CPU采集结果写入系统指标
+func (r *cpuResult) applyTo(stats *SystemStats) {
+	r.total.fill(stats.CPU)
+	if r.hasRateStats {
+		stats.CPU["ctx_switches_per_sec"] = r.ctxSwitches
+		stats.CPU["interrupts_per_sec"] = r.interrupts
+	}
+	if len(r.perCPU) > 0 {
+		stats.PerCPU = r.perCPU
+	}
+}
+
+// fill 将CPU时间占比写入上报使用的map
+func (c CPUTimeStats) fill(m map[string]float64) {
+	m["usage"] = c.Usage
+	m["user"] = c.User
+	m["system"] = c.System
+	m["idle"] = c.Idle
+	m["nice"] = c.Nice
+	m["iowait"] = c.Iowait
+	m["irq"] = c.Irq
+	m["softirq"] = c.Softirq
+	m["steal"] = c.Steal
+	m["guest"] = c.Guest
+}
+
+// collectCPUStats 根据两次采集之间的CPU时间差计算使用率
+// 首次采集时没有基准数据，先采样一次并等待sampleWindow
+func (sc *SystemCollector) collectCPUStats(ctx context.Context, sampleWindow time.Duration) (*cpuResult, error) {
+	prev := sc.lastCPUSample
+	if prev == nil {
+		first, err := sc.sampleCPU(ctx)
+		if err != nil {
+			return nil, err
+		}
+		prev = first
+
+		timer := time.NewTimer(sampleWindow)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+
+	cur, err := sc.sampleCPU(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sc.lastCPUSample = cur
+
+	result := &cpuResult{
+		total:  calculateCPUTimes(prev.total, cur.total),
+		perCPU: make(map[string]CPUTimeStats, len(cur.perCPU)),
+	}
+
+	prevPerCPU := make(map[string]cpu.TimesStat, len(prev.perCPU))
+	for _, t := range prev.perCPU {
+		prevPerCPU[t.CPU] = t
+	}
+	for _, t := range cur.perCPU {
+		// CPU热插拔时新上线的核心本次不计算
+		if p, exists := prevPerCPU[t.CPU]; exists {
+			result.perCPU[t.CPU] = calculateCPUTimes(p, t)
+		}
+	}
+
+	if prev.hasKernel && cur.hasKernel {
+		if elapsed := cur.sampleTime.Sub(prev.sampleTime).Seconds(); elapsed > 0 {
+			if cur.ctxt >= prev.ctxt {
+				result.ctxSwitches = float64(cur.ctxt-prev.ctxt) / elapsed
+			}
+			if cur.intr >= prev.intr {
+				result.interrupts = float64(cur.intr-prev.intr) / elapsed
+			}
+			result.hasRateStats = true
+		}
+	}
+
+	return result, nil
+}
+
+// sampleCPU 读取当前的CPU累计时间
+func (sc *SystemCollector) sampleCPU(ctx context.Context) (*cpuSample, error) {
+	totals, err := sc.sources.cpuTimes(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("获取CPU时间失败: %w", err)
+	}
+	if len(totals) == 0 {
+		return nil, fmt.Errorf("获取CPU时间失败: 结果为空")
+	}
+
+	sample := &cpuSample{
+		total:      totals[0],
+		sampleTime: time.Now(),
+	}
+
+	// 单核数据获取失败时只上报总体数据
+	if perCPU, err := sc.sources.cpuTimes(ctx, true); err == nil {
+		sample.perCPU = perCPU
+	}
+
+	// /proc/stat仅在Linux下存在，其他平台跳过上下文切换和中断统计
+	if ctxt, intr, err := readKernelCounters(sc.procRoot); err == nil {
+		sample.ctxt = ctxt
+		sample.intr = intr
+		sample.hasKernel = true
+	}
+
+	return sample, nil
+}
+
+// calculateCPUTimes 计算两次采样之间各类CPU时间的占比
+func calculateCPUTimes(prev, cur cpu.TimesStat) CPUTimeStats {
+	// Linux下user/nice已包含guest/guest_nice时间，需要扣除避免重复统计
+	user := (cur.User - cur.Guest) - (prev.User - prev.Guest)
+	nice := (cur.Nice - cur.GuestNice) - (prev.Nice - prev.GuestNice)
+	guest := (cur.Guest + cur.GuestNice) - (prev.Guest + prev.GuestNice)
+	system := cur.System - prev.System
+	idle := cur.Idle - prev.Idle
+	iowait := cur.Iowait - prev.Iowait
+	irq := cur.Irq - prev.Irq
+	softirq := cur.Softirq - prev.Softirq
+	steal := cur.Steal - prev.Steal
+
+	total := user + nice + guest + system + idle + iowait + irq + softirq + steal
+	if total <= 0 {
+		return CPUTimeStats{}
+	}
+
+	percent := func(v float64) float64 {
+		if v <= 0 {
+			return 0
+		}
+		return v / total * 100
+	}
+
+	stats := CPUTimeStats{
+		User:    percent(user),
+		System:  percent(system),
+		Idle:    percent(idle),
+		Nice:    percent(nice),
+		Iowait:  percent(iowait),
+		Irq:     percent(irq),
+		Softirq: percent(softirq),
+		Steal:   percent(steal),
+		Guest:   percent(guest),
+	}
+
+	// 与cpu.Percent保持一致，iowait不计入繁忙时间
+	stats.Usage = 100 - stats.Idle - stats.Iowait
+	if stats.Usage < 0 {
+		stats.Usage = 0
+	}
+
+	return stats
+}
+
+// readKernelCounters 从/proc/stat读取累计的上下文切换和中断次数
+func readKernelCounters(procRoot string) (ctxt uint64, intr uint64, err error) {
+	file, err := os.Open(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	var foundCtxt, foundIntr bool
+	scanner := bufio.NewScanner(file)
+	// intr行包含每个中断号的计数，可能很长
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "ctxt":
+			if ctxt, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+				return 0, 0, fmt.Errorf("解析ctxt失败: %w", err)
+			}
+			foundCtxt = true
+		case "intr":
+			// 第一列为所有中断的总数
+			if intr, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+				return 0, 0, fmt.Errorf("解析intr失败: %w", err)
+			}
+			foundIntr = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	if !foundCtxt || !foundIntr {
+		return 0, 0, fmt.Errorf("%s中缺少ctxt或intr字段", filepath.Join(procRoot, "stat"))
+	}
+
+	return ctxt, intr, nil
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// 默认忽略的虚拟块设备前缀
+var ignoredDiskIOPrefixes = []string{"loop", "ram", "zram", "fd", "sr"}
+
+// DiskIOStats 包含块设备的I/O统计信息
+type DiskIOStats struct {
+	// 累计计数器
+	ReadCount  uint64 `json:"read_count"`
+	WriteCount uint64 `json:"write_count"`
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
+
+	// 采集区间内的速率
+	ReadIOPS       float64 `json:"read_iops"`
+	WriteIOPS      float64 `json:"write_iops"`
+	ReadBytesRate  uint64  `json:"read_bytes_per_sec"`
+	WriteBytesRate uint64  `json:"write_bytes_per_sec"`
+
+	// 平均等待时间(毫秒)，区间内无I/O时为0
+	ReadAwait  float64 `json:"read_await"`
+	WriteAwait float64 `json:"write_await"`
+	Await      float64 `json:"await"`
+
+	// 设备繁忙时间占比(%)及平均队列深度
+	Utilization float64 `json:"utilization"`
+	QueueDepth  float64 `json:"queue_depth"`
+	InProgress  uint64  `json:"in_progress"`
+}
+
+// collectDiskIOStats 采集块设备I/O计数器并根据上次采集结果计算速率
+func (sc *SystemCollector) collectDiskIOStats(ctx context.Context, now time.Time) (map[string]DiskIOStats, error) {
+	counters, err := sc.sources.diskIOCounters(ctx, sc.diskDevices...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]DiskIOStats, len(counters))
+	timeDiff := now.Sub(sc.lastDiskIOTime).Seconds()
+
+	for name, counter := range counters {
+		if len(sc.diskDevices) == 0 && isIgnoredDiskDevice(name) {
+			continue
+		}
+
+		ioStats := DiskIOStats{
+			ReadCount:  counter.ReadCount,
+			WriteCount: counter.WriteCount,
+			ReadBytes:  counter.ReadBytes,
+			WriteBytes: counter.WriteBytes,
+			InProgress: counter.IopsInProgress,
+		}
+
+		// 首次采集或设备新出现时无法计算速率
+		if prev, exists := sc.lastDiskIOStats[name]; exists && timeDiff > 0 {
+			calculateDiskIORates(&ioStats, prev, counter, timeDiff)
+		}
+
+		result[name] = ioStats
+	}
+
+	// 保存当前采集结果，用于下次计算速率
+	sc.lastDiskIOStats = counters
+	sc.lastDiskIOTime = now
+
+	return result, nil
+}
+
+// calculateDiskIORates 根据两次采集的计数器差值计算I/O速率和延迟，timeDiff单位为秒
+func calculateDiskIORates(ioStats *DiskIOStats, prev, cur disk.IOCountersStat, timeDiff float64) {
+	if timeDiff <= 0 {
+		return
+	}
+	// 计数器回绕或设备重置时跳过本次计算
+	if cur.ReadCount < prev.ReadCount || cur.WriteCount < prev.WriteCount ||
+		cur.ReadBytes < prev.ReadBytes || cur.WriteBytes < prev.WriteBytes {
+		return
+	}
+
+	reads := cur.ReadCount - prev.ReadCount
+	writes := cur.WriteCount - prev.WriteCount
+
+	ioStats.ReadIOPS = float64(reads) / timeDiff
+	ioStats.WriteIOPS = float64(writes) / timeDiff
+	ioStats.ReadBytesRate = uint64(float64(cur.ReadBytes-prev.ReadBytes) / timeDiff)
+	ioStats.WriteBytesRate = uint64(float64(cur.WriteBytes-prev.WriteBytes) / timeDiff)
+
+	// ReadTime/WriteTime/IoTime/WeightedIO的单位均为毫秒
+	var readTime, writeTime uint64
+	if cur.ReadTime >= prev.ReadTime {
+		readTime = cur.ReadTime - prev.ReadTime
+	}
+	if cur.WriteTime >= prev.WriteTime {
+		writeTime = cur.WriteTime - prev.WriteTime
+	}
+	if reads > 0 {
+		ioStats.ReadAwait = float64(readTime) / float64(reads)
+	}
+	if writes > 0 {
+		ioStats.WriteAwait = float64(writeTime) / float64(writes)
+	}
+	if reads+writes > 0 {
+		ioStats.Await = float64(readTime+writeTime) / float64(reads+writes)
+	}
+
+	intervalMs := timeDiff * 1000
+	if cur.IoTime >= prev.IoTime {
+		ioStats.Utilization = float64(cur.IoTime-prev.IoTime) / intervalMs * 100
+		if ioStats.Utilization > 100 {
+			ioStats.Utilization = 100
+		}
+	}
+	if cur.WeightedIO >= prev.WeightedIO {
+		ioStats.QueueDepth = float64(cur.WeightedIO-prev.WeightedIO) / intervalMs
+	}
+}
+
+// isIgnoredDiskDevice 检查是否为默认忽略的虚拟块设备
+func isIgnoredDiskDevice(name string) bool {
+	for _, prefix := range ignoredDiskIOPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package collector
+
+import "time"
+
+// 事件级别
+const (
+	EventSeverityInfo     = "info"
+	EventSeverityWarning  = "warning"
+	EventSeverityCritical = "critical"
+)
+
+// Event 表示节点上检测到的状态变化，随指标一起上报给主控端
+type Event struct {
+	Type      string                 `json:"type"`
+	Severity  string                 `json:"severity"`
+	Source    string                 `json:"source"`
+	Message   string                 `json:"message"`
+	Timestamp time.Time              `json:"timestamp"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
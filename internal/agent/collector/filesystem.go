@@ -0,0 +1,257 @@
+package collector
+
+import (
+	"context"
+	"log"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// 自动发现时默认排除的文件系统类型（虚拟文件系统及只读镜像）
+var defaultExcludeFSTypes = []string{
+	"tmpfs", "devtmpfs", "overlay", "squashfs",
+	"proc", "sysfs", "cgroup", "cgroup2", "devpts", "mqueue", "debugfs", "tracefs",
+	"securityfs", "pstore", "bpf", "configfs", "fusectl", "hugetlbfs", "autofs",
+	"binfmt_misc", "rpc_pipefs", "nsfs", "ramfs", "efivarfs", "selinuxfs", "fuse.lxcfs",
+}
+
+// 自动发现时默认排除的挂载路径
+var defaultExcludeMounts = []string{
+	"/proc/**", "/sys/**", "/dev/**", "/run/**", "/snap/**", "/var/lib/docker/**",
+}
+
+// FilesystemOptions 文件系统采集选项
+type FilesystemOptions struct {
+	// 仅采集这些文件系统类型（为空表示不限制）
+	IncludeFSTypes []string
+	// 排除的文件系统类型（为空时使用默认列表）
+	ExcludeFSTypes []string
+	// 仅采集匹配这些路径模式的挂载点（为空表示不限制）
+	IncludeMounts []string
+	// 排除匹配这些路径模式的挂载点（为空时使用默认列表）
+	ExcludeMounts []string
+	// 是否上报已消失的挂载点
+	IncludeInactive bool
+}
+
+// mountTarget 本次需要采集的挂载点
+type mountTarget struct {
+	mountPoint string
+	device     string
+	fstype     string
+}
+
+// normalizeFilesystemOptions 为文件系统采集选项补充默认值
+func normalizeFilesystemOptions(opts FilesystemOptions) FilesystemOptions {
+	if len(opts.ExcludeFSTypes) == 0 {
+		opts.ExcludeFSTypes = defaultExcludeFSTypes
+	}
+	if len(opts.ExcludeMounts) == 0 {
+		opts.ExcludeMounts = defaultExcludeMounts
+	}
+	return opts
+}
+
+// collectFilesystemStats 采集文件系统容量及inode使用情况
+// 配置了挂载点时只采集指定挂载点，否则自动发现本机的分区
+func (sc *SystemCollector) collectFilesystemStats(ctx context.Context) map[string]DiskStats {
+	targets := sc.resolveMountTargets(ctx)
+	result := make(map[string]DiskStats, len(targets))
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	// 对每个挂载点并行收集，避免单个挂载点（如NFS）阻塞其他挂载点
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target mountTarget) {
+			defer wg.Done()
+
+			usage, err := sc.sources.diskUsage(ctx, target.mountPoint)
+			if err != nil {
+				log.Printf("获取磁盘挂载点 '%s' 的使用统计失败: %v", target.mountPoint, err)
+				return
+			}
+
+			fstype := usage.Fstype
+			if target.fstype != "" {
+				fstype = target.fstype
+			}
+
+			mutex.Lock()
+			result[target.mountPoint] = DiskStats{
+				Total:             usage.Total,
+				Used:              usage.Used,
+				Free:              usage.Free,
+				UsedPercent:       usage.UsedPercent,
+				FSType:            fstype,
+				Device:            target.device,
+				Mounted:           true,
+				InodesTotal:       usage.InodesTotal,
+				InodesUsed:        usage.InodesUsed,
+				InodesFree:        usage.InodesFree,
+				InodesUsedPercent: usage.InodesUsedPercent,
+			}
+			mutex.Unlock()
+		}(target)
+	}
+
+	wg.Wait()
+
+	sc.trackInactiveMounts(result)
+
+	return result
+}
+
+// resolveMountTargets 确定本次需要采集的挂载点
+func (sc *SystemCollector) resolveMountTargets(ctx context.Context) []mountTarget {
+	partitions, err := sc.sources.diskPartitions(ctx, true)
+
+	// 显式配置的挂载点
+	if len(sc.mountPoints) > 0 {
+		if err != nil {
+			log.Printf("获取磁盘分区列表失败: %v，无法确认配置的挂载点是否已挂载", err)
+			targets := make([]mountTarget, 0, len(sc.mountPoints))
+			for _, mountPoint := range sc.mountPoints {
+				targets = append(targets, mountTarget{mountPoint: mountPoint})
+			}
+			return targets
+		}
+		return matchMountTargets(partitions, sc.mountPoints)
+	}
+
+	if err != nil {
+		log.Printf("自动发现磁盘分区失败: %v，将使用默认的根目录('/')", err)
+		return []mountTarget{{mountPoint: "/"}}
+	}
+
+	return filterPartitions(partitions, sc.filesystemOptions)
+}
+
+// matchMountTargets 在分区列表中查找配置的挂载点，未挂载的路径不采集
+// 未挂载的路径上disk.Usage返回的是上级文件系统的容量，直接采集会误报为已挂载
+func matchMountTargets(partitions []disk.PartitionStat, mountPoints []string) []mountTarget {
+	// 同一路径挂载多次时以最后一次挂载为准
+	mounted := make(map[string]disk.PartitionStat, len(partitions))
+	for _, partition := range partitions {
+		mounted[partition.Mountpoint] = partition
+	}
+
+	targets := make([]mountTarget, 0, len(mountPoints))
+	for _, mountPoint := range mountPoints {
+		partition, ok := mounted[path.Clean(mountPoint)]
+		if !ok {
+			continue
+		}
+		targets = append(targets, mountTarget{
+			mountPoint: mountPoint,
+			device:     partition.Device,
+			fstype:     partition.Fstype,
+		})
+	}
+	return targets
+}
+
+// filterPartitions 按文件系统类型和挂载路径过滤分区
+// 同一设备挂载多次时（如bind mount）只保留路径最短的挂载点，避免重复统计容量
+func filterPartitions(partitions []disk.PartitionStat, opts FilesystemOptions) []mountTarget {
+	var targets []mountTarget
+	deviceIndex := make(map[string]int)
+
+	for _, partition := range partitions {
+		if !matchFSType(partition.Fstype, opts) || !matchMountPoint(partition.Mountpoint, opts) {
+			continue
+		}
+
+		target := mountTarget{
+			mountPoint: partition.Mountpoint,
+			device:     partition.Device,
+			fstype:     partition.Fstype,
+		}
+
+		if strings.HasPrefix(partition.Device, "/dev/") {
+			if idx, exists := deviceIndex[partition.Device]; exists {
+				if len(target.mountPoint) < len(targets[idx].mountPoint) {
+					targets[idx] = target
+				}
+				continue
+			}
+			deviceIndex[partition.Device] = len(targets)
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// matchFSType 检查文件系统类型是否满足过滤条件
+func matchFSType(fstype string, opts FilesystemOptions) bool {
+	if len(opts.IncludeFSTypes) > 0 && !containsString(opts.IncludeFSTypes, fstype) {
+		return false
+	}
+	return !containsString(opts.ExcludeFSTypes, fstype)
+}
+
+// matchMountPoint 检查挂载路径是否满足过滤条件
+func matchMountPoint(mountPoint string, opts FilesystemOptions) bool {
+	if len(opts.IncludeMounts) > 0 && !matchAnyMountGlob(opts.IncludeMounts, mountPoint) {
+		return false
+	}
+	return !matchAnyMountGlob(opts.ExcludeMounts, mountPoint)
+}
+
+// matchAnyMountGlob 检查挂载路径是否匹配任一路径模式
+// 支持path.Match语法，以"/**"结尾的模式匹配该目录本身及其所有子路径
+func matchAnyMountGlob(patterns []string, mountPoint string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/**") {
+			prefix := strings.TrimSuffix(pattern, "/**")
+			if mountPoint == prefix || strings.HasPrefix(mountPoint, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if matched, err := path.Match(pattern, mountPoint); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// trackInactiveMounts 记录已采集过的挂载点，并按配置补充已消失的挂载点
+func (sc *SystemCollector) trackInactiveMounts(current map[string]DiskStats) {
+	if sc.knownMounts == nil {
+		sc.knownMounts = make(map[string]DiskStats)
+	}
+
+	for mountPoint, stats := range current {
+		sc.knownMounts[mountPoint] = stats
+	}
+
+	if !sc.filesystemOptions.IncludeInactive {
+		return
+	}
+
+	for mountPoint, last := range sc.knownMounts {
+		if _, exists := current[mountPoint]; exists {
+			continue
+		}
+		// 已消失的挂载点只保留设备和类型信息，容量数据置零
+		current[mountPoint] = DiskStats{
+			FSType:  last.FSType,
+			Device:  last.Device,
+			Mounted: false,
+		}
+	}
+
+	// 显式配置但未挂载或从未采集成功的挂载点同样视为不活跃
+	for _, mountPoint := range sc.mountPoints {
+		if _, exists := current[mountPoint]; !exists {
+			current[mountPoint] = DiskStats{Mounted: false}
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package collector
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// 默认的硬件清单刷新间隔
+const defaultInventoryInterval = time.Hour
+
+// 硬件变化的事件类型
+const EventHardwareChanged = "hardware_changed"
+
+// HardwareInventory 包含节点的完整硬件清单，仅在注册及发生变化时上报
+type HardwareInventory struct {
+	System       SystemInfo    `json:"system"`
+	CPU          CPUInventory  `json:"cpu"`
+	MemoryTotal  uint64        `json:"memory_total"`
+	NUMANodes    []NUMANode    `json:"numa_nodes,omitempty"`
+	BlockDevices []BlockDevice `json:"block_devices,omitempty"`
+	NICs         []NICInfo     `json:"nics,omitempty"`
+	// 清单内容的摘要，用于检测硬件变化
+	Fingerprint string    `json:"fingerprint"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// SystemInfo 包含来自DMI的整机信息，读取序列号通常需要root权限
+type SystemInfo struct {
+	Vendor       string `json:"vendor,omitempty"`
+	Product      string `json:"product,omitempty"`
+	Serial       string `json:"serial,omitempty"`
+	BoardVendor  string `json:"board_vendor,omitempty"`
+	BoardProduct string `json:"board_product,omitempty"`
+	BIOSVendor   string `json:"bios_vendor,omitempty"`
+	BIOSVersion  string `json:"bios_version,omitempty"`
+	BIOSDate     string `json:"bios_date,omitempty"`
+}
+
+// CPUInventory 包含CPU型号及拓扑信息
+type CPUInventory struct {
+	Model         string  `json:"model"`
+	Vendor        string  `json:"vendor,omitempty"`
+	Sockets       int     `json:"sockets"`
+	PhysicalCores int     `json:"physical_cores"`
+	LogicalCores  int     `json:"logical_cores"`
+	MHz           float64 `json:"mhz,omitempty"`
+}
+
+// NUMANode 包含单个NUMA节点的信息
+type NUMANode struct {
+	ID          int    `json:"id"`
+	CPUs        string `json:"cpus"`
+	MemoryTotal uint64 `json:"memory_total"`
+}
+
+// BlockDevice 包含块设备信息
+type BlockDevice struct {
+	Name       string `json:"name"`
+	Model      string `json:"model,omitempty"`
+	Vendor     string `json:"vendor,omitempty"`
+	Size       uint64 `json:"size"`
+	Rotational bool   `json:"rotational"`
+	Removable  bool   `json:"removable"`
+}
+
+// NICInfo 包含网卡信息，PCIID格式为"厂商ID:设备ID"，虚拟网卡为空
+type NICInfo struct {
+	Name      string `json:"name"`
+	MAC       string `json:"mac,omitempty"`
+	Driver    string `json:"driver,omitempty"`
+	PCIID     string `json:"pci_id,omitempty"`
+	SpeedMbps int    `json:"speed_mbps"`
+	MTU       int    `json:"mtu"`
+	Duplex    string `json:"duplex,omitempty"`
+	OperState string `json:"operstate,omitempty"`
+	Virtual   bool   `json:"virtual"`
+}
+
+// InventoryCollector 硬件清单收集器，按刷新间隔重新采集并检测硬件变化
+type InventoryCollector struct {
+	mu       sync.Mutex
+	interval time.Duration
+
+	// 缓存的硬件清单
+	inventory *HardwareInventory
+	// 上次上报的清单摘要，为空表示尚未上报
+	reportedFingerprint string
+}
+
+// NewInventoryCollector 创建新的硬件清单收集器
+func NewInventoryCollector(interval time.Duration) *InventoryCollector {
+	if interval <= 0 {
+		interval = defaultInventoryInterval
+	}
+	return &InventoryCollector{interval: interval}
+}
+
+// Inventory 返回当前的硬件清单，超过刷新间隔时重新采集
+func (ic *InventoryCollector) Inventory(sysRoot string, now time.Time) *HardwareInventory {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	return ic.inventoryLocked(sysRoot, now)
+}
+
+// Collect 返回当前的硬件清单及需要上报的清单
+// 清单只在首次上报和发生变化时需要上报，硬件变化时同时生成事件
+func (ic *InventoryCollector) Collect(sysRoot string, now time.Time) (current, changed *HardwareInventory, events []Event) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	current = ic.inventoryLocked(sysRoot, now)
+	if current.Fingerprint == ic.reportedFingerprint {
+		return current, nil, nil
+	}
+
+	if ic.reportedFingerprint != "" {
+		events = append(events, Event{
+			Type:      EventHardwareChanged,
+			Severity:  EventSeverityWarning,
+			Source:    "hardware",
+			Message:   "节点硬件清单发生变化",
+			Timestamp: now,
+			Details: map[string]interface{}{
+				"old_fingerprint": ic.reportedFingerprint,
+				"new_fingerprint": current.Fingerprint,
+			},
+		})
+	}
+
+	ic.reportedFingerprint = current.Fingerprint
+	return current, current, events
+}
+
+// inventoryLocked 返回缓存的硬件清单，调用方需持有锁
+func (ic *InventoryCollector) inventoryLocked(sysRoot string, now time.Time) *HardwareInventory {
+	if ic.inventory == nil || now.Sub(ic.inventory.CollectedAt) >= ic.interval {
+		ic.inventory = buildHardwareInventory(sysRoot, now)
+	}
+	return ic.inventory
+}
+
+// HardwareInventory 返回当前的硬件清单，用于节点注册
+func (sc *SystemCollector) HardwareInventory() *HardwareInventory {
+	return sc.inventoryCollector.Inventory(sc.sysRoot, time.Now())
+}
+
+// collectInventory 采集硬件清单并填充硬件参数，返回硬件变化事件
+func (sc *SystemCollector) collectInventory(stats *SystemStats, now time.Time) []Event {
+	current, changed, events := sc.inventoryCollector.Collect(sc.sysRoot, now)
+
+	stats.Hardware.CPUModel = current.CPU.Model
+	stats.Hardware.CPUCores = current.CPU.PhysicalCores
+	stats.Hardware.CPUThreads = current.CPU.LogicalCores
+	stats.Hardware.CPUSockets = current.CPU.Sockets
+	stats.Hardware.MemoryTotal = current.MemoryTotal
+	stats.Inventory = changed
+
+	return events
+}
+
+// buildHardwareInventory 从sysfs和procfs采集硬件清单
+func buildHardwareInventory(sysRoot string, now time.Time) *HardwareInventory {
+	inventory := &HardwareInventory{
+		System:       readDMIInfo(filepath.Join(sysRoot, "class", "dmi", "id")),
+		CPU:          readCPUInventory(),
+		NUMANodes:    readNUMANodes(filepath.Join(sysRoot, "devices", "system", "node")),
+		BlockDevices: readBlockDevices(filepath.Join(sysRoot, "block")),
+		NICs:         readNICs(filepath.Join(sysRoot, "class", "net")),
+	}
+
+	if memStat, err := mem.VirtualMemory(); err == nil {
+		inventory.MemoryTotal = memStat.Total
+	}
+
+	inventory.Fingerprint = inventoryFingerprint(inventory)
+	inventory.CollectedAt = now
+
+	return inventory
+}
+
+// inventoryFingerprint 计算硬件清单的摘要
+// 摘要不包含采集时间、网卡链路状态和虚拟网卡，避免链路抖动或容器启停被误判为硬件变化
+func inventoryFingerprint(inventory *HardwareInventory) string {
+	stable := *inventory
+	stable.Fingerprint = ""
+	stable.CollectedAt = time.Time{}
+	stable.CPU.MHz = 0
+	stable.NICs = nil
+	for _, nic := range inventory.NICs {
+		if nic.Virtual {
+			continue
+		}
+		nic.SpeedMbps = 0
+		nic.Duplex = ""
+		nic.OperState = ""
+		stable.NICs = append(stable.NICs, nic)
+	}
+
+	data, err := json.Marshal(stable)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readDMIInfo 读取DMI整机信息，虚拟机或非x86平台上可能不存在
+func readDMIInfo(dmiDir string) SystemInfo {
+	return SystemInfo{
+		Vendor:       readSysfsString(filepath.Join(dmiDir, "sys_vendor")),
+		Product:      readSysfsString(filepath.Join(dmiDir, "product_name")),
+		Serial:       readSysfsString(filepath.Join(dmiDir, "product_serial")),
+		BoardVendor:  readSysfsString(filepath.Join(dmiDir, "board_vendor")),
+		BoardProduct: readSysfsString(filepath.Join(dmiDir, "board_name")),
+		BIOSVendor:   readSysfsString(filepath.Join(dmiDir, "bios_vendor")),
+		BIOSVersion:  readSysfsString(filepath.Join(dmiDir, "bios_version")),
+		BIOSDate:     readSysfsString(filepath.Join(dmiDir, "bios_date")),
+	}
+}
+
+// readCPUInventory 统计CPU插槽数、物理核心数和逻辑核心数
+func readCPUInventory() CPUInventory {
+	var inventory CPUInventory
+
+	infos, err := cpu.Info()
+	if err == nil && len(infos) > 0 {
+		inventory.Model = infos[0].ModelName
+		inventory.Vendor = infos[0].VendorID
+		inventory.MHz = infos[0].Mhz
+
+		sockets := make(map[string]struct{})
+		cores := make(map[string]struct{})
+		for _, info := range infos {
+			if info.PhysicalID == "" {
+				continue
+			}
+			sockets[info.PhysicalID] = struct{}{}
+			cores[info.PhysicalID+"/"+info.CoreID] = struct{}{}
+		}
+		inventory.Sockets = len(sockets)
+		inventory.PhysicalCores = len(cores)
+	}
+
+	// 部分平台（如ARM）的cpuinfo中没有拓扑信息，使用gopsutil的统计结果
+	if logical, err := cpu.Counts(true); err == nil {
+		inventory.LogicalCores = logical
+	}
+	if inventory.PhysicalCores == 0 {
+		if physical, err := cpu.Counts(false); err == nil {
+			inventory.PhysicalCores = physical
+		}
+	}
+	if inventory.Sockets == 0 && inventory.PhysicalCores > 0 {
+		inventory.Sockets = 1
+	}
+
+	return inventory
+}
+
+// readNUMANodes 读取NUMA节点的CPU列表和内存容量
+func readNUMANodes(nodeDir string) []NUMANode {
+	entries, err := os.ReadDir(nodeDir)
+	if err != nil {
+		return nil
+	}
+
+	var nodes []NUMANode
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "node") {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(name, "node"))
+		if err != nil {
+			continue
+		}
+
+		node := NUMANode{
+			ID:   id,
+			CPUs: readSysfsString(filepath.Join(nodeDir, name, "cpulist")),
+		}
+		node.MemoryTotal = readNUMAMemTotal(filepath.Join(nodeDir, name, "meminfo"))
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// readNUMAMemTotal 从NUMA节点的meminfo中读取内存总量，格式如"Node 0 MemTotal: 16303896 kB"
+func readNUMAMemTotal(path string) uint64 {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 4 && fields[2] == "MemTotal:" {
+			if kb, err := strconv.ParseUint(fields[3], 10, 64); err == nil {
+				return kb * 1024
+			}
+		}
+	}
+	return 0
+}
+
+// readBlockDevices 读取块设备型号、容量及是否为机械盘
+func readBlockDevices(blockDir string) []BlockDevice {
+	entries, err := os.ReadDir(blockDir)
+	if err != nil {
+		return nil
+	}
+
+	var devices []BlockDevice
+	for _, entry := range entries {
+		name := entry.Name()
+		if isIgnoredDiskDevice(name) {
+			continue
+		}
+
+		devDir := filepath.Join(blockDir, name)
+		device := BlockDevice{
+			Name:       name,
+			Model:      readSysfsString(filepath.Join(devDir, "device", "model")),
+			Vendor:     readSysfsString(filepath.Join(devDir, "device", "vendor")),
+			Rotational: readSysfsString(filepath.Join(devDir, "queue", "rotational")) == "1",
+			Removable:  readSysfsString(filepath.Join(devDir, "removable")) == "1",
+		}
+
+		// size的单位固定为512字节的扇区
+		if sectors, err := strconv.ParseUint(readSysfsString(filepath.Join(devDir, "size")), 10, 64); err == nil {
+			device.Size = sectors * 512
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices
+}
+
+// readNICs 读取网卡的驱动、链路速率和MTU
+func readNICs(netDir string) []NICInfo {
+	entries, err := os.ReadDir(netDir)
+	if err != nil {
+		return nil
+	}
+
+	var nics []NICInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == "lo" {
+			continue
+		}
+
+		ifaceDir := filepath.Join(netDir, name)
+		nic := NICInfo{
+			Name:      name,
+			MAC:       readSysfsString(filepath.Join(ifaceDir, "address")),
+			Duplex:    readSysfsString(filepath.Join(ifaceDir, "duplex")),
+			OperState: readSysfsString(filepath.Join(ifaceDir, "operstate")),
+			SpeedMbps: -1,
+		}
+
+		if mtu, err := strconv.Atoi(readSysfsString(filepath.Join(ifaceDir, "mtu"))); err == nil {
+			nic.MTU = mtu
+		}
+		// 链路断开或虚拟网卡读取speed会失败，保持为-1
+		if speed, err := strconv.Atoi(readSysfsString(filepath.Join(ifaceDir, "speed"))); err == nil {
+			nic.SpeedMbps = speed
+		}
+
+		// 虚拟网卡（如bridge、veth）位于/sys/devices/virtual下且没有device链接
+		if isVirtualNIC(ifaceDir) {
+			nic.Virtual = true
+		} else {
+			if driver, err := os.Readlink(filepath.Join(ifaceDir, "device", "driver")); err == nil {
+				nic.Driver = filepath.Base(driver)
+			}
+			vendorID := readSysfsString(filepath.Join(ifaceDir, "device", "vendor"))
+			deviceID := readSysfsString(filepath.Join(ifaceDir, "device", "device"))
+			if vendorID != "" && deviceID != "" {
+				nic.PCIID = fmt.Sprintf("%s:%s", strings.TrimPrefix(vendorID, "0x"), strings.TrimPrefix(deviceID, "0x"))
+			}
+		}
+
+		nics = append(nics, nic)
+	}
+
+	return nics
+}
+
+// isVirtualNIC 判断网卡是否为虚拟网卡
+func isVirtualNIC(ifaceDir string) bool {
+	if target, err := filepath.EvalSymlinks(ifaceDir); err == nil &&
+		strings.Contains(filepath.ToSlash(target), "/devices/virtual/") {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(ifaceDir, "device"))
+	return err != nil
+}
+
+// readSysfsString 读取sysfs文件内容并去除空白，读取失败时返回空字符串
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,415 @@
+package collector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 文件完整性变化的事件类型
+const (
+	EventFileAdded    = "file_added"
+	EventFileRemoved  = "file_removed"
+	EventFileModified = "file_modified"
+)
+
+const (
+	defaultIntegrityInterval = 5 * time.Minute
+	// 单次扫描最多处理的文件数，避免配置的目录过大
+	maxIntegrityFiles = 10000
+	// 超过该大小的文件不计算哈希，只比较大小和修改时间
+	maxIntegrityHashSize = 256 * 1024 * 1024
+)
+
+// IntegrityOptions 文件完整性监控选项
+type IntegrityOptions struct {
+	// 需要监控的文件或目录，目录会被递归扫描，不跟随符号链接
+	Paths []string
+	// 扫描间隔
+	Interval time.Duration
+	// 保存基线的文件，代理重启后与保存的基线比较，停止期间的变化同样会被上报；为空时每次启动重新建立基线
+	StateFile string
+}
+
+// FileIntegrityEntry 单个文件的基线记录
+type FileIntegrityEntry struct {
+	// 内容的SHA-256哈希，符号链接为"symlink:"加目标路径，过大或特殊文件为空
+	Hash    string    `json:"hash"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	UID     uint32    `json:"uid"`
+	GID     uint32    `json:"gid"`
+	Owner   string    `json:"owner"`
+	ModTime time.Time `json:"mod_time"`
+	// 读取失败的原因，此时沿用上一次的哈希
+	Error string `json:"error,omitempty"`
+}
+
+// IntegrityStats 文件完整性监控的汇总信息
+type IntegrityStats struct {
+	// 监控中的文件数
+	Files int `json:"files"`
+	// 最近一次扫描的时间及耗时
+	LastScan       time.Time `json:"last_scan"`
+	ScanDurationMs float64   `json:"scan_duration_ms"`
+	// 最近一次扫描检测到的变化数
+	Added    int `json:"added"`
+	Removed  int `json:"removed"`
+	Modified int `json:"modified"`
+	// 读取失败的文件数
+	Errors int `json:"errors"`
+}
+
+// IntegrityCollector 文件完整性监控收集器
+// 扫描在后台按间隔执行，检测到的变化以事件形式在下一次Collect时返回
+type IntegrityCollector struct {
+	mu      sync.Mutex
+	options IntegrityOptions
+
+	running  bool
+	lastRun  time.Time
+	baseline map[string]FileIntegrityEntry
+	stats    *IntegrityStats
+	pending  []Event
+}
+
+// NewIntegrityCollector 创建新的文件完整性监控收集器，并加载保存的基线
+func NewIntegrityCollector(opts IntegrityOptions) *IntegrityCollector {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultIntegrityInterval
+	}
+	return &IntegrityCollector{
+		options:  opts,
+		baseline: loadIntegrityBaseline(opts.StateFile),
+	}
+}
+
+// Collect 在到期时启动后台扫描，返回最近一次扫描的汇总及尚未上报的变化事件，未配置路径时返回nil
+func (ic *IntegrityCollector) Collect(now time.Time) (*IntegrityStats, []Event) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	if len(ic.options.Paths) == 0 {
+		return nil, nil
+	}
+
+	if !ic.running && (ic.lastRun.IsZero() || now.Sub(ic.lastRun) >= ic.options.Interval) {
+		ic.running = true
+		ic.lastRun = now
+		go ic.scan(context.Background())
+	}
+
+	events := ic.pending
+	ic.pending = nil
+	if ic.stats == nil {
+		return nil, events
+	}
+	stats := *ic.stats
+	return &stats, events
+}
+
+// scan 扫描所有配置的路径并与基线比较，没有基线时只建立基线
+func (ic *IntegrityCollector) scan(ctx context.Context) {
+	start := time.Now()
+	current, errCount := scanIntegrityPaths(ctx, ic.options.Paths)
+
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.running = false
+
+	stats := &IntegrityStats{
+		Files:          len(current),
+		LastScan:       start,
+		ScanDurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		Errors:         errCount,
+	}
+
+	var events []Event
+	if ic.baseline != nil {
+		events = diffIntegrity(ic.baseline, current, start)
+		for _, event := range events {
+			switch event.Type {
+			case EventFileAdded:
+				stats.Added++
+			case EventFileRemoved:
+				stats.Removed++
+			case EventFileModified:
+				stats.Modified++
+			}
+		}
+	}
+	ic.pending = append(ic.pending, events...)
+	ic.stats = stats
+
+	if ic.options.StateFile != "" && (ic.baseline == nil || !sameIntegrityBaseline(ic.baseline, current)) {
+		if err := writeFileAtomic(ic.options.StateFile, current); err != nil {
+			log.Printf("保存文件完整性基线失败: %v", err)
+		}
+	}
+	ic.baseline = current
+}
+
+// scanIntegrityPaths 计算所有配置路径下文件的基线记录，返回记录及读取失败的文件数
+func scanIntegrityPaths(ctx context.Context, paths []string) (map[string]FileIntegrityEntry, int) {
+	entries := make(map[string]FileIntegrityEntry)
+	owners := make(map[string]string)
+	errCount := 0
+
+	add := func(path string, info fs.FileInfo) bool {
+		if _, exists := entries[path]; exists {
+			return true
+		}
+		if len(entries) >= maxIntegrityFiles {
+			log.Printf("完整性监控的文件数超过上限%d，其余文件已忽略", maxIntegrityFiles)
+			return false
+		}
+		entry := newIntegrityEntry(path, info, owners)
+		if entry.Error != "" {
+			errCount++
+		}
+		entries[path] = entry
+		return true
+	}
+
+	for _, root := range paths {
+		root = filepath.Clean(root)
+		info, err := os.Lstat(root)
+		if err != nil {
+			// 不存在的路径视为没有文件，之前存在的文件会被报告为删除
+			if !os.IsNotExist(err) {
+				errCount++
+			}
+			continue
+		}
+		if !info.IsDir() {
+			if !add(root, info) {
+				break
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				errCount++
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				errCount++
+				return nil
+			}
+			if !add(path, info) {
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if err != nil {
+			break
+		}
+	}
+
+	return entries, errCount
+}
+
+// newIntegrityEntry 生成单个文件的基线记录
+func newIntegrityEntry(path string, info fs.FileInfo, owners map[string]string) FileIntegrityEntry {
+	entry := FileIntegrityEntry{
+		Size:    info.Size(),
+		Mode:    info.Mode().String(),
+		ModTime: info.ModTime(),
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		entry.UID = stat.Uid
+		entry.GID = stat.Gid
+	}
+	entry.Owner = lookupOwner(entry.UID, entry.GID, owners)
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.Hash = "symlink:" + target
+		}
+	case info.Mode().IsRegular() && info.Size() <= maxIntegrityHashSize:
+		hash, err := hashFile(path)
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.Hash = hash
+		}
+	}
+	return entry
+}
+
+// hashFile 计算文件内容的SHA-256哈希
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lookupOwner 将uid和gid解析为"用户:组"，无法解析时使用数字
+func lookupOwner(uid, gid uint32, cache map[string]string) string {
+	key := fmt.Sprintf("%d:%d", uid, gid)
+	if owner, ok := cache[key]; ok {
+		return owner
+	}
+
+	userName := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(userName); err == nil {
+		userName = u.Username
+	}
+	groupName := strconv.FormatUint(uint64(gid), 10)
+	if g, err := user.LookupGroupId(groupName); err == nil {
+		groupName = g.Name
+	}
+
+	owner := userName + ":" + groupName
+	cache[key] = owner
+	return owner
+}
+
+// diffIntegrity 比较基线与本次扫描的结果，按路径排序生成变化事件
+// 读取失败的文件沿用基线中的哈希，避免读取错误被误报为内容变化
+func diffIntegrity(baseline, current map[string]FileIntegrityEntry, now time.Time) []Event {
+	var events []Event
+
+	for path, entry := range current {
+		old, ok := baseline[path]
+		if !ok {
+			events = append(events, integrityEvent(EventFileAdded, path, nil, &entry, nil, now))
+			continue
+		}
+
+		if entry.Error != "" {
+			entry.Hash = old.Hash
+			current[path] = entry
+		}
+
+		var changes []string
+		if entry.Hash != old.Hash || (entry.Hash == "" && (entry.Size != old.Size || !entry.ModTime.Equal(old.ModTime))) {
+			changes = append(changes, "content")
+		}
+		if entry.Mode != old.Mode {
+			changes = append(changes, "mode")
+		}
+		if entry.UID != old.UID || entry.GID != old.GID {
+			changes = append(changes, "owner")
+		}
+		if len(changes) > 0 {
+			events = append(events, integrityEvent(EventFileModified, path, &old, &entry, changes, now))
+		}
+	}
+
+	for path, old := range baseline {
+		if _, ok := current[path]; !ok {
+			events = append(events, integrityEvent(EventFileRemoved, path, &old, nil, nil, now))
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Details["path"].(string) < events[j].Details["path"].(string)
+	})
+	return events
+}
+
+// integrityEvent 生成文件变化事件，详情中包含变化前后的哈希、权限和属主
+func integrityEvent(eventType, path string, old, current *FileIntegrityEntry, changes []string, now time.Time) Event {
+	details := map[string]interface{}{"path": path}
+	var message string
+
+	switch eventType {
+	case EventFileAdded:
+		message = fmt.Sprintf("新增文件 %s", path)
+	case EventFileRemoved:
+		message = fmt.Sprintf("文件 %s 已被删除", path)
+	default:
+		message = fmt.Sprintf("文件 %s 已被修改 (%v)", path, changes)
+		details["changes"] = changes
+	}
+
+	if old != nil {
+		details["old_hash"] = old.Hash
+		details["old_mode"] = old.Mode
+		details["old_owner"] = old.Owner
+	}
+	if current != nil {
+		details["new_hash"] = current.Hash
+		details["new_mode"] = current.Mode
+		details["new_owner"] = current.Owner
+		details["size"] = current.Size
+	}
+
+	return Event{
+		Type:      eventType,
+		Severity:  EventSeverityWarning,
+		Source:    "integrity",
+		Message:   message,
+		Timestamp: now,
+		Details:   details,
+	}
+}
+
+// sameIntegrityBaseline 判断两次扫描的记录是否一致，修改时间的变化也需要保存
+func sameIntegrityBaseline(a, b map[string]FileIntegrityEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, entry := range a {
+		other, ok := b[path]
+		if !ok || entry.Hash != other.Hash || entry.Size != other.Size || entry.Mode != other.Mode ||
+			entry.UID != other.UID || entry.GID != other.GID || !entry.ModTime.Equal(other.ModTime) || entry.Error != other.Error {
+			return false
+		}
+	}
+	return true
+}
+
+// loadIntegrityBaseline 读取保存的基线，不存在时返回nil，首次扫描只建立基线
+func loadIntegrityBaseline(stateFile string) map[string]FileIntegrityEntry {
+	if stateFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("读取文件完整性基线失败: %v", err)
+		}
+		return nil
+	}
+	var baseline map[string]FileIntegrityEntry
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		log.Printf("解析文件完整性基线失败: %v", err)
+		return nil
+	}
+	return baseline
+}
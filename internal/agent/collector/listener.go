@@ -0,0 +1,187 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	psnet "github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// 监听端口变化的事件类型
+const (
+	EventListenerAdded   = "listener_added"
+	EventListenerRemoved = "listener_removed"
+)
+
+// ListenerInfo 包含一个监听中的套接字信息
+type ListenerInfo struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     uint32 `json:"port"`
+	PID      int32  `json:"pid"`
+	Process  string `json:"process,omitempty"`
+}
+
+// key 返回监听套接字的唯一标识
+func (l ListenerInfo) key() string {
+	return fmt.Sprintf("%s/%s:%d", l.Protocol, l.Address, l.Port)
+}
+
+// ListenerCollector 监听端口收集器，检测监听端口的新增和消失
+type ListenerCollector struct {
+	mu      sync.Mutex
+	enabled bool
+
+	// 上次采集到的监听套接字
+	known map[string]ListenerInfo
+	// 是否已建立基线，首次采集不产生事件
+	initialized bool
+}
+
+// NewListenerCollector 创建新的监听端口收集器
+func NewListenerCollector(enabled bool) *ListenerCollector {
+	return &ListenerCollector{
+		enabled: enabled,
+		known:   make(map[string]ListenerInfo),
+	}
+}
+
+// Collect 从连接列表中提取监听套接字，并与上次结果对比生成变化事件
+// 未启用时返回nil
+func (lc *ListenerCollector) Collect(connections []psnet.ConnectionStat) ([]ListenerInfo, []Event) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if !lc.enabled {
+		return nil, nil
+	}
+
+	now := time.Now()
+	current := make(map[string]ListenerInfo)
+	processNames := make(map[int32]string)
+
+	for _, conn := range connections {
+		protocol, ok := listenerProtocol(conn)
+		if !ok {
+			continue
+		}
+
+		listener := ListenerInfo{
+			Protocol: protocol,
+			Address:  conn.Laddr.IP,
+			Port:     conn.Laddr.Port,
+			PID:      conn.Pid,
+		}
+
+		// 开启SO_REUSEPORT时同一地址可能有多个套接字，只保留一个
+		key := listener.key()
+		if _, exists := current[key]; exists {
+			continue
+		}
+
+		if conn.Pid > 0 {
+			name, cached := processNames[conn.Pid]
+			if !cached {
+				if p, err := process.NewProcess(conn.Pid); err == nil {
+					name, _ = p.Name()
+				}
+				processNames[conn.Pid] = name
+			}
+			listener.Process = name
+		}
+
+		current[key] = listener
+	}
+
+	var events []Event
+	if lc.initialized {
+		for key, listener := range current {
+			if _, exists := lc.known[key]; !exists {
+				events = append(events, newListenerEvent(EventListenerAdded, EventSeverityWarning, listener, now))
+			}
+		}
+		for key, listener := range lc.known {
+			if _, exists := current[key]; !exists {
+				events = append(events, newListenerEvent(EventListenerRemoved, EventSeverityInfo, listener, now))
+			}
+		}
+	}
+
+	lc.known = current
+	lc.initialized = true
+
+	listeners := make([]ListenerInfo, 0, len(current))
+	for _, listener := range current {
+		listeners = append(listeners, listener)
+	}
+	sort.Slice(listeners, func(i, j int) bool {
+		if listeners[i].Port != listeners[j].Port {
+			return listeners[i].Port < listeners[j].Port
+		}
+		return listeners[i].key() < listeners[j].key()
+	})
+
+	return listeners, events
+}
+
+// listenerProtocol 判断连接是否为监听套接字并返回协议名称
+// TCP以LISTEN状态为准，UDP以未连接远端的已绑定套接字为准
+func listenerProtocol(conn psnet.ConnectionStat) (string, bool) {
+	var protocol string
+	switch conn.Type {
+	case syscall.SOCK_STREAM:
+		if conn.Status != "LISTEN" {
+			return "", false
+		}
+		protocol = "tcp"
+	case syscall.SOCK_DGRAM:
+		if conn.Raddr.Port != 0 || conn.Laddr.Port == 0 {
+			return "", false
+		}
+		protocol = "udp"
+	default:
+		return "", false
+	}
+
+	switch conn.Family {
+	case syscall.AF_INET:
+		return protocol, true
+	case syscall.AF_INET6:
+		return protocol + "6", true
+	default:
+		// 忽略unix域套接字
+		return "", false
+	}
+}
+
+// newListenerEvent 创建监听端口变化事件
+func newListenerEvent(eventType, severity string, listener ListenerInfo, now time.Time) Event {
+	action := "新增"
+	if eventType == EventListenerRemoved {
+		action = "关闭"
+	}
+
+	processName := listener.Process
+	if processName == "" {
+		processName = "未知进程"
+	}
+
+	return Event{
+		Type:      eventType,
+		Severity:  severity,
+		Source:    "listener",
+		Message:   fmt.Sprintf("%s监听端口 %s %s:%d (%s, PID %d)", action, listener.Protocol, listener.Address, listener.Port, processName, listener.PID),
+		Timestamp: now,
+		Details: map[string]interface{}{
+			"protocol": listener.Protocol,
+			"address":  listener.Address,
+			"port":     listener.Port,
+			"pid":      listener.PID,
+			"process":  listener.Process,
+		},
+	}
+}
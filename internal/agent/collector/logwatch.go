@@ -0,0 +1,433 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// 每个文件每次采集默认最多上报的匹配行样本数
+	defaultLogMaxSamples = 5
+	// 样本行的最大长度，超出部分被截断
+	maxLogSampleLength = 512
+	// 单行的最大长度，超长的行只匹配前面的部分
+	maxLogLineLength = 64 * 1024
+	// 每个文件每次采集最多读取的字节数，剩余内容在下次采集时读取
+	maxLogReadPerCollect = 32 * 1024 * 1024
+	// 读取位置的保存间隔，代理异常退出时最多重复统计这段时间内的日志
+	logOffsetSaveInterval = 10 * time.Second
+	// 读取时每处理这么多行检查一次是否已被取消
+	logCancelCheckLines = 256
+)
+
+// LogPattern 需要计数的日志模式
+type LogPattern struct {
+	// 模式名称，作为计数的键
+	Name  string
+	Regex string
+}
+
+// LogWatchConfig 单个日志文件的监控配置
+type LogWatchConfig struct {
+	// 名称，作为logs中的键，为空时使用文件路径
+	Name string
+	Path string
+	// 需要计数的模式
+	Patterns []LogPattern
+	// 每次采集最多上报的匹配行样本数
+	MaxSamples int
+	// 附加到结果上的标签
+	Labels map[string]string
+}
+
+// LogWatchOptions 日志监控选项
+type LogWatchOptions struct {
+	Files []LogWatchConfig
+	// 保存读取位置的文件，代理重启后从上次的位置继续读取，为空时不保存
+	StateFile string
+}
+
+// LogSample 匹配行样本
+type LogSample struct {
+	Pattern string `json:"pattern"`
+	Line    string `json:"line"`
+}
+
+// LogWatchStats 包含单个日志文件在本次采集间隔内的统计
+type LogWatchStats struct {
+	Path string `json:"path"`
+	// 各模式在本次间隔内的匹配行数
+	Counts map[string]uint64 `json:"counts"`
+	// 本次间隔内读取的行数和字节数
+	Lines uint64 `json:"lines"`
+	Bytes uint64 `json:"bytes"`
+	// 距上次采集的秒数，用于计算每分钟的匹配数
+	IntervalSeconds float64     `json:"interval_seconds"`
+	Samples         []LogSample `json:"samples,omitempty"`
+	// 本次间隔内检测到文件轮转或截断
+	Rotated   bool `json:"rotated,omitempty"`
+	Truncated bool `json:"truncated,omitempty"`
+	// 文件不存在或无法读取的原因
+	Error  string            `json:"error,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// logOffset 持久化的读取位置
+type logOffset struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// logPattern 编译后的日志模式
+type logPattern struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// logWatchState 单个日志文件的跟踪状态
+type logWatchState struct {
+	config   LogWatchConfig
+	patterns []logPattern
+
+	file   *os.File
+	inode  uint64
+	offset int64
+	// 上一次读到的行超过长度上限，跳过该行的剩余部分
+	skipping bool
+}
+
+// LogWatchCollector 日志文件监控收集器
+// 跟踪文件追加的内容并按模式计数，能够处理logrotate的重命名和copytruncate两种轮转方式
+type LogWatchCollector struct {
+	mu        sync.Mutex
+	stateFile string
+	watches   []*logWatchState
+	saved     map[string]logOffset
+	lastSave  time.Time
+	lastRun   time.Time
+	// 已读取但尚未上报的统计，采集被取消时保留到下一次采集一起上报
+	pending map[string]*LogWatchStats
+}
+
+// NewLogWatchCollector 创建新的日志监控收集器，无效的配置会被忽略
+func NewLogWatchCollector(opts LogWatchOptions) *LogWatchCollector {
+	lc := &LogWatchCollector{
+		stateFile: opts.StateFile,
+		saved:     loadLogOffsets(opts.StateFile),
+		pending:   make(map[string]*LogWatchStats),
+	}
+
+	seen := make(map[string]bool)
+	for _, cfg := range opts.Files {
+		if cfg.Path == "" {
+			log.Printf("忽略未配置路径的日志监控: %s", cfg.Name)
+			continue
+		}
+		if cfg.Name == "" {
+			cfg.Name = cfg.Path
+		}
+		if seen[cfg.Name] {
+			log.Printf("忽略重复的日志监控: %s", cfg.Name)
+			continue
+		}
+		if cfg.MaxSamples <= 0 {
+			cfg.MaxSamples = defaultLogMaxSamples
+		}
+
+		state := &logWatchState{config: cfg}
+		valid := true
+		for _, p := range cfg.Patterns {
+			pattern, err := regexp.Compile(p.Regex)
+			if err != nil {
+				log.Printf("忽略日志监控 %s: 模式 %s 无效: %v", cfg.Name, p.Name, err)
+				valid = false
+				break
+			}
+			name := p.Name
+			if name == "" {
+				name = p.Regex
+			}
+			state.patterns = append(state.patterns, logPattern{name: name, pattern: pattern})
+		}
+		if !valid {
+			continue
+		}
+
+		seen[cfg.Name] = true
+		lc.watches = append(lc.watches, state)
+	}
+
+	return lc
+}
+
+// Collect 读取各文件自上次采集以来追加的内容并计数，未配置文件时返回nil
+// ctx被取消时停止读取并返回nil，已读取部分的计数保留到下一次采集一起上报，
+// 因此超时被放弃的采集不会丢失已经越过的日志内容
+func (lc *LogWatchCollector) Collect(ctx context.Context, now time.Time) map[string]LogWatchStats {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if len(lc.watches) == 0 {
+		return nil
+	}
+
+	for _, w := range lc.watches {
+		if ctx.Err() != nil {
+			break
+		}
+
+		stats, ok := lc.pending[w.config.Name]
+		if !ok {
+			stats = &LogWatchStats{
+				Path:   w.config.Path,
+				Counts: make(map[string]uint64, len(w.patterns)),
+				Labels: w.config.Labels,
+			}
+			for _, p := range w.patterns {
+				stats.Counts[p.name] = 0
+			}
+			lc.pending[w.config.Name] = stats
+		}
+
+		stats.Error = ""
+		if err := lc.follow(ctx, w, stats); err != nil && ctx.Err() == nil {
+			stats.Error = err.Error()
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	var interval float64
+	if !lc.lastRun.IsZero() {
+		interval = now.Sub(lc.lastRun).Seconds()
+	}
+	lc.lastRun = now
+
+	results := make(map[string]LogWatchStats, len(lc.pending))
+	for name, stats := range lc.pending {
+		stats.IntervalSeconds = interval
+		results[name] = *stats
+	}
+	lc.pending = make(map[string]*LogWatchStats, len(lc.watches))
+
+	if now.Sub(lc.lastSave) >= logOffsetSaveInterval {
+		lc.saveOffsets()
+		lc.lastSave = now
+	}
+	return results
+}
+
+// follow 检查文件是否轮转或截断，并读取新追加的内容
+func (lc *LogWatchCollector) follow(ctx context.Context, w *logWatchState, stats *LogWatchStats) error {
+	info, err := os.Stat(w.config.Path)
+	if err != nil {
+		// 文件被重命名后新文件尚未创建，继续读取旧文件中追加的内容
+		if w.file != nil {
+			w.read(ctx, stats, false)
+		}
+		return err
+	}
+	inode := fileInode(info)
+
+	if w.file != nil && inode != w.inode {
+		// 文件已轮转（重命名后重新创建），读完旧文件后切换到新文件；
+		// 旧文件未读完就被取消时保持打开，下次采集继续读取
+		if !w.read(ctx, stats, true) {
+			return ctx.Err()
+		}
+		w.close()
+		stats.Rotated = true
+		return w.open(ctx, info, 0, stats)
+	}
+
+	if w.file == nil {
+		// 首次打开时从保存的位置继续读取；没有记录时从文件末尾开始，不统计历史内容
+		offset := info.Size()
+		if saved, ok := lc.saved[w.config.Path]; ok {
+			if saved.Inode == inode && saved.Offset <= info.Size() {
+				offset = saved.Offset
+			} else {
+				// 代理停止期间文件已轮转，新文件从头读取
+				offset = 0
+				stats.Rotated = true
+			}
+		}
+		return w.open(ctx, info, offset, stats)
+	}
+
+	if info.Size() < w.offset {
+		// 文件被截断（copytruncate），从头读取
+		w.offset = 0
+		w.skipping = false
+		stats.Truncated = true
+	}
+	w.read(ctx, stats, false)
+	return nil
+}
+
+// open 打开文件并从指定位置读取
+func (w *logWatchState) open(ctx context.Context, info os.FileInfo, offset int64, stats *LogWatchStats) error {
+	f, err := os.Open(w.config.Path)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	w.inode = fileInode(info)
+	w.offset = offset
+	w.skipping = false
+	w.read(ctx, stats, false)
+	return nil
+}
+
+// close 关闭当前跟踪的文件
+func (w *logWatchState) close() {
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+}
+
+// read 从当前位置读取完整的行并计数，final为true时文件不会再增长，末尾不完整的行也会被处理
+// ctx被取消时停止读取并返回false，读取位置停在最后处理的行之后
+func (w *logWatchState) read(ctx context.Context, stats *LogWatchStats, final bool) bool {
+	if _, err := w.file.Seek(w.offset, io.SeekStart); err != nil {
+		return true
+	}
+
+	reader := bufio.NewReaderSize(io.LimitReader(w.file, maxLogReadPerCollect), maxLogLineLength)
+	for n := 0; ; n++ {
+		if n%logCancelCheckLines == 0 && ctx.Err() != nil {
+			return false
+		}
+
+		line, err := reader.ReadSlice('\n')
+		if errors.Is(err, io.EOF) && !final {
+			// 不完整的行留到下次读取
+			return true
+		}
+		if len(line) == 0 {
+			return true
+		}
+
+		w.offset += int64(len(line))
+		stats.Bytes += uint64(len(line))
+
+		if w.skipping {
+			// 超长行的剩余部分已在之前计数
+			w.skipping = errors.Is(err, bufio.ErrBufferFull)
+			continue
+		}
+		w.skipping = errors.Is(err, bufio.ErrBufferFull)
+
+		stats.Lines++
+		w.match(bytes.TrimRight(line, "\r\n"), stats)
+
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			return true
+		}
+	}
+}
+
+// match 按各模式匹配一行，一行匹配多个模式时分别计数
+func (w *logWatchState) match(line []byte, stats *LogWatchStats) {
+	for _, p := range w.patterns {
+		if !p.pattern.Match(line) {
+			continue
+		}
+		stats.Counts[p.name]++
+		if len(stats.Samples) < w.config.MaxSamples {
+			sample := line
+			if len(sample) > maxLogSampleLength {
+				sample = sample[:maxLogSampleLength]
+			}
+			stats.Samples = append(stats.Samples, LogSample{Pattern: p.name, Line: string(sample)})
+		}
+	}
+}
+
+// fileInode 返回文件的设备号和inode组合，用于识别文件是否被替换
+func fileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Dev)<<48 ^ stat.Ino
+	}
+	return 0
+}
+
+// loadLogOffsets 读取保存的读取位置
+func loadLogOffsets(stateFile string) map[string]logOffset {
+	offsets := make(map[string]logOffset)
+	if stateFile == "" {
+		return offsets
+	}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("读取日志读取位置失败: %v", err)
+		}
+		return offsets
+	}
+	if err := json.Unmarshal(data, &offsets); err != nil {
+		log.Printf("解析日志读取位置失败: %v", err)
+		return make(map[string]logOffset)
+	}
+	return offsets
+}
+
+// saveOffsets 保存各文件的读取位置，先写入临时文件再重命名，避免写入中途退出导致文件损坏
+func (lc *LogWatchCollector) saveOffsets() {
+	if lc.stateFile == "" {
+		return
+	}
+
+	offsets := make(map[string]logOffset, len(lc.watches))
+	changed := false
+	for _, w := range lc.watches {
+		offset, ok := lc.saved[w.config.Path]
+		if w.file != nil {
+			current := logOffset{Inode: w.inode, Offset: w.offset}
+			changed = changed || !ok || current != offset
+			offset, ok = current, true
+		}
+		if ok {
+			offsets[w.config.Path] = offset
+		}
+	}
+	if !changed {
+		return
+	}
+	lc.saved = offsets
+
+	if err := writeFileAtomic(lc.stateFile, offsets); err != nil {
+		log.Printf("保存日志读取位置失败: %v", err)
+	}
+}
+
+// writeFileAtomic 将数据编码为JSON后原子地写入文件
+func writeFileAtomic(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
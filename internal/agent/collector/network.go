@@ -0,0 +1,286 @@
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// 上报的TCP连接状态，未出现的状态计为0，保证序列稳定
+var tcpStates = []string{
+	"ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING",
+}
+
+// TCPStats 包含TCP协议栈统计信息，来源于/proc/net/snmp和/proc/net/netstat
+type TCPStats struct {
+	ActiveOpens     uint64 `json:"active_opens"`
+	PassiveOpens    uint64 `json:"passive_opens"`
+	AttemptFails    uint64 `json:"attempt_fails"`
+	EstabResets     uint64 `json:"estab_resets"`
+	CurrEstab       uint64 `json:"curr_estab"`
+	InSegs          uint64 `json:"in_segs"`
+	OutSegs         uint64 `json:"out_segs"`
+	RetransSegs     uint64 `json:"retrans_segs"`
+	InErrs          uint64 `json:"in_errs"`
+	OutRsts         uint64 `json:"out_rsts"`
+	ListenOverflows uint64 `json:"listen_overflows"`
+	ListenDrops     uint64 `json:"listen_drops"`
+	SyncookiesSent  uint64 `json:"syncookies_sent"`
+
+	// 采集区间内的速率
+	RetransRate         float64 `json:"retrans_segs_per_sec"`
+	RetransPercent      float64 `json:"retrans_percent"`
+	ListenOverflowsRate float64 `json:"listen_overflows_per_sec"`
+	ListenDropsRate     float64 `json:"listen_drops_per_sec"`
+	SyncookiesSentRate  float64 `json:"syncookies_sent_per_sec"`
+}
+
+// newInterfaceStats 根据网卡计数器生成接口统计，hasPrev为false时不计算速率
+func newInterfaceStats(cur, prev psnet.IOCountersStat, hasPrev bool, timeDiff float64) InterfaceStats {
+	stats := InterfaceStats{
+		BytesSent:   cur.BytesSent,
+		BytesRecv:   cur.BytesRecv,
+		PacketsSent: cur.PacketsSent,
+		PacketsRecv: cur.PacketsRecv,
+		Errin:       cur.Errin,
+		Errout:      cur.Errout,
+		Dropin:      cur.Dropin,
+		Dropout:     cur.Dropout,
+		Fifoin:      cur.Fifoin,
+		Fifoout:     cur.Fifoout,
+	}
+
+	if hasPrev && timeDiff > 0 {
+		stats.UploadSpeed = uint64(counterRate(prev.BytesSent, cur.BytesSent, timeDiff))
+		stats.DownloadSpeed = uint64(counterRate(prev.BytesRecv, cur.BytesRecv, timeDiff))
+		stats.PacketsSentRate = counterRate(prev.PacketsSent, cur.PacketsSent, timeDiff)
+		stats.PacketsRecvRate = counterRate(prev.PacketsRecv, cur.PacketsRecv, timeDiff)
+		stats.ErrorsRate = counterRate(prev.Errin+prev.Errout, cur.Errin+cur.Errout, timeDiff)
+		stats.DropsRate = counterRate(prev.Dropin+prev.Dropout, cur.Dropin+cur.Dropout, timeDiff)
+	}
+
+	return stats
+}
+
+// interfaceRates 保存网卡计数器的上次采集结果，用于计算速率
+// 顺序采集和并行采集共用同一份状态，更新时加锁
+type interfaceRates struct {
+	mu   sync.Mutex
+	last map[string]psnet.IOCountersStat
+	time time.Time
+}
+
+// update 根据本次采集的计数器计算各接口的统计及总流量，并保存为下次计算的基准
+// interfaces为空时统计所有接口
+func (r *interfaceRates) update(counters []psnet.IOCountersStat, interfaces []string, now time.Time) (map[string]InterfaceStats, uint64, uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make(map[string]InterfaceStats, len(counters))
+	var totalSent, totalRecv uint64
+	timeDiff := now.Sub(r.time).Seconds()
+
+	for _, netIO := range counters {
+		if len(interfaces) == 0 || containsString(interfaces, netIO.Name) {
+			// 首次采集或接口新出现时无法计算速率
+			prev, exists := r.last[netIO.Name]
+			result[netIO.Name] = newInterfaceStats(netIO, prev, exists, timeDiff)
+
+			totalSent += netIO.BytesSent
+			totalRecv += netIO.BytesRecv
+		}
+	}
+
+	r.last = make(map[string]psnet.IOCountersStat, len(counters))
+	for _, netIO := range counters {
+		r.last[netIO.Name] = netIO
+	}
+	r.time = now
+
+	return result, totalSent, totalRecv
+}
+
+// ipAddresses 本机的非回环地址，按IPv4/IPv6及公网/私有地址分类
+type ipAddresses struct {
+	privateIPv4, publicIPv4, privateIPv6, publicIPv6 []string
+}
+
+// applyTo 将地址追加到网络统计中
+func (a ipAddresses) applyTo(network *NetworkStats) {
+	network.PrivateIPv4 = append(network.PrivateIPv4, a.privateIPv4...)
+	network.PublicIPv4 = append(network.PublicIPv4, a.publicIPv4...)
+	network.PrivateIPv6 = append(network.PrivateIPv6, a.privateIPv6...)
+	network.PublicIPv6 = append(network.PublicIPv6, a.publicIPv6...)
+}
+
+// collectIPAddresses 收集所有网络接口的IP地址
+func collectIPAddresses() (ipAddresses, error) {
+	var addresses ipAddresses
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return addresses, fmt.Errorf("获取网络接口失败: %w", err)
+	}
+
+	for _, iface := range interfaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+
+			ip := ipnet.IP
+			if ip.IsLoopback() {
+				continue
+			}
+
+			if ip.To4() != nil {
+				// IPv4地址
+				if isPrivateIP(ip) {
+					addresses.privateIPv4 = append(addresses.privateIPv4, ip.String())
+				} else {
+					addresses.publicIPv4 = append(addresses.publicIPv4, ip.String())
+				}
+			} else {
+				// IPv6地址
+				if isPrivateIP(ip) {
+					addresses.privateIPv6 = append(addresses.privateIPv6, ip.String())
+				} else {
+					addresses.publicIPv6 = append(addresses.publicIPv6, ip.String())
+				}
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+// countConnections 统计TCP/UDP连接数及TCP连接状态分布
+func countConnections(connections []psnet.ConnectionStat) (tcpCount, udpCount int, states map[string]int) {
+	states = make(map[string]int, len(tcpStates))
+	for _, state := range tcpStates {
+		states[state] = 0
+	}
+
+	for _, conn := range connections {
+		if conn.Type == syscall.SOCK_STREAM {
+			tcpCount++
+			if conn.Status != "" && conn.Status != "NONE" {
+				states[conn.Status]++
+			}
+		} else if conn.Type == syscall.SOCK_DGRAM {
+			udpCount++
+		}
+	}
+
+	return tcpCount, udpCount, states
+}
+
+// collectTCPStats 采集TCP协议栈计数器并根据上次采集结果计算速率
+func (sc *SystemCollector) collectTCPStats(now time.Time) (*TCPStats, error) {
+	snmp, err := readProcNetCounters(filepath.Join(sc.procRoot, "net", "snmp"), "Tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &TCPStats{
+		ActiveOpens:  snmp["ActiveOpens"],
+		PassiveOpens: snmp["PassiveOpens"],
+		AttemptFails: snmp["AttemptFails"],
+		EstabResets:  snmp["EstabResets"],
+		CurrEstab:    snmp["CurrEstab"],
+		InSegs:       snmp["InSegs"],
+		OutSegs:      snmp["OutSegs"],
+		RetransSegs:  snmp["RetransSegs"],
+		InErrs:       snmp["InErrs"],
+		OutRsts:      snmp["OutRsts"],
+	}
+
+	// 部分内核或容器环境中不存在netstat，只上报snmp中的计数器
+	if netstat, err := readProcNetCounters(filepath.Join(sc.procRoot, "net", "netstat"), "TcpExt"); err == nil {
+		stats.ListenOverflows = netstat["ListenOverflows"]
+		stats.ListenDrops = netstat["ListenDrops"]
+		stats.SyncookiesSent = netstat["SyncookiesSent"]
+	}
+
+	// 首次采集无法计算速率
+	if prev := sc.lastTCPStats; prev != nil {
+		if timeDiff := now.Sub(sc.lastTCPTime).Seconds(); timeDiff > 0 {
+			stats.RetransRate = counterRate(prev.RetransSegs, stats.RetransSegs, timeDiff)
+			stats.ListenOverflowsRate = counterRate(prev.ListenOverflows, stats.ListenOverflows, timeDiff)
+			stats.ListenDropsRate = counterRate(prev.ListenDrops, stats.ListenDrops, timeDiff)
+			stats.SyncookiesSentRate = counterRate(prev.SyncookiesSent, stats.SyncookiesSent, timeDiff)
+		}
+		if stats.OutSegs > prev.OutSegs && stats.RetransSegs >= prev.RetransSegs {
+			stats.RetransPercent = float64(stats.RetransSegs-prev.RetransSegs) / float64(stats.OutSegs-prev.OutSegs) * 100
+		}
+	}
+
+	// 保存当前采集结果，用于下次计算速率
+	sc.lastTCPStats = stats
+	sc.lastTCPTime = now
+
+	return stats, nil
+}
+
+// readProcNetCounters 解析/proc/net/snmp和/proc/net/netstat中指定协议的计数器
+// 每个协议占两行，第一行为字段名，第二行为对应的值，例如:
+//
+//	Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens ...
+//	Tcp: 1 200 120000 -1 11 ...
+func readProcNetCounters(path, protocol string) (map[string]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	prefix := protocol + ":"
+	var header []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != prefix {
+			continue
+		}
+
+		if header == nil {
+			header = fields[1:]
+			continue
+		}
+
+		values := fields[1:]
+		if len(values) != len(header) {
+			return nil, fmt.Errorf("%s中%s的字段数与值数量不一致", path, protocol)
+		}
+
+		counters := make(map[string]uint64, len(header))
+		for i, name := range header {
+			// MaxConn等字段可能为-1，负值直接忽略
+			if value, err := strconv.ParseUint(values[i], 10, 64); err == nil {
+				counters[name] = value
+			}
+		}
+		return counters, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("%s中没有%s的统计数据", path, protocol)
+}
@@ -1,97 +1,125 @@
 package collector
 
 import (
-	"net"
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"runtime"
 	"sync"
-	"syscall"
 	"time"
 
-	"log"
-
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/disk"
-	"github.com/shirou/gopsutil/v3/host"
-	"github.com/shirou/gopsutil/v3/load"
-	"github.com/shirou/gopsutil/v3/mem"
 	psnet "github.com/shirou/gopsutil/v3/net"
 )
 
 // ParallelCollector 实现了一个并行收集系统指标的收集器
+// 各类指标由注册表中的子收集器并行采集，每个子收集器可单独启用并设置间隔和超时
 type ParallelCollector struct {
 	// 继承SystemCollector的所有字段
 	SystemCollector
+
+	registry *Registry
 }
 
 // NewParallelCollector 创建一个新的并行收集器
 func NewParallelCollector(options ...func(*SystemCollector)) *ParallelCollector {
 	baseCollector := NewSystemCollector(options...)
-	return &ParallelCollector{
+	pc := &ParallelCollector{
 		SystemCollector: *baseCollector,
+		registry:        NewRegistry(),
+	}
+	pc.registerCollectors()
+	return pc
+}
+
+// registerCollectors 注册内置的子收集器，未单独配置的子收集器默认启用且每个周期都执行
+func (pc *ParallelCollector) registerCollectors() {
+	collectors := []struct {
+		name    string
+		collect CollectFunc
+	}{
+		{CollectorCPU, pc.collectCPUInfo},
+		{CollectorMemory, pc.collectMemoryInfo},
+		{CollectorPressure, pc.collectPressureInfo},
+		{CollectorDisk, pc.collectDiskInfo},
+		{CollectorDiskIO, pc.collectDiskIOInfo},
+		{CollectorNetwork, pc.collectNetworkInfo},
+		{CollectorProcesses, pc.collectProcessInfo},
+		{CollectorCgroups, pc.collectCgroupInfo},
+		{CollectorSystemd, pc.collectSystemdInfo},
+		{CollectorSensors, pc.collectSensorInfo},
+		{CollectorTimeSync, pc.collectTimeSyncInfo},
+		{CollectorHardware, func(ctx context.Context, stats *SystemStats, now time.Time) error {
+			stats.Events = append(stats.Events, pc.collectInventory(stats, now)...)
+			return nil
+		}},
+		// 插件、抓取、探测、证书扫描和完整性监控在后台按各自的间隔执行，这里只取最近一次的结果
+		{CollectorCustom, func(ctx context.Context, stats *SystemStats, now time.Time) error {
+			stats.Custom = pc.pluginCollector.Collect(now)
+			return nil
+		}},
+		{CollectorScrape, func(ctx context.Context, stats *SystemStats, now time.Time) error {
+			stats.Custom = pc.scrapeCollector.Collect(now)
+			return nil
+		}},
+		{CollectorProbes, func(ctx context.Context, stats *SystemStats, now time.Time) error {
+			var events []Event
+			stats.Probes, events = pc.probeCollector.Collect(now)
+			stats.Events = append(stats.Events, events...)
+			return nil
+		}},
+		{CollectorLogs, func(ctx context.Context, stats *SystemStats, now time.Time) error {
+			stats.Logs = pc.logWatchCollector.Collect(ctx, now)
+			return nil
+		}},
+		{CollectorCertificates, func(ctx context.Context, stats *SystemStats, now time.Time) error {
+			stats.Certificates = pc.certificateCollector.Collect(now)
+			return nil
+		}},
+		{CollectorIntegrity, func(ctx context.Context, stats *SystemStats, now time.Time) error {
+			var events []Event
+			stats.Integrity, events = pc.integrityCollector.Collect(now)
+			stats.Events = append(stats.Events, events...)
+			return nil
+		}},
+	}
+
+	for _, c := range collectors {
+		settings, ok := pc.collectorSettings[c.name]
+		if !ok {
+			settings = CollectorSettings{Enabled: true}
+		}
+		pc.registry.Register(c.name, c.collect, settings)
 	}
 }
 
+// Registry 返回子收集器注册表，可用于运行时调整各子收集器的参数
+func (pc *ParallelCollector) Registry() *Registry {
+	return pc.registry
+}
+
 // Collect 并行收集系统指标
+// 部分子收集器失败或超时不影响其他指标的上报，失败的子收集器记录在CollectorErrors中
 func (pc *ParallelCollector) Collect() (*SystemStats, error) {
 	now := time.Now()
-	stats := &SystemStats{
-		Timestamp:   now,
-		CurrentTime: now.Format(time.RFC3339),
-		CPU:         make(map[string]float64),
-		Disk:        make(map[string]DiskStats),
-		Network: NetworkStats{
-			Interfaces:  make(map[string]InterfaceStats),
-			PublicIPv4:  []string{},
-			PublicIPv6:  []string{},
-			PrivateIPv4: []string{},
-			PrivateIPv6: []string{},
-		},
-	}
-
-	// 使用WaitGroup来并行执行收集任务
-	var wg sync.WaitGroup
+	stats := newSystemStats(now)
 
 	// 1. 收集主机基本信息（很快，保持同步）
-	if hostInfo, err := host.Info(); err == nil {
+	if hostInfo, err := pc.sources.hostInfo(context.Background()); err == nil {
 		stats.Hostname = hostInfo.Hostname
 		stats.Platform = hostInfo.Platform + " " + hostInfo.PlatformVersion + " " + runtime.GOARCH
 		stats.Uptime = hostInfo.Uptime
 	}
 
-	// 2. 并行收集CPU信息
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		pc.collectCPUInfo(stats)
-	}()
-
-	// 3. 并行收集内存信息
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		pc.collectMemoryInfo(stats)
-	}()
-
-	// 4. 并行收集磁盘信息
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		pc.collectDiskInfo(stats)
-	}()
-
-	// 5. 并行收集网络信息（最耗时的部分）
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		pc.collectNetworkInfo(stats, now)
-	}()
-
-	// 等待所有收集任务完成
-	wg.Wait()
+	// 2. 并行执行各子收集器，全部结束后合并结果
+	if err := pc.registry.Run(stats, now); err != nil {
+		log.Printf("部分子收集器采集失败: %v", err)
+	}
 
-	// 设置硬件信息
-	stats.Hardware.CPUCores = stats.Hardware.CPUCores
-	stats.Hardware.MemoryTotal = stats.Memory.Total
+	// 设置硬件信息，内存采集未启用时使用硬件清单中的内存总量
+	if stats.Memory.Total > 0 {
+		stats.Hardware.MemoryTotal = stats.Memory.Total
+	}
 	stats.Hardware.DiskTotal = calculateTotalDiskSpace(stats.Disk)
 
 	return stats, nil
@@ -107,257 +135,222 @@ func calculateTotalDiskSpace(diskStats map[string]DiskStats) uint64 {
 }
 
 // 收集CPU信息
-func (pc *ParallelCollector) collectCPUInfo(stats *SystemStats) {
-	// 收集CPU使用率 - 减少等待时间从1秒到250毫秒
-	if cpuPercent, err := cpu.Percent(time.Millisecond*250, false); err == nil && len(cpuPercent) > 0 {
-		stats.CPU["usage"] = cpuPercent[0]
+func (pc *ParallelCollector) collectCPUInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
+	// 收集CPU使用率及各类CPU时间占比 - 首次采集的等待时间从1秒减少到250毫秒
+	cpuStats, err := pc.collectCPUStats(ctx, time.Millisecond*250)
+	if err != nil {
+		return err
 	}
+	cpuStats.applyTo(stats)
 
-	// 收集CPU详细信息
-	if cpuInfo, err := cpu.Info(); err == nil && len(cpuInfo) > 0 {
-		stats.Hardware.CPUModel = cpuInfo[0].ModelName
-		stats.Hardware.CPUCores = len(cpuInfo)
+	// 收集系统负载
+	loadAvg, err := pc.sources.loadAvg(ctx)
+	if err != nil {
+		return fmt.Errorf("获取系统负载失败: %w", err)
 	}
+	stats.LoadAvg = LoadAvgStats{
+		Load1:  loadAvg.Load1,
+		Load5:  loadAvg.Load5,
+		Load15: loadAvg.Load15,
+	}
+	return nil
+}
 
-	// 收集系统负载
-	if loadAvg, err := load.Avg(); err == nil {
-		stats.LoadAvg = LoadAvgStats{
-			Load1:  loadAvg.Load1,
-			Load5:  loadAvg.Load5,
-			Load15: loadAvg.Load15,
-		}
+// 收集进程信息
+func (pc *ParallelCollector) collectProcessInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
+	processStats, err := pc.processCollector.Collect()
+	if err != nil {
+		return fmt.Errorf("获取进程信息失败: %w", err)
+	}
+	stats.Processes = processStats
+	return nil
+}
+
+// 收集cgroup信息
+func (pc *ParallelCollector) collectCgroupInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
+	cgroupStats, err := pc.cgroupCollector.Collect()
+	if err != nil {
+		return fmt.Errorf("获取cgroup信息失败: %w", err)
+	}
+	stats.Cgroups = cgroupStats
+	return nil
+}
+
+// 收集传感器信息
+func (pc *ParallelCollector) collectSensorInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
+	sensors, err := pc.sensorCollector.Collect(pc.sysRoot)
+	if err != nil {
+		return fmt.Errorf("获取传感器信息失败: %w", err)
+	}
+	stats.Sensors = sensors
+	return nil
+}
+
+// 收集时间同步状态
+func (pc *ParallelCollector) collectTimeSyncInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
+	timeSync, err := pc.timeSyncCollector.Collect()
+	if err != nil {
+		return err
+	}
+	stats.TimeSync = timeSync
+	return nil
+}
+
+// 收集systemd单元信息
+func (pc *ParallelCollector) collectSystemdInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
+	units, events, err := pc.systemdCollector.Collect(now)
+	if err != nil {
+		return err
 	}
+	stats.Systemd = units
+	stats.Events = append(stats.Events, events...)
+	return nil
 }
 
 // 收集内存信息
-func (pc *ParallelCollector) collectMemoryInfo(stats *SystemStats) {
+func (pc *ParallelCollector) collectMemoryInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
 	// 收集内存信息
-	if memStat, err := mem.VirtualMemory(); err == nil {
-		stats.Memory.Total = memStat.Total
-		stats.Memory.Used = memStat.Used
-		stats.Memory.Free = memStat.Free
-		stats.Memory.UsedPercent = memStat.UsedPercent
+	memStat, err := pc.sources.virtualMemory(ctx)
+	if err != nil {
+		return fmt.Errorf("获取内存信息失败: %w", err)
 	}
+	stats.Memory.Total = memStat.Total
+	stats.Memory.Used = memStat.Used
+	stats.Memory.Free = memStat.Free
+	stats.Memory.UsedPercent = memStat.UsedPercent
 
 	// 收集交换分区信息
-	if swapStat, err := mem.SwapMemory(); err == nil {
-		stats.Memory.SwapTotal = swapStat.Total
-		stats.Memory.SwapUsed = swapStat.Used
-		stats.Memory.SwapPercent = swapStat.UsedPercent
+	swapStat, err := pc.sources.swapMemory(ctx)
+	if err != nil {
+		return fmt.Errorf("获取交换分区信息失败: %w", err)
 	}
+	stats.Memory.SwapTotal = swapStat.Total
+	stats.Memory.SwapUsed = swapStat.Used
+	stats.Memory.SwapPercent = swapStat.UsedPercent
+	return nil
 }
 
-// 收集磁盘信息
-func (pc *ParallelCollector) collectDiskInfo(stats *SystemStats) {
-	var diskMutex sync.Mutex
-	var diskWg sync.WaitGroup
-
-	// 检查挂载点配置是否有效
-	if len(pc.mountPoints) == 0 {
-		log.Printf("警告: 没有配置任何磁盘挂载点，将使用默认的根目录('/')")
-		pc.mountPoints = []string{"/"}
-	}
+// 收集资源压力信息
+func (pc *ParallelCollector) collectPressureInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
+	// PSI需要4.20以上内核并启用CONFIG_PSI，不可用时直接跳过
+	stats.Pressure = pc.collectPressureStats()
 
-	// 对每个挂载点并行收集
-	for _, mountPoint := range pc.mountPoints {
-		diskWg.Add(1)
-		go func(mount string) {
-			defer diskWg.Done()
-
-			diskStat, err := disk.Usage(mount)
-			if err != nil {
-				log.Printf("获取磁盘挂载点 '%s' 的使用统计失败: %v", mount, err)
-				return
-			}
-
-			// 安全更新共享的map
-			diskMutex.Lock()
-			stats.Disk[mount] = DiskStats{
-				Total:       diskStat.Total,
-				Used:        diskStat.Used,
-				Free:        diskStat.Free,
-				UsedPercent: diskStat.UsedPercent,
-				FSType:      diskStat.Fstype,
-			}
-			diskMutex.Unlock()
-		}(mountPoint)
+	vmstat, err := pc.collectVMStat(now)
+	if err != nil {
+		return fmt.Errorf("获取vmstat计数器失败: %w", err)
 	}
+	stats.VMStat = vmstat
+	return nil
+}
 
-	// 等待所有磁盘收集完成
-	diskWg.Wait()
+// 收集磁盘信息
+func (pc *ParallelCollector) collectDiskInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
+	stats.Disk = pc.collectFilesystemStats(ctx)
 
 	// 检查是否成功收集到任何磁盘数据
 	if len(stats.Disk) == 0 {
-		log.Printf("警告: 没有成功收集到任何磁盘使用数据")
+		return fmt.Errorf("没有成功收集到任何磁盘使用数据")
 	}
+	return nil
+}
+
+// 收集磁盘I/O信息
+func (pc *ParallelCollector) collectDiskIOInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
+	diskIOStats, err := pc.collectDiskIOStats(ctx, now)
+	if err != nil {
+		return fmt.Errorf("获取磁盘I/O计数器失败: %w", err)
+	}
+	stats.DiskIO = diskIOStats
+	return nil
 }
 
 // 收集网络信息
-func (pc *ParallelCollector) collectNetworkInfo(stats *SystemStats, now time.Time) {
-	var netWg sync.WaitGroup
-	var netMutex sync.Mutex
+// 接口计数器、IP地址、连接和TCP统计并行采集，各自返回结果，全部结束后再写入stats
+func (pc *ParallelCollector) collectNetworkInfo(ctx context.Context, stats *SystemStats, now time.Time) error {
+	var (
+		netWg sync.WaitGroup
+
+		interfaces           map[string]InterfaceStats
+		totalSent, totalRecv uint64
+		interfacesErr        error
+		addresses            ipAddresses
+		addressesErr         error
+		connections          []psnet.ConnectionStat
+		connectionsErr       error
+		tcpStats             *TCPStats
+		tcpStatsErr          error
+	)
 
 	// 1. 收集网络接口信息
 	netWg.Add(1)
 	go func() {
 		defer netWg.Done()
 
-		netIOCounters, err := psnet.IOCounters(true)
+		netIOCounters, err := pc.sources.netIOCounters(ctx, true)
 		if err != nil {
-			log.Printf("获取网络接口计数器失败: %v", err)
+			interfacesErr = fmt.Errorf("获取网络接口计数器失败: %w", err)
 			return
 		}
-
 		if len(netIOCounters) == 0 {
-			log.Printf("警告: 系统未返回任何网络接口计数器数据")
+			interfacesErr = fmt.Errorf("系统未返回任何网络接口计数器数据")
 			return
 		}
-
-		var totalSent, totalRecv uint64
-		var prevNetIOCounters map[string]psnet.IOCountersStat
-
-		// 获取上次采集的网络数据，计算速率
-		if pc.lastNetworkStats != nil {
-			prevNetIOCounters = pc.lastNetworkStats
-			timeDiff := now.Sub(pc.lastCollectTime).Seconds()
-
-			if timeDiff > 0 {
-				for _, netIO := range netIOCounters {
-					if len(pc.interfaces) == 0 || containsString(pc.interfaces, netIO.Name) {
-						prev, exists := prevNetIOCounters[netIO.Name]
-
-						// 计算网络速率
-						uploadSpeed := uint64(0)
-						downloadSpeed := uint64(0)
-
-						if exists && timeDiff > 0 {
-							uploadSpeed = uint64(float64(netIO.BytesSent-prev.BytesSent) / timeDiff)
-							downloadSpeed = uint64(float64(netIO.BytesRecv-prev.BytesRecv) / timeDiff)
-						}
-
-						netMutex.Lock()
-						stats.Network.Interfaces[netIO.Name] = InterfaceStats{
-							BytesSent:     netIO.BytesSent,
-							BytesRecv:     netIO.BytesRecv,
-							UploadSpeed:   uploadSpeed,
-							DownloadSpeed: downloadSpeed,
-						}
-						netMutex.Unlock()
-
-						totalSent += netIO.BytesSent
-						totalRecv += netIO.BytesRecv
-					}
-				}
-			}
-		} else {
-			// 首次采集，无法计算速率
-			for _, netIO := range netIOCounters {
-				if len(pc.interfaces) == 0 || containsString(pc.interfaces, netIO.Name) {
-					netMutex.Lock()
-					stats.Network.Interfaces[netIO.Name] = InterfaceStats{
-						BytesSent:     netIO.BytesSent,
-						BytesRecv:     netIO.BytesRecv,
-						UploadSpeed:   0,
-						DownloadSpeed: 0,
-					}
-					netMutex.Unlock()
-
-					totalSent += netIO.BytesSent
-					totalRecv += netIO.BytesRecv
-				}
-			}
-		}
-
-		netMutex.Lock()
-		stats.Network.TotalSent = totalSent
-		stats.Network.TotalReceived = totalRecv
-
-		// 保存当前采集结果，用于下次计算速率
-		pc.lastNetworkStats = make(map[string]psnet.IOCountersStat)
-		for _, netIO := range netIOCounters {
-			pc.lastNetworkStats[netIO.Name] = netIO
-		}
-		pc.lastCollectTime = now
-		netMutex.Unlock()
+		interfaces, totalSent, totalRecv = pc.netRates.update(netIOCounters, pc.interfaces, now)
 	}()
 
 	// 2. 收集IP地址信息
 	netWg.Add(1)
+	go func() {
+		defer netWg.Done()
+		addresses, addressesErr = collectIPAddresses()
+	}()
+
+	// 3. 收集TCP和UDP连接（最耗时的部分，单独处理）
+	netWg.Add(1)
 	go func() {
 		defer netWg.Done()
 
-		interfaces, err := net.Interfaces()
-		if err == nil {
-			var privateIPv4, publicIPv4, privateIPv6, publicIPv6 []string
-
-			for _, iface := range interfaces {
-				addrs, err := iface.Addrs()
-				if err != nil {
-					continue
-				}
-
-				for _, addr := range addrs {
-					ipnet, ok := addr.(*net.IPNet)
-					if !ok {
-						continue
-					}
-
-					ip := ipnet.IP
-					if ip.IsLoopback() {
-						continue
-					}
-
-					if ip.To4() != nil {
-						// IPv4地址
-						if isPrivateIP(ip) {
-							privateIPv4 = append(privateIPv4, ip.String())
-						} else {
-							publicIPv4 = append(publicIPv4, ip.String())
-						}
-					} else {
-						// IPv6地址
-						if isPrivateIP(ip) {
-							privateIPv6 = append(privateIPv6, ip.String())
-						} else {
-							publicIPv6 = append(publicIPv6, ip.String())
-						}
-					}
-				}
-			}
-
-			netMutex.Lock()
-			stats.Network.PrivateIPv4 = privateIPv4
-			stats.Network.PublicIPv4 = publicIPv4
-			stats.Network.PrivateIPv6 = privateIPv6
-			stats.Network.PublicIPv6 = publicIPv6
-			netMutex.Unlock()
+		var err error
+		connections, err = pc.sources.connections(ctx, "all")
+		if err != nil {
+			connectionsErr = fmt.Errorf("获取网络连接失败: %w", err)
 		}
 	}()
 
-	// 3. 收集TCP和UDP连接数（最耗时的部分，单独处理）
+	// 4. 收集TCP重传及监听队列溢出统计
 	netWg.Add(1)
 	go func() {
 		defer netWg.Done()
 
-		if connections, err := psnet.Connections("all"); err == nil {
-			tcpCount := 0
-			udpCount := 0
-
-			for _, conn := range connections {
-				if conn.Type == syscall.SOCK_STREAM {
-					tcpCount++
-				} else if conn.Type == syscall.SOCK_DGRAM {
-					udpCount++
-				}
-			}
-
-			netMutex.Lock()
-			stats.Network.TCPConnCount = tcpCount
-			stats.Network.UDPConnCount = udpCount
-			netMutex.Unlock()
+		var err error
+		tcpStats, err = pc.collectTCPStats(now)
+		if err != nil {
+			tcpStatsErr = fmt.Errorf("获取TCP协议栈统计失败: %w", err)
 		}
 	}()
 
-	// 等待所有网络收集完成
+	// 等待所有网络收集完成后再合并
 	netWg.Wait()
+
+	if interfacesErr == nil {
+		stats.Network.Interfaces = interfaces
+		stats.Network.TotalSent = totalSent
+		stats.Network.TotalReceived = totalRecv
+	}
+	if addressesErr == nil {
+		addresses.applyTo(&stats.Network)
+	}
+	if connectionsErr == nil {
+		stats.Network.TCPConnCount, stats.Network.UDPConnCount, stats.Network.TCPStates = countConnections(connections)
+
+		// 复用连接列表提取监听端口
+		listeners, events := pc.listenerCollector.Collect(connections)
+		stats.Listeners = listeners
+		stats.Events = append(stats.Events, events...)
+	}
+	if tcpStatsErr == nil {
+		stats.Network.TCP = tcpStats
+	}
+
+	return errors.Join(interfacesErr, addressesErr, connectionsErr, tcpStatsErr)
 }
@@ -0,0 +1,490 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 插件输出格式
+const (
+	PluginFormatNagios     = "nagios"
+	PluginFormatJSON       = "json"
+	PluginFormatPrometheus = "prometheus"
+)
+
+// 插件执行状态，nagios格式按退出码确定，其他格式退出码为0时为ok
+const (
+	PluginStatusOK       = "ok"
+	PluginStatusWarning  = "warning"
+	PluginStatusCritical = "critical"
+	PluginStatusUnknown  = "unknown"
+	PluginStatusError    = "error"
+)
+
+const (
+	defaultPluginInterval = time.Minute
+	defaultPluginTimeout  = 10 * time.Second
+	// 插件输出的最大字节数，超出部分被截断
+	maxPluginOutput = 1024 * 1024
+	// 单个插件最多上报的指标数
+	maxPluginMetrics = 1000
+)
+
+// 插件的默认PATH，插件不继承代理的环境变量，避免泄露令牌和密钥
+const pluginDefaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// PluginConfig 自定义脚本插件配置
+type PluginConfig struct {
+	// 插件名称，作为custom中的键
+	Name    string
+	Command string
+	Args    []string
+	// 输出格式：nagios、json或prometheus
+	Format   string
+	Interval time.Duration
+	Timeout  time.Duration
+	// 附加到插件结果上的标签
+	Labels map[string]string
+	// 传递给插件的环境变量，插件只能看到这里配置的变量及PATH、LANG
+	Env map[string]string
+	// 工作目录，为空时使用代理的工作目录
+	Dir string
+}
+
+// CustomMetric 插件上报的一个指标
+type CustomMetric struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+	// Prometheus指标类型（counter/gauge等），其他格式为空
+	Type   string            `json:"type,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// PluginResult 插件最近一次执行的结果
+type PluginResult struct {
+	Status     string            `json:"status"`
+	ExitCode   int               `json:"exit_code"`
+	Message    string            `json:"message,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Metrics    []CustomMetric    `json:"metrics"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	DurationMs float64           `json:"duration_ms"`
+	Timestamp  time.Time         `json:"timestamp"`
+}
+
+// pluginState 单个插件的运行状态
+type pluginState struct {
+	config  PluginConfig
+	running bool
+	lastRun time.Time
+	result  *PluginResult
+}
+
+// PluginCollector 自定义脚本插件收集器
+// 插件在后台按各自的间隔执行，Collect只返回最近一次的结果，不会阻塞采集周期
+type PluginCollector struct {
+	mu      sync.Mutex
+	plugins []*pluginState
+}
+
+// NewPluginCollector 创建新的插件收集器，无效的插件配置会被忽略
+func NewPluginCollector(configs []PluginConfig) *PluginCollector {
+	pc := &PluginCollector{}
+	seen := make(map[string]bool)
+	for _, cfg := range configs {
+		cfg, err := normalizePluginConfig(cfg)
+		if err != nil {
+			log.Printf("忽略无效的插件配置: %v", err)
+			continue
+		}
+		if seen[cfg.Name] {
+			log.Printf("忽略重复的插件配置: %s", cfg.Name)
+			continue
+		}
+		seen[cfg.Name] = true
+		pc.plugins = append(pc.plugins, &pluginState{config: cfg})
+	}
+	return pc
+}
+
+// normalizePluginConfig 校验插件配置并补充默认值
+func normalizePluginConfig(cfg PluginConfig) (PluginConfig, error) {
+	if cfg.Name == "" {
+		return cfg, errors.New("插件名称不能为空")
+	}
+	if cfg.Command == "" {
+		return cfg, fmt.Errorf("插件 %s 未配置命令", cfg.Name)
+	}
+	switch cfg.Format {
+	case "":
+		cfg.Format = PluginFormatNagios
+	case PluginFormatNagios, PluginFormatJSON, PluginFormatPrometheus:
+	default:
+		return cfg, fmt.Errorf("插件 %s 的输出格式 %s 不受支持", cfg.Name, cfg.Format)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultPluginInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultPluginTimeout
+	}
+	return cfg, nil
+}
+
+// Collect 启动到期的插件并返回各插件最近一次的结果，未配置插件时返回nil
+func (pc *PluginCollector) Collect(now time.Time) map[string]PluginResult {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if len(pc.plugins) == 0 {
+		return nil
+	}
+
+	results := make(map[string]PluginResult, len(pc.plugins))
+	for _, p := range pc.plugins {
+		if !p.running && (p.result == nil || now.Sub(p.lastRun) >= p.config.Interval) {
+			p.running = true
+			p.lastRun = now
+			go func(p *pluginState) {
+				result := runPlugin(context.Background(), p.config)
+
+				pc.mu.Lock()
+				p.running = false
+				p.result = result
+				pc.mu.Unlock()
+			}(p)
+		}
+
+		if p.result != nil {
+			results[p.config.Name] = *p.result
+		}
+	}
+
+	return results
+}
+
+// runPlugin 在受限环境中执行插件并解析输出
+func runPlugin(ctx context.Context, cfg PluginConfig) *PluginResult {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := &PluginResult{
+		Labels:    cfg.Labels,
+		Timestamp: start,
+		Metrics:   []CustomMetric{},
+	}
+
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	cmd.Dir = cfg.Dir
+	cmd.Env = pluginEnv(cfg)
+	// 插件派生的子进程可能继续占用输出管道，超时后最多再等待1秒
+	cmd.WaitDelay = time.Second
+
+	var stdout, stderr limitedBuffer
+	stdout.limit = maxPluginOutput
+	stderr.limit = 4096
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Status = PluginStatusError
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("执行超时(%v)", cfg.Timeout)
+		return result
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		result.Status = PluginStatusError
+		result.ExitCode = -1
+		result.Error = err.Error()
+		return result
+	}
+
+	var parseErr error
+	switch cfg.Format {
+	case PluginFormatNagios:
+		result.Status = nagiosStatus(result.ExitCode)
+		result.Message, result.Metrics = parseNagiosOutput(stdout.String())
+	case PluginFormatJSON:
+		result.Metrics, parseErr = parseJSONMetrics(stdout.Bytes())
+	case PluginFormatPrometheus:
+		result.Metrics, parseErr = parsePrometheusMetrics(stdout.String())
+	}
+
+	if len(result.Metrics) > maxPluginMetrics {
+		result.Metrics = result.Metrics[:maxPluginMetrics]
+		parseErr = fmt.Errorf("指标数超过上限%d，多余的指标已丢弃", maxPluginMetrics)
+	}
+
+	if result.Status == "" {
+		if result.ExitCode == 0 {
+			result.Status = PluginStatusOK
+		} else {
+			result.Status = PluginStatusError
+		}
+	}
+	if parseErr != nil {
+		result.Error = "解析输出失败: " + parseErr.Error()
+	} else if result.ExitCode != 0 && stderr.Len() > 0 {
+		result.Error = strings.TrimSpace(stderr.String())
+	}
+	if stdout.truncated {
+		result.Error = strings.TrimSpace(result.Error + " 输出超过上限已截断")
+	}
+
+	return result
+}
+
+// pluginEnv 生成插件的环境变量
+func pluginEnv(cfg PluginConfig) []string {
+	env := []string{
+		"PATH=" + pluginDefaultPath,
+		"LANG=C",
+		"SYSLENS_PLUGIN=" + cfg.Name,
+	}
+
+	keys := make([]string, 0, len(cfg.Env))
+	for k := range cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+cfg.Env[k])
+	}
+	return env
+}
+
+// nagiosStatus 将Nagios插件的退出码转换为状态
+func nagiosStatus(exitCode int) string {
+	switch exitCode {
+	case 0:
+		return PluginStatusOK
+	case 1:
+		return PluginStatusWarning
+	case 2:
+		return PluginStatusCritical
+	default:
+		return PluginStatusUnknown
+	}
+}
+
+// parseNagiosOutput 解析Nagios插件输出，格式为:
+//
+//	TEXT OUTPUT | PERFDATA
+//	LONG TEXT LINE 1
+//	LONG TEXT LINE 2 | PERFDATA
+//	PERFDATA
+//
+// 第一行"|"之前为状态信息，其余"|"之后的内容均为性能数据
+func parseNagiosOutput(output string) (string, []CustomMetric) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	var perfData []string
+	message, perf, _ := strings.Cut(lines[0], "|")
+	perfData = append(perfData, perf)
+
+	inPerf := false
+	for _, line := range lines[1:] {
+		if inPerf {
+			perfData = append(perfData, line)
+			continue
+		}
+		if _, perf, found := strings.Cut(line, "|"); found {
+			perfData = append(perfData, perf)
+			inPerf = true
+		}
+	}
+
+	return strings.TrimSpace(message), parsePerfData(strings.Join(perfData, " "))
+}
+
+// parsePerfData 解析Nagios性能数据: 'label'=value[UOM];[warn];[crit];[min];[max]
+func parsePerfData(perfData string) []CustomMetric {
+	metrics := []CustomMetric{}
+	s := strings.TrimSpace(perfData)
+	for s != "" {
+		var label string
+		if strings.HasPrefix(s, "'") {
+			// 带引号的标签可以包含空格，''表示单引号
+			end := 1
+			var b strings.Builder
+			for end < len(s) {
+				if s[end] == '\'' {
+					if end+1 < len(s) && s[end+1] == '\'' {
+						b.WriteByte('\'')
+						end += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[end])
+				end++
+			}
+			label = b.String()
+			s = s[min(end+1, len(s)):]
+		} else {
+			eq := strings.IndexByte(s, '=')
+			if eq < 0 {
+				break
+			}
+			label = s[:eq]
+			s = s[eq:]
+		}
+
+		if !strings.HasPrefix(s, "=") {
+			break
+		}
+		s = s[1:]
+
+		item := s
+		if space := strings.IndexAny(s, " \t"); space >= 0 {
+			item, s = s[:space], strings.TrimLeft(s[space:], " \t")
+		} else {
+			s = ""
+		}
+
+		valuePart, _, _ := strings.Cut(item, ";")
+		numEnd := 0
+		for numEnd < len(valuePart) && strings.IndexByte("0123456789.-+eE", valuePart[numEnd]) >= 0 {
+			numEnd++
+		}
+		value, err := strconv.ParseFloat(valuePart[:numEnd], 64)
+		if err != nil || label == "" {
+			// 值为U表示无法获取，直接跳过
+			continue
+		}
+
+		metrics = append(metrics, CustomMetric{
+			Name:  strings.TrimSpace(label),
+			Value: value,
+			Unit:  valuePart[numEnd:],
+		})
+	}
+	return metrics
+}
+
+// parseJSONMetrics 解析JSON格式的输出，支持两种形式:
+//
+//	{"queue_depth": 12, "replication": {"lag_seconds": 0.5}}
+//	[{"name": "queue_depth", "value": 12, "labels": {"queue": "orders"}}]
+//
+// 嵌套对象的键以"_"连接，布尔值转换为1或0，其他类型的值被忽略
+func parseJSONMetrics(data []byte) ([]CustomMetric, error) {
+	data = bytes.TrimSpace(data)
+	metrics := []CustomMetric{}
+
+	if bytes.HasPrefix(data, []byte("[")) {
+		var items []struct {
+			Name   string            `json:"name"`
+			Value  interface{}       `json:"value"`
+			Unit   string            `json:"unit"`
+			Labels map[string]string `json:"labels"`
+		}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			value, ok := jsonNumber(item.Value)
+			if !ok || item.Name == "" {
+				continue
+			}
+			metrics = append(metrics, CustomMetric{Name: item.Name, Value: value, Unit: item.Unit, Labels: item.Labels})
+		}
+		return metrics, nil
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	flattenJSONMetrics("", object, &metrics)
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+	return metrics, nil
+}
+
+// flattenJSONMetrics 将嵌套的JSON对象展开为指标
+func flattenJSONMetrics(prefix string, object map[string]interface{}, metrics *[]CustomMetric) {
+	for key, raw := range object {
+		name := key
+		if prefix != "" {
+			name = prefix + "_" + key
+		}
+		if nested, ok := raw.(map[string]interface{}); ok {
+			flattenJSONMetrics(name, nested, metrics)
+			continue
+		}
+		if value, ok := jsonNumber(raw); ok {
+			*metrics = append(*metrics, CustomMetric{Name: name, Value: value})
+		}
+	}
+}
+
+// jsonNumber 将JSON值转换为数值
+func jsonNumber(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		value, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return 0, false
+		}
+		return value, true
+	}
+	return 0, false
+}
+
+// parsePrometheusMetrics 解析Prometheus文本格式的输出
+func parsePrometheusMetrics(output string) ([]CustomMetric, error) {
+	samples, err := parsePrometheusText(strings.NewReader(output), maxPluginMetrics)
+	metrics := make([]CustomMetric, 0, len(samples))
+	for _, sample := range samples {
+		metrics = append(metrics, CustomMetric{Name: sample.Name, Value: sample.Value, Type: sample.Type, Labels: sample.Labels})
+	}
+	return metrics, err
+}
+
+// limitedBuffer 限制写入大小的缓冲区，超出部分被丢弃
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write 写入数据，超出上限时丢弃多余部分但不返回错误，避免插件因管道错误退出
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.Buffer.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,196 @@
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PSI资源类型
+var pressureResources = []string{"cpu", "memory", "io"}
+
+// 需要采集的vmstat计数器
+var vmstatKeys = []string{"pgfault", "pgmajfault", "pswpin", "pswpout", "oom_kill"}
+
+// PressureStats 包含单类资源的压力停滞信息(PSI)
+// some表示至少有一个任务因该资源停滞，full表示所有非空闲任务同时停滞
+type PressureStats struct {
+	SomeAvg10  float64 `json:"some_avg10"`
+	SomeAvg60  float64 `json:"some_avg60"`
+	SomeAvg300 float64 `json:"some_avg300"`
+	SomeTotal  uint64  `json:"some_total"`
+	FullAvg10  float64 `json:"full_avg10"`
+	FullAvg60  float64 `json:"full_avg60"`
+	FullAvg300 float64 `json:"full_avg300"`
+	FullTotal  uint64  `json:"full_total"`
+}
+
+// VMStatStats 包含/proc/vmstat中的关键计数器
+type VMStatStats struct {
+	PgFault    uint64 `json:"pgfault"`
+	PgMajFault uint64 `json:"pgmajfault"`
+	PswpIn     uint64 `json:"pswpin"`
+	PswpOut    uint64 `json:"pswpout"`
+	OOMKill    uint64 `json:"oom_kill"`
+
+	// 采集区间内的速率
+	PgMajFaultRate float64 `json:"pgmajfault_per_sec"`
+	PswpInRate     float64 `json:"pswpin_per_sec"`
+	PswpOutRate    float64 `json:"pswpout_per_sec"`
+	// 采集区间内新增的OOM Kill次数
+	OOMKillDelta uint64 `json:"oom_kill_delta"`
+}
+
+// collectPressureStats 采集PSI信息，内核未启用PSI时返回nil
+func (sc *SystemCollector) collectPressureStats() map[string]PressureStats {
+	result := make(map[string]PressureStats, len(pressureResources))
+	for _, resource := range pressureResources {
+		pressure, err := readPressureFile(filepath.Join(sc.procRoot, "pressure", resource))
+		if err != nil {
+			continue
+		}
+		result[resource] = pressure
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// collectVMStat 采集vmstat计数器并根据上次采集结果计算速率
+func (sc *SystemCollector) collectVMStat(now time.Time) (*VMStatStats, error) {
+	values, err := readVMStat(filepath.Join(sc.procRoot, "vmstat"))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &VMStatStats{
+		PgFault:    values["pgfault"],
+		PgMajFault: values["pgmajfault"],
+		PswpIn:     values["pswpin"],
+		PswpOut:    values["pswpout"],
+		OOMKill:    values["oom_kill"],
+	}
+
+	// 首次采集无法计算速率
+	if prev := sc.lastVMStat; prev != nil {
+		if timeDiff := now.Sub(sc.lastVMStatTime).Seconds(); timeDiff > 0 {
+			stats.PgMajFaultRate = counterRate(prev.PgMajFault, stats.PgMajFault, timeDiff)
+			stats.PswpInRate = counterRate(prev.PswpIn, stats.PswpIn, timeDiff)
+			stats.PswpOutRate = counterRate(prev.PswpOut, stats.PswpOut, timeDiff)
+		}
+		if stats.OOMKill >= prev.OOMKill {
+			stats.OOMKillDelta = stats.OOMKill - prev.OOMKill
+		}
+	}
+
+	// 保存当前采集结果，用于下次计算速率
+	sc.lastVMStat = stats
+	sc.lastVMStatTime = now
+
+	return stats, nil
+}
+
+// counterRate 计算计数器在区间内的每秒增量，计数器回绕时返回0
+func counterRate(prev, cur uint64, timeDiff float64) float64 {
+	if cur < prev || timeDiff <= 0 {
+		return 0
+	}
+	return float64(cur-prev) / timeDiff
+}
+
+// readPressureFile 解析/proc/pressure下的文件，格式如下:
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func readPressureFile(path string) (PressureStats, error) {
+	var stats PressureStats
+
+	file, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	defer file.Close()
+
+	var found bool
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var avg10, avg60, avg300 *float64
+		var total *uint64
+		switch fields[0] {
+		case "some":
+			avg10, avg60, avg300, total = &stats.SomeAvg10, &stats.SomeAvg60, &stats.SomeAvg300, &stats.SomeTotal
+		case "full":
+			avg10, avg60, avg300, total = &stats.FullAvg10, &stats.FullAvg60, &stats.FullAvg300, &stats.FullTotal
+		default:
+			continue
+		}
+
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "avg10":
+				*avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				*avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				*avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				*total, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return stats, fmt.Errorf("解析%s的%s失败: %w", path, key, err)
+			}
+		}
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+
+	if !found {
+		return stats, fmt.Errorf("%s中没有有效的PSI数据", path)
+	}
+	return stats, nil
+}
+
+// readVMStat 读取/proc/vmstat中需要采集的计数器，内核不支持的计数器保持为0
+func readVMStat(path string) (map[string]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]uint64, len(vmstatKeys))
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || !containsString(vmstatKeys, fields[0]) {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("解析vmstat计数器%s失败: %w", fields[0], err)
+		}
+		values[fields[0]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
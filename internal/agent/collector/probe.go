@@ -0,0 +1,629 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// 探测类型
+const (
+	ProbeTypeHTTP = "http"
+	ProbeTypeTCP  = "tcp"
+	ProbeTypeDNS  = "dns"
+	ProbeTypeICMP = "icmp"
+)
+
+// 探测状态变化的事件类型
+const (
+	EventProbeFailed    = "probe_failed"
+	EventProbeRecovered = "probe_recovered"
+)
+
+const (
+	defaultProbeInterval = 30 * time.Second
+	defaultProbeTimeout  = 5 * time.Second
+	// ICMP探测默认发送的请求数
+	defaultPingCount = 3
+	// HTTP探测读取响应体的最大字节数，用于正则匹配
+	maxProbeBodySize = 1024 * 1024
+)
+
+// ProbeConfig 主动探测配置
+type ProbeConfig struct {
+	// 探测名称，作为probes中的键
+	Name string
+	// 探测类型：http、tcp、dns或icmp
+	Type string
+	// 探测目标：http为URL，tcp为host:port，dns为要解析的域名，icmp为主机名或IP
+	Target   string
+	Interval time.Duration
+	Timeout  time.Duration
+	// 附加到探测结果上的标签
+	Labels map[string]string
+
+	// HTTP请求方法，默认GET
+	Method  string
+	Headers map[string]string
+	// 期望的状态码，为空时2xx和3xx视为成功
+	ExpectedStatus []int
+	// 响应体需要匹配的正则表达式
+	BodyRegex string
+	// 不校验服务端证书（仍会检查证书有效期）
+	TLSSkipVerify bool
+	// 证书剩余有效期低于该值时探测失败，为0时不检查
+	TLSExpiryThreshold time.Duration
+
+	// DNS记录类型：A、AAAA、CNAME、MX、TXT或NS，默认A
+	RecordType string
+	// DNS服务器地址（host[:port]），为空时使用系统解析器
+	DNSServer string
+	// 期望的解析结果，解析结果至少包含其中一个时视为成功
+	ExpectedAnswers []string
+
+	// ICMP探测发送的请求数
+	PingCount int
+
+	bodyPattern *regexp.Regexp
+}
+
+// ProbeResult 单个探测最近一次的结果
+type ProbeResult struct {
+	Type    string `json:"type"`
+	Target  string `json:"target"`
+	Success bool   `json:"success"`
+	// 探测耗时，ICMP为往返时延的平均值
+	LatencyMs float64 `json:"latency_ms"`
+	// 探测失败的原因
+	Error string `json:"error,omitempty"`
+
+	// HTTP响应状态码
+	StatusCode int `json:"status_code,omitempty"`
+	// 证书链中最早的过期时间及剩余天数（仅HTTPS）
+	CertExpiry   *time.Time `json:"cert_expiry,omitempty"`
+	CertDaysLeft float64    `json:"cert_days_left,omitempty"`
+
+	// DNS解析结果
+	Answers []string `json:"answers,omitempty"`
+
+	// ICMP收发包数及丢包率（百分比）
+	PacketsSent int     `json:"packets_sent,omitempty"`
+	PacketsRecv int     `json:"packets_recv,omitempty"`
+	PacketLoss  float64 `json:"packet_loss,omitempty"`
+
+	Labels    map[string]string `json:"labels,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// probeState 单个探测的运行状态
+type probeState struct {
+	config  ProbeConfig
+	running bool
+	lastRun time.Time
+	result  *ProbeResult
+}
+
+// ProbeCollector 主动探测收集器
+// 探测在后台按各自的间隔执行，Collect只返回最近一次的结果，不会阻塞采集周期
+type ProbeCollector struct {
+	mu sync.Mutex
+	// 本地配置的探测
+	local  []ProbeConfig
+	probes []*probeState
+	// 尚未上报的探测状态变化事件
+	events []Event
+}
+
+// NewProbeCollector 创建新的探测收集器，无效的探测配置会被忽略
+func NewProbeCollector(configs []ProbeConfig) *ProbeCollector {
+	pc := &ProbeCollector{local: normalizeProbeConfigs(configs)}
+	pc.probes = make([]*probeState, 0, len(pc.local))
+	for _, cfg := range pc.local {
+		pc.probes = append(pc.probes, &probeState{config: cfg})
+	}
+	return pc
+}
+
+// SetRemoteProbes 应用主控端下发的探测配置
+// 下发的探测与本地探测合并，同名时以下发的配置为准；配置未变化的探测保留最近的结果
+func (pc *ProbeCollector) SetRemoteProbes(configs []ProbeConfig) {
+	remote := normalizeProbeConfigs(configs)
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	merged := make([]ProbeConfig, 0, len(pc.local)+len(remote))
+	overridden := make(map[string]bool, len(remote))
+	for _, cfg := range remote {
+		overridden[cfg.Name] = true
+	}
+	for _, cfg := range pc.local {
+		if !overridden[cfg.Name] {
+			merged = append(merged, cfg)
+		}
+	}
+	merged = append(merged, remote...)
+
+	existing := make(map[string]*probeState, len(pc.probes))
+	for _, state := range pc.probes {
+		existing[state.config.Name] = state
+	}
+
+	probes := make([]*probeState, 0, len(merged))
+	for _, cfg := range merged {
+		if state, ok := existing[cfg.Name]; ok && sameProbeConfig(state.config, cfg) {
+			probes = append(probes, state)
+			continue
+		}
+		probes = append(probes, &probeState{config: cfg})
+	}
+	pc.probes = probes
+}
+
+// Collect 启动到期的探测并返回各探测最近一次的结果及状态变化事件，未配置探测时返回nil
+func (pc *ProbeCollector) Collect(now time.Time) (map[string]ProbeResult, []Event) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	events := pc.events
+	pc.events = nil
+
+	if len(pc.probes) == 0 {
+		return nil, events
+	}
+
+	results := make(map[string]ProbeResult, len(pc.probes))
+	for _, p := range pc.probes {
+		if !p.running && (p.result == nil || now.Sub(p.lastRun) >= p.config.Interval) {
+			p.running = true
+			p.lastRun = now
+			go func(p *probeState) {
+				result := runProbe(context.Background(), p.config)
+
+				pc.mu.Lock()
+				if event, ok := probeEvent(p.config, p.result, result); ok {
+					pc.events = append(pc.events, event)
+				}
+				p.running = false
+				p.result = result
+				pc.mu.Unlock()
+			}(p)
+		}
+
+		if p.result != nil {
+			results[p.config.Name] = *p.result
+		}
+	}
+
+	return results, events
+}
+
+// sameProbeConfig 判断两个探测配置是否相同，忽略编译后的正则
+func sameProbeConfig(a, b ProbeConfig) bool {
+	a.bodyPattern, b.bodyPattern = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// normalizeProbeConfigs 校验探测配置并补充默认值，无效或重复的配置被忽略
+func normalizeProbeConfigs(configs []ProbeConfig) []ProbeConfig {
+	normalized := make([]ProbeConfig, 0, len(configs))
+	seen := make(map[string]bool)
+	for _, cfg := range configs {
+		cfg, err := normalizeProbeConfig(cfg)
+		if err != nil {
+			log.Printf("忽略无效的探测配置: %v", err)
+			continue
+		}
+		if seen[cfg.Name] {
+			log.Printf("忽略重复的探测配置: %s", cfg.Name)
+			continue
+		}
+		seen[cfg.Name] = true
+		normalized = append(normalized, cfg)
+	}
+	return normalized
+}
+
+// normalizeProbeConfig 校验单个探测配置并补充默认值
+func normalizeProbeConfig(cfg ProbeConfig) (ProbeConfig, error) {
+	if cfg.Target == "" {
+		return cfg, fmt.Errorf("探测 %s 未配置目标", cfg.Name)
+	}
+	if cfg.Name == "" {
+		cfg.Name = cfg.Type + ":" + cfg.Target
+	}
+
+	switch cfg.Type {
+	case ProbeTypeHTTP:
+		if cfg.Method == "" {
+			cfg.Method = http.MethodGet
+		}
+		if cfg.BodyRegex != "" {
+			pattern, err := regexp.Compile(cfg.BodyRegex)
+			if err != nil {
+				return cfg, fmt.Errorf("探测 %s 的响应体正则无效: %w", cfg.Name, err)
+			}
+			cfg.bodyPattern = pattern
+		}
+	case ProbeTypeTCP:
+		if _, _, err := net.SplitHostPort(cfg.Target); err != nil {
+			return cfg, fmt.Errorf("探测 %s 的目标应为host:port: %w", cfg.Name, err)
+		}
+	case ProbeTypeDNS:
+		cfg.RecordType = strings.ToUpper(cfg.RecordType)
+		switch cfg.RecordType {
+		case "":
+			cfg.RecordType = "A"
+		case "A", "AAAA", "CNAME", "MX", "TXT", "NS":
+		default:
+			return cfg, fmt.Errorf("探测 %s 的记录类型 %s 不受支持", cfg.Name, cfg.RecordType)
+		}
+		if cfg.DNSServer != "" {
+			if _, _, err := net.SplitHostPort(cfg.DNSServer); err != nil {
+				cfg.DNSServer = net.JoinHostPort(cfg.DNSServer, "53")
+			}
+		}
+	case ProbeTypeICMP:
+		if cfg.PingCount <= 0 {
+			cfg.PingCount = defaultPingCount
+		}
+	default:
+		return cfg, fmt.Errorf("探测 %s 的类型 %s 不受支持", cfg.Name, cfg.Type)
+	}
+
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultProbeInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultProbeTimeout
+	}
+	return cfg, nil
+}
+
+// probeEvent 根据探测结果的变化生成事件，首次探测失败也会产生失败事件
+func probeEvent(cfg ProbeConfig, previous, current *ProbeResult) (Event, bool) {
+	wasSuccess := previous == nil || previous.Success
+	if wasSuccess == current.Success {
+		return Event{}, false
+	}
+
+	event := Event{
+		Type:      EventProbeFailed,
+		Severity:  EventSeverityCritical,
+		Source:    "probe",
+		Message:   fmt.Sprintf("探测 %s (%s %s) 失败: %s", cfg.Name, cfg.Type, cfg.Target, current.Error),
+		Timestamp: current.Timestamp,
+		Details: map[string]interface{}{
+			"probe":  cfg.Name,
+			"type":   cfg.Type,
+			"target": cfg.Target,
+			"error":  current.Error,
+		},
+	}
+	if current.Success {
+		event.Type = EventProbeRecovered
+		event.Severity = EventSeverityInfo
+		event.Message = fmt.Sprintf("探测 %s (%s %s) 已恢复", cfg.Name, cfg.Type, cfg.Target)
+	}
+	return event, true
+}
+
+// runProbe 执行单次探测
+func runProbe(ctx context.Context, cfg ProbeConfig) *ProbeResult {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := &ProbeResult{
+		Type:      cfg.Type,
+		Target:    cfg.Target,
+		Labels:    cfg.Labels,
+		Timestamp: start,
+	}
+
+	var err error
+	switch cfg.Type {
+	case ProbeTypeHTTP:
+		err = probeHTTP(ctx, cfg, result)
+	case ProbeTypeTCP:
+		err = probeTCP(ctx, cfg)
+	case ProbeTypeDNS:
+		err = probeDNS(ctx, cfg, result)
+	case ProbeTypeICMP:
+		err = probeICMP(ctx, cfg, result)
+	}
+
+	// ICMP的耗时为往返时延，由probeICMP设置
+	if cfg.Type != ProbeTypeICMP {
+		result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+// probeHTTP 发送HTTP请求并检查状态码、响应体和证书有效期
+func probeHTTP(ctx context.Context, cfg ProbeConfig, result *ProbeResult) error {
+	req, err := http.NewRequestWithContext(ctx, cfg.Method, cfg.Target, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("User-Agent", "SysLens-Agent/Probe")
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	// 每次探测都建立新连接，耗时包含DNS解析、建连和TLS握手
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: cfg.TLSSkipVerify},
+			DisableKeepAlives: true,
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+
+	// 读取响应体后再计算耗时，与用户看到的完整响应时间一致
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		expiry := resp.TLS.PeerCertificates[0].NotAfter
+		for _, cert := range resp.TLS.PeerCertificates[1:] {
+			if cert.NotAfter.Before(expiry) {
+				expiry = cert.NotAfter
+			}
+		}
+		result.CertExpiry = &expiry
+		result.CertDaysLeft = time.Until(expiry).Hours() / 24
+	}
+
+	if !httpStatusExpected(resp.StatusCode, cfg.ExpectedStatus) {
+		return fmt.Errorf("状态码 %d 不符合预期", resp.StatusCode)
+	}
+	if cfg.bodyPattern != nil && !cfg.bodyPattern.Match(body) {
+		return fmt.Errorf("响应体不匹配 %s", cfg.BodyRegex)
+	}
+	if cfg.TLSExpiryThreshold > 0 && result.CertExpiry != nil && time.Until(*result.CertExpiry) < cfg.TLSExpiryThreshold {
+		return fmt.Errorf("证书将于 %s 过期，剩余 %.1f 天", result.CertExpiry.Format(time.RFC3339), result.CertDaysLeft)
+	}
+	return nil
+}
+
+// httpStatusExpected 判断状态码是否符合预期
+func httpStatusExpected(status int, expected []int) bool {
+	if len(expected) == 0 {
+		return status >= 200 && status < 400
+	}
+	for _, code := range expected {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// probeTCP 建立TCP连接后立即关闭
+func probeTCP(ctx context.Context, cfg ProbeConfig) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", cfg.Target)
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+	conn.Close()
+	return nil
+}
+
+// probeDNS 解析域名并检查解析结果
+func probeDNS(ctx context.Context, cfg ProbeConfig, result *ProbeResult) error {
+	resolver := net.DefaultResolver
+	if cfg.DNSServer != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, cfg.DNSServer)
+			},
+		}
+	}
+
+	var answers []string
+	switch cfg.RecordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if cfg.RecordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, cfg.Target)
+		if err != nil {
+			return fmt.Errorf("解析失败: %w", err)
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, cfg.Target)
+		if err != nil {
+			return fmt.Errorf("解析失败: %w", err)
+		}
+		answers = append(answers, cname)
+	case "MX":
+		records, err := resolver.LookupMX(ctx, cfg.Target)
+		if err != nil {
+			return fmt.Errorf("解析失败: %w", err)
+		}
+		for _, mx := range records {
+			answers = append(answers, mx.Host)
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, cfg.Target)
+		if err != nil {
+			return fmt.Errorf("解析失败: %w", err)
+		}
+		answers = records
+	case "NS":
+		records, err := resolver.LookupNS(ctx, cfg.Target)
+		if err != nil {
+			return fmt.Errorf("解析失败: %w", err)
+		}
+		for _, ns := range records {
+			answers = append(answers, ns.Host)
+		}
+	}
+
+	result.Answers = answers
+	if len(answers) == 0 {
+		return errors.New("未解析到任何记录")
+	}
+	if len(cfg.ExpectedAnswers) == 0 {
+		return nil
+	}
+	for _, expected := range cfg.ExpectedAnswers {
+		for _, answer := range answers {
+			if strings.TrimSuffix(answer, ".") == strings.TrimSuffix(expected, ".") {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("解析结果 %v 不包含期望的记录", answers)
+}
+
+// probeICMP 发送ICMP回显请求，统计往返时延和丢包率
+func probeICMP(ctx context.Context, cfg ProbeConfig, result *ProbeResult) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, cfg.Target)
+	if err != nil {
+		return fmt.Errorf("解析失败: %w", err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("未解析到 %s 的地址", cfg.Target)
+	}
+	// 优先使用IPv4地址
+	ip := addrs[0].IP
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			ip = addr.IP
+			break
+		}
+	}
+
+	conn, privileged, err := listenICMP(ip.To4() != nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var (
+		echoType     icmp.Type = ipv4.ICMPTypeEcho
+		replyType    icmp.Type = ipv4.ICMPTypeEchoReply
+		protocol               = 1
+		dst          net.Addr  = &net.UDPAddr{IP: ip}
+		id                     = os.Getpid() & 0xffff
+		totalRTT     time.Duration
+		perPacketTTL = cfg.Timeout / time.Duration(cfg.PingCount)
+	)
+	if ip.To4() == nil {
+		echoType, replyType, protocol = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, 58
+	}
+	if privileged {
+		dst = &net.IPAddr{IP: ip}
+	}
+
+	buf := make([]byte, 1500)
+	for seq := 1; seq <= cfg.PingCount && ctx.Err() == nil; seq++ {
+		msg := icmp.Message{
+			Type: echoType,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("syslens-probe")},
+		}
+		packet, err := msg.Marshal(nil)
+		if err != nil {
+			return fmt.Errorf("构造ICMP请求失败: %w", err)
+		}
+
+		start := time.Now()
+		if _, err := conn.WriteTo(packet, dst); err != nil {
+			return fmt.Errorf("发送ICMP请求失败: %w", err)
+		}
+		result.PacketsSent++
+
+		deadline := start.Add(perPacketTTL)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		conn.SetReadDeadline(deadline)
+
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				// 超时视为丢包
+				break
+			}
+			reply, err := icmp.ParseMessage(protocol, buf[:n])
+			if err != nil || reply.Type != replyType {
+				continue
+			}
+			echo, ok := reply.Body.(*icmp.Echo)
+			// 非特权模式下内核会改写ID，只能按序号匹配
+			if !ok || echo.Seq != seq || (privileged && echo.ID != id) {
+				continue
+			}
+			totalRTT += time.Since(start)
+			result.PacketsRecv++
+			break
+		}
+	}
+
+	if result.PacketsSent > 0 {
+		result.PacketLoss = float64(result.PacketsSent-result.PacketsRecv) / float64(result.PacketsSent) * 100
+	}
+	if result.PacketsRecv == 0 {
+		return fmt.Errorf("%d个请求全部超时", result.PacketsSent)
+	}
+	result.LatencyMs = float64(totalRTT.Microseconds()) / 1000 / float64(result.PacketsRecv)
+	return nil
+}
+
+// listenICMP 打开ICMP套接字，优先使用无需root权限的ping套接字（受net.ipv4.ping_group_range限制），
+// 失败时尝试原始套接字
+func listenICMP(ipv4Target bool) (*icmp.PacketConn, bool, error) {
+	unprivileged, privileged := "udp4", "ip4:icmp"
+	address := "0.0.0.0"
+	if !ipv4Target {
+		unprivileged, privileged = "udp6", "ip6:ipv6-icmp"
+		address = "::"
+	}
+
+	conn, err := icmp.ListenPacket(unprivileged, address)
+	if err == nil {
+		return conn, false, nil
+	}
+	conn, rawErr := icmp.ListenPacket(privileged, address)
+	if rawErr == nil {
+		return conn, true, nil
+	}
+	return nil, false, fmt.Errorf("打开ICMP套接字失败: %v; %v", err, rawErr)
+}
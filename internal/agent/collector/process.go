@@ -0,0 +1,289 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+const (
+	// 默认TopN进程数量
+	defaultMaxProcesses = 10
+	// 全量明细的最大条数，避免进程数异常时单次上报过大
+	maxAllProcesses = 2000
+)
+
+// ProcessOptions 进程采集选项
+type ProcessOptions struct {
+	// 是否启用进程采集
+	Enabled bool
+	// 是否上报全部进程明细（按CPU使用率排序，最多maxAllProcesses条）
+	CollectAll bool
+	// 需要重点监控的进程名称
+	Targets []string
+	// TopN列表的最大条数
+	MaxProcesses int
+	// 是否上报进程命令行参数
+	IncludeArgs bool
+}
+
+// ProcessStats 包含单个进程的资源使用信息
+type ProcessStats struct {
+	PID           int32   `json:"pid"`
+	Name          string  `json:"name"`
+	Cmdline       string  `json:"cmdline,omitempty"`
+	Username      string  `json:"username,omitempty"`
+	State         string  `json:"state"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryRSS     uint64  `json:"memory_rss"`
+	MemoryPercent float64 `json:"memory_percent"`
+	NumThreads    int32   `json:"num_threads"`
+	NumFDs        int32   `json:"num_fds"`
+	Uptime        uint64  `json:"uptime"`
+}
+
+// ProcessesStats 包含进程采集结果
+type ProcessesStats struct {
+	Total     int                       `json:"total"`
+	TopCPU    []ProcessStats            `json:"top_cpu"`
+	TopMemory []ProcessStats            `json:"top_memory"`
+	Targets   map[string][]ProcessStats `json:"targets,omitempty"`
+	All       []ProcessStats            `json:"all,omitempty"`
+}
+
+// ProcessCollector 进程指标收集器
+type ProcessCollector struct {
+	mu      sync.Mutex
+	options ProcessOptions
+
+	// 上次采集的进程CPU时间，用于计算区间内的CPU使用率
+	lastCPUTimes map[int32]processCPUTime
+	lastCollect  time.Time
+}
+
+// processCPUTime 记录进程的累计CPU时间，createTime用于识别PID复用
+type processCPUTime struct {
+	createTime int64
+	total      float64
+}
+
+// processSample 单次采集中的进程基础数据
+type processSample struct {
+	proc       *process.Process
+	name       string
+	createTime int64
+	rss        uint64
+	cpuPercent float64
+}
+
+// NewProcessCollector 创建新的进程收集器
+func NewProcessCollector(opts ProcessOptions) *ProcessCollector {
+	return &ProcessCollector{
+		options:      normalizeProcessOptions(opts),
+		lastCPUTimes: make(map[int32]processCPUTime),
+	}
+}
+
+// normalizeProcessOptions 为进程采集选项补充默认值
+func normalizeProcessOptions(opts ProcessOptions) ProcessOptions {
+	if opts.MaxProcesses <= 0 {
+		opts.MaxProcesses = defaultMaxProcesses
+	}
+	return opts
+}
+
+// Options 返回当前的进程采集选项
+func (pc *ProcessCollector) Options() ProcessOptions {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.options
+}
+
+// SetOptions 更新进程采集选项，用于应用主控端下发的配置
+func (pc *ProcessCollector) SetOptions(opts ProcessOptions) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.options = normalizeProcessOptions(opts)
+}
+
+// Collect 采集进程指标，未启用时返回nil
+func (pc *ProcessCollector) Collect() (*ProcessesStats, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if !pc.options.Enabled {
+		return nil, nil
+	}
+
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("获取进程列表失败: %w", err)
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(pc.lastCollect).Seconds()
+	currentCPUTimes := make(map[int32]processCPUTime, len(procs))
+	samples := make([]*processSample, 0, len(procs))
+
+	// 第一轮只采集开销较小的字段，用于排序和筛选
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil {
+			// 进程可能已经退出
+			continue
+		}
+
+		sample := &processSample{proc: p, name: name}
+		sample.createTime, _ = p.CreateTime()
+		if memInfo, err := p.MemoryInfo(); err == nil {
+			sample.rss = memInfo.RSS
+		}
+
+		var cpuTotal float64
+		if times, err := p.Times(); err == nil {
+			cpuTotal = times.User + times.System
+		}
+
+		prev, exists := pc.lastCPUTimes[p.Pid]
+		if exists && prev.createTime == sample.createTime && elapsed > 0 {
+			if delta := cpuTotal - prev.total; delta > 0 {
+				sample.cpuPercent = delta / elapsed * 100
+			}
+		} else if sample.createTime > 0 {
+			// 首次出现的进程使用其生命周期内的平均CPU使用率
+			if lifetime := float64(now.UnixMilli()-sample.createTime) / 1000; lifetime > 0 {
+				sample.cpuPercent = cpuTotal / lifetime * 100
+			}
+		}
+
+		currentCPUTimes[p.Pid] = processCPUTime{createTime: sample.createTime, total: cpuTotal}
+		samples = append(samples, sample)
+	}
+
+	// 保存当前采集结果，用于下次计算CPU使用率
+	pc.lastCPUTimes = currentCPUTimes
+	pc.lastCollect = now
+
+	var memTotal uint64
+	if memStat, err := mem.VirtualMemory(); err == nil {
+		memTotal = memStat.Total
+	}
+
+	// 第二轮只为入选的进程采集完整信息，同一进程只采集一次
+	details := make(map[int32]ProcessStats)
+	detail := func(s *processSample) ProcessStats {
+		if ps, ok := details[s.proc.Pid]; ok {
+			return ps
+		}
+		ps := pc.buildProcessStats(s, now, memTotal)
+		details[s.proc.Pid] = ps
+		return ps
+	}
+
+	return selectProcesses(samples, pc.options, detail), nil
+}
+
+// selectProcesses 根据采集选项从进程样本中挑选TopN、全量明细及重点监控进程
+// detail用于获取入选进程的完整信息
+func selectProcesses(samples []*processSample, opts ProcessOptions, detail func(*processSample) ProcessStats) *ProcessesStats {
+	maxProcs := opts.MaxProcesses
+	result := &ProcessesStats{
+		Total:     len(samples),
+		TopCPU:    []ProcessStats{},
+		TopMemory: []ProcessStats{},
+	}
+
+	// 按CPU使用率排序
+	byCPU := make([]*processSample, len(samples))
+	copy(byCPU, samples)
+	sort.SliceStable(byCPU, func(i, j int) bool {
+		return byCPU[i].cpuPercent > byCPU[j].cpuPercent
+	})
+	for i := 0; i < len(byCPU) && i < maxProcs; i++ {
+		result.TopCPU = append(result.TopCPU, detail(byCPU[i]))
+	}
+
+	// 全量明细沿用CPU排序，超过上限时舍弃CPU使用率最低的进程
+	if opts.CollectAll {
+		n := min(len(byCPU), maxAllProcesses)
+		result.All = make([]ProcessStats, 0, n)
+		for i := 0; i < n; i++ {
+			result.All = append(result.All, detail(byCPU[i]))
+		}
+	}
+
+	// 按常驻内存排序
+	byMemory := make([]*processSample, len(samples))
+	copy(byMemory, samples)
+	sort.SliceStable(byMemory, func(i, j int) bool {
+		return byMemory[i].rss > byMemory[j].rss
+	})
+	for i := 0; i < len(byMemory) && i < maxProcs; i++ {
+		result.TopMemory = append(result.TopMemory, detail(byMemory[i]))
+	}
+
+	// 重点监控进程，未运行的进程保留空列表，便于主控端判断进程是否存活
+	if len(opts.Targets) > 0 {
+		result.Targets = make(map[string][]ProcessStats, len(opts.Targets))
+		for _, target := range opts.Targets {
+			result.Targets[target] = []ProcessStats{}
+		}
+		for _, s := range byCPU {
+			if _, ok := result.Targets[s.name]; ok {
+				result.Targets[s.name] = append(result.Targets[s.name], detail(s))
+			}
+		}
+	}
+
+	return result
+}
+
+// buildProcessStats 采集单个进程的完整信息
+func (pc *ProcessCollector) buildProcessStats(s *processSample, now time.Time, memTotal uint64) ProcessStats {
+	ps := ProcessStats{
+		PID:        s.proc.Pid,
+		Name:       s.name,
+		CPUPercent: s.cpuPercent,
+		MemoryRSS:  s.rss,
+	}
+
+	if memTotal > 0 {
+		ps.MemoryPercent = float64(s.rss) / float64(memTotal) * 100
+	}
+
+	if status, err := s.proc.Status(); err == nil && len(status) > 0 {
+		ps.State = status[0]
+	}
+
+	if threads, err := s.proc.NumThreads(); err == nil {
+		ps.NumThreads = threads
+	}
+
+	// 读取其他用户进程的文件描述符需要root权限，失败时保持为0
+	if fds, err := s.proc.NumFDs(); err == nil {
+		ps.NumFDs = fds
+	}
+
+	if username, err := s.proc.Username(); err == nil {
+		ps.Username = username
+	}
+
+	if s.createTime > 0 {
+		if uptime := now.UnixMilli() - s.createTime; uptime > 0 {
+			ps.Uptime = uint64(uptime / 1000)
+		}
+	}
+
+	if pc.options.IncludeArgs {
+		if cmdline, err := s.proc.Cmdline(); err == nil {
+			ps.Cmdline = strings.TrimSpace(cmdline)
+		}
+	}
+
+	return ps
+}
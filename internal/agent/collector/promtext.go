@@ -0,0 +1,177 @@
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// promSample Prometheus文本格式中的一个样本
+type promSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+	// 指标类型（counter/gauge/histogram/summary/untyped），来自# TYPE注释
+	Type string
+}
+
+// parsePrometheusText 解析Prometheus文本格式(exposition format 0.0.4)
+// 非有限值（NaN、±Inf）无法编码为JSON，直接丢弃；maxSamples大于0时超出部分返回错误
+func parsePrometheusText(r io.Reader, maxSamples int) ([]promSample, error) {
+	var samples []promSample
+	types := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			// 只关心类型声明: # TYPE name type
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			continue
+		}
+
+		sample, err := parsePromLine(line)
+		if err != nil {
+			return samples, fmt.Errorf("第%d行: %w", lineNo, err)
+		}
+		if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+			continue
+		}
+
+		sample.Type = promSampleType(sample.Name, types)
+		if maxSamples > 0 && len(samples) >= maxSamples {
+			return samples, fmt.Errorf("样本数超过上限%d", maxSamples)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return samples, err
+	}
+
+	return samples, nil
+}
+
+// parsePromLine 解析单行样本: name{label="value",...} value [timestamp]
+func parsePromLine(line string) (promSample, error) {
+	sample := promSample{}
+
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd <= 0 {
+		return sample, fmt.Errorf("无效的样本: %q", line)
+	}
+	sample.Name = line[:nameEnd]
+	rest := line[nameEnd:]
+
+	if strings.HasPrefix(rest, "{") {
+		labels, remaining, err := parsePromLabels(rest[1:])
+		if err != nil {
+			return sample, err
+		}
+		sample.Labels = labels
+		rest = remaining
+	}
+
+	// 时间戳由采集端统一设置，忽略样本自带的时间戳
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return sample, fmt.Errorf("样本缺少值: %q", line)
+	}
+	value, err := parsePromValue(fields[0])
+	if err != nil {
+		return sample, fmt.Errorf("无效的样本值 %q: %w", fields[0], err)
+	}
+	sample.Value = value
+
+	return sample, nil
+}
+
+// parsePromLabels 解析标签集合，输入为"{"之后的内容，返回"}"之后的剩余内容
+func parsePromLabels(s string) (map[string]string, string, error) {
+	labels := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if strings.HasPrefix(s, "}") {
+			return labels, s[1:], nil
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, "", fmt.Errorf("无效的标签: %q", s)
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " \t")
+		if !strings.HasPrefix(s, `"`) {
+			return nil, "", fmt.Errorf("标签%s的值缺少引号", name)
+		}
+
+		// 标签值支持\\、\"和\n转义
+		var value strings.Builder
+		i := 1
+		closed := false
+		for ; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			if c == '"' {
+				closed = true
+				break
+			}
+			value.WriteByte(c)
+		}
+		if !closed {
+			return nil, "", fmt.Errorf("标签%s的值缺少结束引号", name)
+		}
+
+		labels[name] = value.String()
+		s = s[i+1:]
+	}
+}
+
+// parsePromValue 解析样本值，支持NaN和±Inf
+func parsePromValue(s string) (float64, error) {
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "+Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// promSampleType 根据# TYPE声明确定样本类型，histogram和summary的样本带有_bucket等后缀
+func promSampleType(name string, types map[string]string) string {
+	if t, ok := types[name]; ok {
+		return t
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_total", "_created"} {
+		if base := strings.TrimSuffix(name, suffix); base != name {
+			if t, ok := types[base]; ok {
+				return t
+			}
+		}
+	}
+	return "untyped"
+}
@@ -0,0 +1,386 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 默认的子收集器超时时间
+const defaultCollectorTimeout = 5 * time.Second
+
+// 内置子收集器名称
+const (
+	CollectorCPU          = "cpu"
+	CollectorMemory       = "memory"
+	CollectorPressure     = "pressure"
+	CollectorDisk         = "disk"
+	CollectorDiskIO       = "disk_io"
+	CollectorNetwork      = "network"
+	CollectorProcesses    = "processes"
+	CollectorCgroups      = "cgroups"
+	CollectorSystemd      = "systemd"
+	CollectorSensors      = "sensors"
+	CollectorTimeSync     = "time_sync"
+	CollectorHardware     = "hardware"
+	CollectorCustom       = "custom"
+	CollectorScrape       = "scrape"
+	CollectorProbes       = "probes"
+	CollectorLogs         = "logs"
+	CollectorCertificates = "certificates"
+	CollectorIntegrity    = "integrity"
+)
+
+// CollectFunc 子收集器的采集函数，将结果写入传入的SystemStats
+// 每次调用都会传入新的SystemStats，采集函数之间互不共享；ctx在超时后取消
+// 返回错误时已写入的部分结果仍会被合并
+type CollectFunc func(ctx context.Context, stats *SystemStats, now time.Time) error
+
+// CollectorSettings 子收集器的运行参数
+type CollectorSettings struct {
+	Enabled bool
+	// 采集间隔，为0时每个采集周期都执行，未到间隔时沿用上次的结果
+	Interval time.Duration
+	// 单次采集的超时时间，为0时使用默认值
+	Timeout time.Duration
+}
+
+// subCollector 注册表中的子收集器及其运行状态
+type subCollector struct {
+	name     string
+	collect  CollectFunc
+	settings CollectorSettings
+
+	// 上次采集仍未结束（如NFS挂载点卡住）时不会重复启动
+	running bool
+	lastRun time.Time
+	last    *SystemStats
+}
+
+// Registry 子收集器注册表，各子收集器按自己的间隔和超时并行执行，结果合并为一份报告
+type Registry struct {
+	mu         sync.Mutex
+	collectors []*subCollector
+	index      map[string]*subCollector
+}
+
+// NewRegistry 创建新的子收集器注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		index: make(map[string]*subCollector),
+	}
+}
+
+// Register 注册子收集器，同名的子收集器会被替换
+func (r *Registry) Register(name string, collect CollectFunc, settings CollectorSettings) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub := &subCollector{
+		name:     name,
+		collect:  collect,
+		settings: normalizeCollectorSettings(settings),
+	}
+
+	if existing, ok := r.index[name]; ok {
+		for i, c := range r.collectors {
+			if c == existing {
+				r.collectors[i] = sub
+				break
+			}
+		}
+	} else {
+		r.collectors = append(r.collectors, sub)
+	}
+	r.index[name] = sub
+}
+
+// Configure 更新子收集器的运行参数
+func (r *Registry) Configure(name string, settings CollectorSettings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.index[name]
+	if !ok {
+		return fmt.Errorf("未知的子收集器: %s", name)
+	}
+	sub.settings = normalizeCollectorSettings(settings)
+	// 参数变化后立即按新参数采集
+	sub.last = nil
+	return nil
+}
+
+// Settings 返回子收集器的运行参数
+func (r *Registry) Settings(name string) (CollectorSettings, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.index[name]
+	if !ok {
+		return CollectorSettings{}, false
+	}
+	return sub.settings, true
+}
+
+// Names 按注册顺序返回所有子收集器名称
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.collectors))
+	for _, sub := range r.collectors {
+		names = append(names, sub.name)
+	}
+	return names
+}
+
+// Run 并行执行所有启用的子收集器，并将结果合并到stats中
+// 未到采集间隔、上次采集尚未结束或本次超时的子收集器沿用上次的结果
+// 各子收集器的错误记录在stats.CollectorErrors中，并按注册顺序合并后返回
+func (r *Registry) Run(stats *SystemStats, now time.Time) error {
+	type task struct {
+		sub      *subCollector
+		settings CollectorSettings
+		cached   *SystemStats
+		due      bool
+	}
+
+	r.mu.Lock()
+	tasks := make([]task, 0, len(r.collectors))
+	for _, sub := range r.collectors {
+		if !sub.settings.Enabled {
+			continue
+		}
+
+		t := task{sub: sub, settings: sub.settings, cached: sub.last}
+		if !sub.running && (sub.last == nil || now.Sub(sub.lastRun) >= sub.settings.Interval) {
+			t.due = true
+			sub.running = true
+		}
+		tasks = append(tasks, t)
+	}
+	r.mu.Unlock()
+
+	// 每个子收集器写入自己的结果，互不共享
+	results := make([]*SystemStats, len(tasks))
+	errs := make([]error, len(tasks))
+	var wg sync.WaitGroup
+	for i, t := range tasks {
+		if !t.due {
+			results[i] = reusedStats(t.cached)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, t task) {
+			defer wg.Done()
+			results[i], errs[i] = r.runWithTimeout(t.sub, t.settings.Timeout, t.cached, now)
+		}(i, t)
+	}
+	wg.Wait()
+
+	// 所有子收集器结束后再合并，避免并发写入同一份报告
+	for i, partial := range results {
+		if partial != nil {
+			mergeStats(stats, partial)
+		}
+		if errs[i] != nil {
+			if stats.CollectorErrors == nil {
+				stats.CollectorErrors = make(map[string]string)
+			}
+			stats.CollectorErrors[tasks[i].sub.name] = errs[i].Error()
+			errs[i] = fmt.Errorf("%s: %w", tasks[i].sub.name, errs[i])
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runWithTimeout 执行单个子收集器，超时后取消ctx、放弃等待并沿用上次的结果
+func (r *Registry) runWithTimeout(sub *subCollector, timeout time.Duration, cached *SystemStats, now time.Time) (*SystemStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	partial := newSystemStats(now)
+	var collectErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		collectErr = sub.collect(ctx, partial, now)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		select {
+		case <-done:
+			// 采集与超时同时结束时仍使用本次的结果
+		default:
+			// 超时的采集在后台结束后才允许再次执行，避免卡住的调用不断堆积
+			go func() {
+				<-done
+				cancel()
+				r.mu.Lock()
+				sub.running = false
+				r.mu.Unlock()
+			}()
+			return reusedStats(cached), fmt.Errorf("采集超时(%v)，沿用上次的结果", timeout)
+		}
+	}
+
+	cancel()
+	r.mu.Lock()
+	sub.running = false
+	sub.lastRun = now
+	sub.last = partial
+	r.mu.Unlock()
+	return partial, collectErr
+}
+
+// normalizeCollectorSettings 为子收集器运行参数补充默认值
+func normalizeCollectorSettings(settings CollectorSettings) CollectorSettings {
+	if settings.Interval < 0 {
+		settings.Interval = 0
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = defaultCollectorTimeout
+	}
+	return settings
+}
+
+// newSystemStats 创建初始化了各映射的空指标
+func newSystemStats(now time.Time) *SystemStats {
+	return &SystemStats{
+		Timestamp:   now,
+		CurrentTime: now.Format(time.RFC3339),
+		CPU:         make(map[string]float64),
+		Disk:        make(map[string]DiskStats),
+		DiskIO:      make(map[string]DiskIOStats),
+		Network: NetworkStats{
+			Interfaces:  make(map[string]InterfaceStats),
+			PublicIPv4:  []string{},
+			PublicIPv6:  []string{},
+			PrivateIPv4: []string{},
+			PrivateIPv6: []string{},
+		},
+	}
+}
+
+// reusedStats 返回沿用的上次结果，事件、硬件清单和日志计数只在产生时上报一次
+func reusedStats(last *SystemStats) *SystemStats {
+	if last == nil {
+		return nil
+	}
+	reused := *last
+	reused.Events = nil
+	reused.Inventory = nil
+	// 日志计数是采集间隔内的增量，重复上报会被重复统计
+	reused.Logs = nil
+	return &reused
+}
+
+// mergeStats 将子收集器的结果合并到报告中
+func mergeStats(dst, src *SystemStats) {
+	for k, v := range src.CPU {
+		dst.CPU[k] = v
+	}
+	if src.PerCPU != nil {
+		dst.PerCPU = src.PerCPU
+	}
+	if src.LoadAvg != (LoadAvgStats{}) {
+		dst.LoadAvg = src.LoadAvg
+	}
+	if src.Memory != (MemoryStats{}) {
+		dst.Memory = src.Memory
+	}
+	for k, v := range src.Disk {
+		dst.Disk[k] = v
+	}
+	for k, v := range src.DiskIO {
+		dst.DiskIO[k] = v
+	}
+	if networkCollected(src.Network) {
+		dst.Network = src.Network
+	}
+	if src.Pressure != nil {
+		dst.Pressure = src.Pressure
+	}
+	if src.VMStat != nil {
+		dst.VMStat = src.VMStat
+	}
+	if src.Cgroups != nil {
+		dst.Cgroups = src.Cgroups
+	}
+	if src.Processes != nil {
+		dst.Processes = src.Processes
+	}
+	if src.Listeners != nil {
+		dst.Listeners = src.Listeners
+	}
+	if src.Systemd != nil {
+		dst.Systemd = src.Systemd
+	}
+	if src.Sensors != nil {
+		dst.Sensors = src.Sensors
+	}
+	if src.TimeSync != nil {
+		dst.TimeSync = src.TimeSync
+	}
+	// 插件和Prometheus抓取的结果都上报在custom中，按名称合并
+	for k, v := range src.Custom {
+		if dst.Custom == nil {
+			dst.Custom = make(map[string]PluginResult)
+		}
+		dst.Custom[k] = v
+	}
+	if src.Probes != nil {
+		dst.Probes = src.Probes
+	}
+	if src.Logs != nil {
+		dst.Logs = src.Logs
+	}
+	if src.Certificates != nil {
+		dst.Certificates = src.Certificates
+	}
+	if src.Integrity != nil {
+		dst.Integrity = src.Integrity
+	}
+	if src.Inventory != nil {
+		dst.Inventory = src.Inventory
+	}
+	mergeHardwareInfo(&dst.Hardware, src.Hardware)
+	dst.Events = append(dst.Events, src.Events...)
+}
+
+// mergeHardwareInfo 合并硬件参数中已填充的字段
+func mergeHardwareInfo(dst *HardwareInfo, src HardwareInfo) {
+	if src.CPUModel != "" {
+		dst.CPUModel = src.CPUModel
+	}
+	if src.CPUCores > 0 {
+		dst.CPUCores = src.CPUCores
+	}
+	if src.CPUThreads > 0 {
+		dst.CPUThreads = src.CPUThreads
+	}
+	if src.CPUSockets > 0 {
+		dst.CPUSockets = src.CPUSockets
+	}
+	if src.MemoryTotal > 0 {
+		dst.MemoryTotal = src.MemoryTotal
+	}
+	if src.DiskTotal > 0 {
+		dst.DiskTotal = src.DiskTotal
+	}
+	if src.GPUModel != nil {
+		dst.GPUModel = src.GPUModel
+		dst.GPUMemory = src.GPUMemory
+	}
+}
+
+// networkCollected 判断子收集器是否采集了网络信息
+func networkCollected(network NetworkStats) bool {
+	return len(network.Interfaces) > 0 || network.TCPStates != nil || network.TCP != nil ||
+		len(network.PublicIPv4) > 0 || len(network.PrivateIPv4) > 0 ||
+		len(network.PublicIPv6) > 0 || len(network.PrivateIPv6) > 0
+}
@@ -0,0 +1,301 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultScrapeInterval = 15 * time.Second
+	defaultScrapeTimeout  = 5 * time.Second
+	// 单个目标默认最多上报的序列数
+	defaultScrapeMaxSeries = 1000
+	// 所有目标合计默认最多上报的序列数
+	defaultScrapeMaxTotalSeries = 10000
+	// 单次抓取的最大响应大小
+	maxScrapeBodySize = 10 * 1024 * 1024
+)
+
+// textfile目录中的结果以此为前缀，与抓取目标和插件区分
+const textfileResultPrefix = "textfile:"
+
+// ScrapeTarget 本地Prometheus exporter抓取目标
+type ScrapeTarget struct {
+	// 目标名称，作为custom中的键
+	Name     string
+	URL      string
+	Interval time.Duration
+	Timeout  time.Duration
+	// 单个目标最多上报的序列数
+	MaxSeries int
+	// 附加到结果上的标签
+	Labels map[string]string
+}
+
+// ScrapeOptions Prometheus抓取选项
+type ScrapeOptions struct {
+	Targets []ScrapeTarget
+	// node_exporter风格的textfile目录，读取其中的*.prom文件
+	TextfileDir string
+	// 仅上报匹配的指标名称（支持通配符，为空表示所有）
+	IncludeMetrics []string
+	// 不上报的指标名称（支持通配符）
+	ExcludeMetrics []string
+	// 所有目标合计最多上报的序列数
+	MaxTotalSeries int
+}
+
+// scrapeState 单个抓取目标的运行状态
+type scrapeState struct {
+	target  ScrapeTarget
+	running bool
+	lastRun time.Time
+	result  *PluginResult
+}
+
+// ScrapeCollector Prometheus exporter及textfile目录收集器
+// 抓取在后台按各目标的间隔执行，Collect只返回最近一次的结果
+type ScrapeCollector struct {
+	mu      sync.Mutex
+	options ScrapeOptions
+	targets []*scrapeState
+	client  *http.Client
+}
+
+// NewScrapeCollector 创建新的Prometheus抓取收集器，无效的目标配置会被忽略
+func NewScrapeCollector(opts ScrapeOptions) *ScrapeCollector {
+	if opts.MaxTotalSeries <= 0 {
+		opts.MaxTotalSeries = defaultScrapeMaxTotalSeries
+	}
+
+	sc := &ScrapeCollector{
+		options: opts,
+		client:  &http.Client{},
+	}
+
+	seen := make(map[string]bool)
+	for _, target := range opts.Targets {
+		if target.URL == "" {
+			log.Printf("忽略未配置URL的抓取目标: %s", target.Name)
+			continue
+		}
+		if target.Name == "" {
+			target.Name = target.URL
+		}
+		if seen[target.Name] {
+			log.Printf("忽略重复的抓取目标: %s", target.Name)
+			continue
+		}
+		seen[target.Name] = true
+
+		if target.Interval <= 0 {
+			target.Interval = defaultScrapeInterval
+		}
+		if target.Timeout <= 0 {
+			target.Timeout = defaultScrapeTimeout
+		}
+		if target.MaxSeries <= 0 {
+			target.MaxSeries = defaultScrapeMaxSeries
+		}
+		sc.targets = append(sc.targets, &scrapeState{target: target})
+	}
+
+	return sc
+}
+
+// Collect 启动到期的抓取并返回各目标最近一次的结果，同时读取textfile目录
+// 未配置任何目标时返回nil
+func (sc *ScrapeCollector) Collect(now time.Time) map[string]PluginResult {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if len(sc.targets) == 0 && sc.options.TextfileDir == "" {
+		return nil
+	}
+
+	results := make(map[string]PluginResult)
+	for _, state := range sc.targets {
+		if !state.running && (state.result == nil || now.Sub(state.lastRun) >= state.target.Interval) {
+			state.running = true
+			state.lastRun = now
+			go func(state *scrapeState) {
+				result := sc.scrape(context.Background(), state.target)
+
+				sc.mu.Lock()
+				state.running = false
+				state.result = result
+				sc.mu.Unlock()
+			}(state)
+		}
+
+		if state.result != nil {
+			results[state.target.Name] = *state.result
+		}
+	}
+
+	// textfile由其他程序定期写入，读取本地文件很快，每个周期直接读取
+	if sc.options.TextfileDir != "" {
+		for name, result := range sc.readTextfiles(now) {
+			results[name] = result
+		}
+	}
+
+	sc.limitTotalSeries(results)
+
+	return results
+}
+
+// scrape 抓取单个目标并转换为自定义指标
+func (sc *ScrapeCollector) scrape(ctx context.Context, target ScrapeTarget) *PluginResult {
+	ctx, cancel := context.WithTimeout(ctx, target.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := &PluginResult{
+		Status:    PluginStatusOK,
+		Labels:    target.Labels,
+		Timestamp: start,
+		Metrics:   []CustomMetric{},
+	}
+	defer func() {
+		result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		result.Status = PluginStatusError
+		result.Error = fmt.Sprintf("创建抓取请求失败: %v", err)
+		return result
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4;q=1,*/*;q=0.1")
+	req.Header.Set("User-Agent", "SysLens-Agent/Scrape")
+
+	resp, err := sc.client.Do(req)
+	if err != nil {
+		result.Status = PluginStatusError
+		result.Error = fmt.Sprintf("抓取失败: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		result.Status = PluginStatusError
+		result.Error = fmt.Sprintf("抓取失败，状态码: %d", resp.StatusCode)
+		return result
+	}
+
+	sc.fillMetrics(result, io.LimitReader(resp.Body, maxScrapeBodySize), target.MaxSeries)
+	return result
+}
+
+// readTextfiles 读取textfile目录中的*.prom文件，每个文件作为一个结果
+func (sc *ScrapeCollector) readTextfiles(now time.Time) map[string]PluginResult {
+	files, err := filepath.Glob(filepath.Join(sc.options.TextfileDir, "*.prom"))
+	if err != nil {
+		return nil
+	}
+
+	results := make(map[string]PluginResult, len(files))
+	for _, file := range files {
+		name := textfileResultPrefix + strings.TrimSuffix(filepath.Base(file), ".prom")
+		result := PluginResult{
+			Status:    PluginStatusOK,
+			Timestamp: now,
+			Metrics:   []CustomMetric{},
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			result.Status = PluginStatusError
+			result.Error = err.Error()
+			results[name] = result
+			continue
+		}
+		sc.fillMetrics(&result, io.LimitReader(f, maxScrapeBodySize), defaultScrapeMaxSeries)
+		f.Close()
+
+		// 文件的修改时间代表数据的实际产生时间
+		if info, err := os.Stat(file); err == nil {
+			result.Timestamp = info.ModTime()
+		}
+		results[name] = result
+	}
+
+	return results
+}
+
+// fillMetrics 解析Prometheus文本并按名称过滤后写入结果
+func (sc *ScrapeCollector) fillMetrics(result *PluginResult, r io.Reader, maxSeries int) {
+	samples, err := parsePrometheusText(r, 0)
+	if err != nil {
+		result.Status = PluginStatusError
+		result.Error = "解析指标失败: " + err.Error()
+	}
+
+	for _, sample := range samples {
+		if !sc.metricAllowed(sample.Name) {
+			continue
+		}
+		if len(result.Metrics) >= maxSeries {
+			result.Error = strings.TrimSpace(result.Error + fmt.Sprintf(" 序列数超过上限%d，多余的序列已丢弃", maxSeries))
+			break
+		}
+		result.Metrics = append(result.Metrics, CustomMetric{
+			Name:   sample.Name,
+			Value:  sample.Value,
+			Type:   sample.Type,
+			Labels: sample.Labels,
+		})
+	}
+}
+
+// metricAllowed 判断指标名称是否满足包含/排除规则
+func (sc *ScrapeCollector) metricAllowed(name string) bool {
+	if len(sc.options.IncludeMetrics) > 0 && !matchAnyName(sc.options.IncludeMetrics, name) {
+		return false
+	}
+	return !matchAnyName(sc.options.ExcludeMetrics, name)
+}
+
+// limitTotalSeries 限制所有目标合计的序列数，按名称顺序保留，超出的目标被截断
+func (sc *ScrapeCollector) limitTotalSeries(results map[string]PluginResult) {
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	remaining := sc.options.MaxTotalSeries
+	for _, name := range names {
+		result := results[name]
+		if len(result.Metrics) <= remaining {
+			remaining -= len(result.Metrics)
+			continue
+		}
+
+		result.Metrics = result.Metrics[:remaining]
+		result.Error = strings.TrimSpace(result.Error + fmt.Sprintf(" 合计序列数超过上限%d，多余的序列已丢弃", sc.options.MaxTotalSeries))
+		results[name] = result
+		remaining = 0
+	}
+}
+
+// matchAnyName 判断名称是否匹配任一通配符模式
+func matchAnyName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
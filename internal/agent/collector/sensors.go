@@ -0,0 +1,238 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 传感器类型
+const (
+	SensorKindTemperature = "temperature"
+	SensorKindFan         = "fan"
+)
+
+// hwmon中的传感器输入文件，如temp1_input、fan2_input
+var hwmonInputPattern = regexp.MustCompile(`^(temp|fan)(\d+)_input$`)
+
+// SensorStats 包含单个硬件传感器的读数，温度单位为摄氏度，风扇单位为RPM
+type SensorStats struct {
+	Chip   string `json:"chip"`
+	Sensor string `json:"sensor"`
+	Kind   string `json:"kind"`
+	// 数据来源：hwmon或thermal
+	Source string  `json:"source"`
+	Value  float64 `json:"value"`
+	// 阈值，驱动未提供时为0
+	Min      float64 `json:"min,omitempty"`
+	Max      float64 `json:"max,omitempty"`
+	Critical float64 `json:"critical,omitempty"`
+	// 驱动报告告警或读数达到临界阈值
+	Alarm  bool              `json:"alarm"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// SensorCollector 硬件传感器收集器，读取/sys/class/hwmon和/sys/class/thermal
+type SensorCollector struct {
+	mu      sync.Mutex
+	enabled bool
+}
+
+// NewSensorCollector 创建新的硬件传感器收集器
+func NewSensorCollector(enabled bool) *SensorCollector {
+	return &SensorCollector{enabled: enabled}
+}
+
+// Collect 采集sysRoot下所有温度和风扇传感器的读数
+// 未启用或节点没有传感器（如虚拟机）时返回nil
+func (sc *SensorCollector) Collect(sysRoot string) ([]SensorStats, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if !sc.enabled {
+		return nil, nil
+	}
+
+	sensors := readHwmonSensors(filepath.Join(sysRoot, "class", "hwmon"))
+	sensors = append(sensors, readThermalZones(filepath.Join(sysRoot, "class", "thermal"))...)
+
+	sort.Slice(sensors, func(i, j int) bool {
+		if sensors[i].Source != sensors[j].Source {
+			return sensors[i].Source < sensors[j].Source
+		}
+		if sensors[i].Chip != sensors[j].Chip {
+			return sensors[i].Chip < sensors[j].Chip
+		}
+		if sensors[i].Labels["device"] != sensors[j].Labels["device"] {
+			return sensors[i].Labels["device"] < sensors[j].Labels["device"]
+		}
+		return sensors[i].Sensor < sensors[j].Sensor
+	})
+
+	return sensors, nil
+}
+
+// readHwmonSensors 读取hwmon设备的温度和风扇传感器
+func readHwmonSensors(hwmonDir string) []SensorStats {
+	entries, err := os.ReadDir(hwmonDir)
+	if err != nil {
+		return nil
+	}
+
+	var sensors []SensorStats
+	for _, entry := range entries {
+		chipDir := filepath.Join(hwmonDir, entry.Name())
+
+		// 旧版驱动将属性文件放在device子目录中
+		if _, err := os.Stat(filepath.Join(chipDir, "name")); err != nil {
+			if _, err := os.Stat(filepath.Join(chipDir, "device", "name")); err == nil {
+				chipDir = filepath.Join(chipDir, "device")
+			}
+		}
+
+		chip := readSysfsString(filepath.Join(chipDir, "name"))
+		if chip == "" {
+			chip = entry.Name()
+		}
+
+		labels := map[string]string{"hwmon": entry.Name()}
+		// 同名芯片（如多路CPU的coretemp）通过设备名区分
+		if device, err := os.Readlink(filepath.Join(hwmonDir, entry.Name(), "device")); err == nil {
+			labels["device"] = filepath.Base(device)
+		}
+
+		files, err := os.ReadDir(chipDir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			match := hwmonInputPattern.FindStringSubmatch(file.Name())
+			if match == nil {
+				continue
+			}
+			prefix := match[1] + match[2]
+
+			// 未接入的传感器读取时会返回错误，直接跳过
+			value, ok := readSysfsFloat(filepath.Join(chipDir, file.Name()))
+			if !ok {
+				continue
+			}
+
+			sensor := SensorStats{
+				Chip:   chip,
+				Sensor: readSysfsString(filepath.Join(chipDir, prefix+"_label")),
+				Source: "hwmon",
+				Labels: labels,
+			}
+			if sensor.Sensor == "" {
+				sensor.Sensor = prefix
+			}
+
+			if match[1] == "temp" {
+				// 温度单位为千分之一摄氏度
+				sensor.Kind = SensorKindTemperature
+				sensor.Value = value / 1000
+				if v, ok := readSysfsFloat(filepath.Join(chipDir, prefix+"_max")); ok {
+					sensor.Max = v / 1000
+				}
+				if v, ok := readSysfsFloat(filepath.Join(chipDir, prefix+"_crit")); ok {
+					sensor.Critical = v / 1000
+				}
+				sensor.Alarm = readSysfsString(filepath.Join(chipDir, prefix+"_crit_alarm")) == "1"
+			} else {
+				sensor.Kind = SensorKindFan
+				sensor.Value = value
+				if v, ok := readSysfsFloat(filepath.Join(chipDir, prefix+"_min")); ok {
+					sensor.Min = v
+				}
+				if v, ok := readSysfsFloat(filepath.Join(chipDir, prefix+"_max")); ok {
+					sensor.Max = v
+				}
+			}
+
+			if readSysfsString(filepath.Join(chipDir, prefix+"_alarm")) == "1" {
+				sensor.Alarm = true
+			}
+			if sensor.Critical > 0 && sensor.Value >= sensor.Critical {
+				sensor.Alarm = true
+			}
+
+			sensors = append(sensors, sensor)
+		}
+	}
+
+	return sensors
+}
+
+// readThermalZones 读取内核thermal子系统的温度区域及其触发点
+func readThermalZones(thermalDir string) []SensorStats {
+	entries, err := os.ReadDir(thermalDir)
+	if err != nil {
+		return nil
+	}
+
+	var sensors []SensorStats
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "thermal_zone") {
+			continue
+		}
+		zoneDir := filepath.Join(thermalDir, entry.Name())
+
+		value, ok := readSysfsFloat(filepath.Join(zoneDir, "temp"))
+		if !ok {
+			continue
+		}
+
+		chip := readSysfsString(filepath.Join(zoneDir, "type"))
+		if chip == "" {
+			chip = entry.Name()
+		}
+
+		sensor := SensorStats{
+			Chip:   chip,
+			Sensor: entry.Name(),
+			Kind:   SensorKindTemperature,
+			Source: "thermal",
+			Value:  value / 1000,
+		}
+
+		// 触发点类型为critical时系统将关机，hot次之
+		for i := 0; ; i++ {
+			tripType := readSysfsString(filepath.Join(zoneDir, "trip_point_"+strconv.Itoa(i)+"_type"))
+			if tripType == "" {
+				break
+			}
+			tripTemp, ok := readSysfsFloat(filepath.Join(zoneDir, "trip_point_"+strconv.Itoa(i)+"_temp"))
+			if !ok || tripTemp <= 0 {
+				continue
+			}
+			switch tripType {
+			case "critical":
+				sensor.Critical = tripTemp / 1000
+			case "hot":
+				sensor.Max = tripTemp / 1000
+			}
+		}
+
+		if sensor.Critical > 0 && sensor.Value >= sensor.Critical {
+			sensor.Alarm = true
+		}
+
+		sensors = append(sensors, sensor)
+	}
+
+	return sensors
+}
+
+// readSysfsFloat 读取sysfs中的数值文件
+func readSysfsFloat(path string) (float64, bool) {
+	value, err := strconv.ParseFloat(readSysfsString(path), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
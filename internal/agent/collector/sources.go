@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// systemSources 采集使用的gopsutil数据源，测试中可替换为模拟实现
+// 所有数据源都接受context，子收集器超时后可以尽早放弃
+type systemSources struct {
+	hostInfo       func(ctx context.Context) (*host.InfoStat, error)
+	cpuTimes       func(ctx context.Context, percpu bool) ([]cpu.TimesStat, error)
+	loadAvg        func(ctx context.Context) (*load.AvgStat, error)
+	virtualMemory  func(ctx context.Context) (*mem.VirtualMemoryStat, error)
+	swapMemory     func(ctx context.Context) (*mem.SwapMemoryStat, error)
+	diskPartitions func(ctx context.Context, all bool) ([]disk.PartitionStat, error)
+	diskUsage      func(ctx context.Context, path string) (*disk.UsageStat, error)
+	diskIOCounters func(ctx context.Context, names ...string) (map[string]disk.IOCountersStat, error)
+	netIOCounters  func(ctx context.Context, pernic bool) ([]psnet.IOCountersStat, error)
+	connections    func(ctx context.Context, kind string) ([]psnet.ConnectionStat, error)
+}
+
+// defaultSystemSources 返回读取本机数据的gopsutil数据源
+func defaultSystemSources() systemSources {
+	return systemSources{
+		hostInfo:       host.InfoWithContext,
+		cpuTimes:       cpu.TimesWithContext,
+		loadAvg:        load.AvgWithContext,
+		virtualMemory:  mem.VirtualMemoryWithContext,
+		swapMemory:     mem.SwapMemoryWithContext,
+		diskPartitions: disk.PartitionsWithContext,
+		diskUsage:      disk.UsageWithContext,
+		diskIOCounters: disk.IOCountersWithContext,
+		netIOCounters:  psnet.IOCountersWithContext,
+		connections:    psnet.ConnectionsWithContext,
+	}
+}
@@ -1,17 +1,13 @@
 package collector
 
 import (
+	"context"
 	"net"
 	"runtime"
-	"syscall"
 	"time"
 
-	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
-	"github.com/shirou/gopsutil/v3/host"
-	"github.com/shirou/gopsutil/v3/load"
 	"github.com/shirou/gopsutil/v3/mem"
-	psnet "github.com/shirou/gopsutil/v3/net"
 )
 
 // SystemStats 包含系统各项指标数据
@@ -25,6 +21,8 @@ type SystemStats struct {
 
 	// 硬件参数
 	Hardware HardwareInfo `json:"hardware"`
+	// 完整硬件清单，仅在首次上报和硬件发生变化时附带
+	Inventory *HardwareInventory `json:"inventory,omitempty"`
 
 	// 系统负载
 	LoadAvg LoadAvgStats `json:"load_avg"`
@@ -34,12 +32,75 @@ type SystemStats struct {
 	Memory  MemoryStats          `json:"memory"`
 	Disk    map[string]DiskStats `json:"disk"`
 	Network NetworkStats         `json:"network"`
+
+	// 各CPU核心的时间占比，键为核心名称（如cpu0）
+	PerCPU map[string]CPUTimeStats `json:"per_cpu,omitempty"`
+
+	// 块设备I/O统计
+	DiskIO map[string]DiskIOStats `json:"disk_io"`
+
+	// 资源压力信息(PSI)，键为cpu/memory/io，内核不支持时为空
+	Pressure map[string]PressureStats `json:"pressure,omitempty"`
+	// 内存分页及OOM计数器
+	VMStat *VMStatStats `json:"vmstat,omitempty"`
+
+	// cgroup资源使用信息（未启用或非cgroup v2环境时为空）
+	Cgroups []CgroupStats `json:"cgroups,omitempty"`
+
+	// 进程信息（未启用进程采集时为空）
+	Processes *ProcessesStats `json:"processes,omitempty"`
+
+	// systemd单元状态（未启用且主控端未下发单元时为空）
+	Systemd []SystemdUnitStats `json:"systemd,omitempty"`
+
+	// 温度及风扇传感器（未启用或节点没有传感器时为空）
+	Sensors []SensorStats `json:"sensors,omitempty"`
+
+	// 时间同步状态（未启用或节点没有chrony和timedatectl时为空）
+	TimeSync *TimeSyncStats `json:"time_sync,omitempty"`
+
+	// 自定义插件及Prometheus抓取的结果，键为插件或抓取目标名称（未配置时为空）
+	Custom map[string]PluginResult `json:"custom,omitempty"`
+
+	// 主动探测结果，键为探测名称（未配置探测时为空）
+	Probes map[string]ProbeResult `json:"probes,omitempty"`
+
+	// 日志文件的模式计数，键为监控名称（未配置日志监控时为空）
+	Logs map[string]LogWatchStats `json:"logs,omitempty"`
+
+	// 证书文件及TLS端点的证书（未配置证书扫描时为空）
+	Certificates []CertificateInfo `json:"certificates,omitempty"`
+
+	// 文件完整性监控的汇总，变化通过事件上报（未配置监控路径时为空）
+	Integrity *IntegrityStats `json:"integrity,omitempty"`
+
+	// 监听中的套接字（未启用监听端口采集时为空）
+	Listeners []ListenerInfo `json:"listeners,omitempty"`
+
+	// 本次采集检测到的事件
+	Events []Event `json:"events,omitempty"`
+
+	// 本次采集失败或超时的子收集器及错误信息，键为子收集器名称
+	CollectorErrors map[string]string `json:"collector_errors,omitempty"`
+
+	// 代理当前使用的上报目标（由上报模块填写，尚未上报成功时为空）
+	ReportEndpoint *ReportEndpoint `json:"report_endpoint,omitempty"`
+}
+
+// ReportEndpoint 代理当前使用的上报目标
+type ReportEndpoint struct {
+	URL  string `json:"url"`
+	Kind string `json:"kind"` // aggregator或server
+	// 目标在配置中的优先级，0为最高；大于0表示已切换到备用目标
+	Priority int `json:"priority"`
 }
 
 // HardwareInfo 包含硬件信息
 type HardwareInfo struct {
 	CPUModel    string   `json:"cpu_model"`
-	CPUCores    int      `json:"cpu_cores"`
+	CPUCores    int      `json:"cpu_cores"` // 物理核心数
+	CPUThreads  int      `json:"cpu_threads"`
+	CPUSockets  int      `json:"cpu_sockets"`
 	MemoryTotal uint64   `json:"memory_total"`
 	DiskTotal   uint64   `json:"disk_total"`
 	GPUModel    []string `json:"gpu_model,omitempty"`
@@ -71,6 +132,14 @@ type DiskStats struct {
 	Free        uint64  `json:"free"`
 	UsedPercent float64 `json:"used_percent"`
 	FSType      string  `json:"fstype"`
+	Device      string  `json:"device,omitempty"`
+	Mounted     bool    `json:"mounted"`
+
+	// inode使用情况
+	InodesTotal       uint64  `json:"inodes_total"`
+	InodesUsed        uint64  `json:"inodes_used"`
+	InodesFree        uint64  `json:"inodes_free"`
+	InodesUsedPercent float64 `json:"inodes_used_percent"`
 }
 
 // NetworkStats 包含网络使用信息
@@ -84,6 +153,8 @@ type NetworkStats struct {
 	TotalReceived uint64                    `json:"total_received"`
 	TCPConnCount  int                       `json:"tcp_connections"`
 	UDPConnCount  int                       `json:"udp_connections"`
+	TCPStates     map[string]int            `json:"tcp_states,omitempty"`
+	TCP           *TCPStats                 `json:"tcp,omitempty"`
 }
 
 // InterfaceStats 包含网络接口信息
@@ -92,6 +163,20 @@ type InterfaceStats struct {
 	BytesRecv     uint64 `json:"bytes_recv"`
 	UploadSpeed   uint64 `json:"upload_speed"`
 	DownloadSpeed uint64 `json:"download_speed"`
+
+	// 包数及错误统计
+	PacketsSent     uint64  `json:"packets_sent"`
+	PacketsRecv     uint64  `json:"packets_recv"`
+	PacketsSentRate float64 `json:"packets_sent_per_sec"`
+	PacketsRecvRate float64 `json:"packets_recv_per_sec"`
+	Errin           uint64  `json:"errin"`
+	Errout          uint64  `json:"errout"`
+	Dropin          uint64  `json:"dropin"`
+	Dropout         uint64  `json:"dropout"`
+	Fifoin          uint64  `json:"fifoin"`
+	Fifoout         uint64  `json:"fifoout"`
+	ErrorsRate      float64 `json:"errors_per_sec"`
+	DropsRate       float64 `json:"drops_per_sec"`
 }
 
 // Collector 系统指标收集器接口
@@ -102,17 +187,101 @@ type Collector interface {
 // SystemCollector 实现了Collector接口的系统指标收集器
 type SystemCollector struct {
 	// 可配置的采集选项
-	mountPoints      []string
-	interfaces       []string
-	lastNetworkStats map[string]psnet.IOCountersStat
-	lastCollectTime  time.Time
+	mountPoints       []string
+	filesystemOptions FilesystemOptions
+	interfaces        []string
+	diskDevices       []string
+
+	// 以下增量状态各自只由一个子收集器读写，注册表保证同一子收集器不会并发执行
+	lastDiskIOStats map[string]disk.IOCountersStat
+	lastDiskIOTime  time.Time
+	lastCPUSample   *cpuSample
+	lastVMStat      *VMStatStats
+	lastVMStatTime  time.Time
+	lastTCPStats    *TCPStats
+	lastTCPTime     time.Time
+
+	// proc文件系统根目录，容器中运行时可指向宿主机的/proc
+	procRoot string
+	// sys文件系统根目录
+	sysRoot string
+
+	// 已采集过的挂载点，用于上报已消失的挂载点
+	knownMounts map[string]DiskStats
+
+	// 进程收集器
+	processCollector *ProcessCollector
+	// cgroup收集器
+	cgroupCollector *CgroupCollector
+	// 监听端口收集器
+	listenerCollector *ListenerCollector
+	// systemd单元收集器
+	systemdCollector *SystemdCollector
+	// 硬件清单收集器
+	inventoryCollector *InventoryCollector
+	// 硬件传感器收集器
+	sensorCollector *SensorCollector
+	// 时间同步状态收集器
+	timeSyncCollector *TimeSyncCollector
+	// 自定义插件收集器
+	pluginCollector *PluginCollector
+	// Prometheus exporter抓取收集器
+	scrapeCollector *ScrapeCollector
+	// 主动探测收集器
+	probeCollector *ProbeCollector
+	// 日志文件监控收集器
+	logWatchCollector *LogWatchCollector
+	// 证书过期扫描收集器
+	certificateCollector *CertificateCollector
+	// 文件完整性监控收集器
+	integrityCollector *IntegrityCollector
+
+	// 各子收集器的运行参数，未配置的子收集器使用默认参数
+	collectorSettings map[string]CollectorSettings
+
+	// 网卡计数器的上次采集结果
+	netRates *interfaceRates
+	// gopsutil数据源
+	sources systemSources
 }
 
 // NewSystemCollector 创建新的系统指标收集器
 func NewSystemCollector(options ...func(*SystemCollector)) *SystemCollector {
 	sc := &SystemCollector{
-		mountPoints: []string{"/"},
-		interfaces:  []string{}, // 空切片表示收集所有网络接口
+		mountPoints:       []string{}, // 空切片表示自动发现所有分区
+		filesystemOptions: normalizeFilesystemOptions(FilesystemOptions{}),
+		interfaces:        []string{}, // 空切片表示收集所有网络接口
+		diskDevices:       []string{}, // 空切片表示收集所有物理块设备
+		procRoot:          "/proc",
+		sysRoot:           "/sys",
+		netRates:          &interfaceRates{},
+		sources:           defaultSystemSources(),
+		// 进程采集默认关闭
+		processCollector: NewProcessCollector(ProcessOptions{}),
+		// cgroup采集默认关闭
+		cgroupCollector: NewCgroupCollector(CgroupOptions{}),
+		// 监听端口采集默认关闭
+		listenerCollector: NewListenerCollector(false),
+		// systemd单元采集默认关闭
+		systemdCollector: NewSystemdCollector(SystemdOptions{}),
+		// 硬件清单默认每小时刷新一次
+		inventoryCollector: NewInventoryCollector(defaultInventoryInterval),
+		// 传感器采集默认关闭
+		sensorCollector: NewSensorCollector(false),
+		// 时间同步状态采集默认关闭
+		timeSyncCollector: NewTimeSyncCollector(false),
+		// 默认不配置插件
+		pluginCollector: NewPluginCollector(nil),
+		// 默认不抓取任何exporter
+		scrapeCollector: NewScrapeCollector(ScrapeOptions{}),
+		// 默认不配置探测
+		probeCollector: NewProbeCollector(nil),
+		// 默认不监控日志文件
+		logWatchCollector: NewLogWatchCollector(LogWatchOptions{}),
+		// 默认不扫描证书
+		certificateCollector: NewCertificateCollector(CertificateOptions{}),
+		// 默认不监控文件完整性
+		integrityCollector: NewIntegrityCollector(IntegrityOptions{}),
 	}
 
 	// 应用可选配置
@@ -130,6 +299,13 @@ func WithMountPoints(mounts []string) func(*SystemCollector) {
 	}
 }
 
+// WithFilesystemOptions 设置分区自动发现的过滤条件
+func WithFilesystemOptions(opts FilesystemOptions) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.filesystemOptions = normalizeFilesystemOptions(opts)
+	}
+}
+
 // WithInterfaces 设置要监控的网络接口
 func WithInterfaces(ifaces []string) func(*SystemCollector) {
 	return func(sc *SystemCollector) {
@@ -137,25 +313,164 @@ func WithInterfaces(ifaces []string) func(*SystemCollector) {
 	}
 }
 
-// Collect 采集系统指标
+// WithDiskDevices 设置要监控I/O的块设备
+func WithDiskDevices(devices []string) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.diskDevices = devices
+	}
+}
+
+// WithProcRoot 设置proc文件系统根目录
+func WithProcRoot(procRoot string) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		if procRoot != "" {
+			sc.procRoot = procRoot
+		}
+	}
+}
+
+// WithSysRoot 设置sys文件系统根目录
+func WithSysRoot(sysRoot string) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		if sysRoot != "" {
+			sc.sysRoot = sysRoot
+		}
+	}
+}
+
+// WithInventoryInterval 设置硬件清单的刷新间隔
+func WithInventoryInterval(interval time.Duration) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.inventoryCollector = NewInventoryCollector(interval)
+	}
+}
+
+// WithProcesses 设置进程采集选项
+func WithProcesses(opts ProcessOptions) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.processCollector.SetOptions(opts)
+	}
+}
+
+// WithCgroups 设置cgroup采集选项
+func WithCgroups(opts CgroupOptions) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.cgroupCollector = NewCgroupCollector(opts)
+	}
+}
+
+// WithListeners 设置是否采集监听端口
+func WithListeners(enabled bool) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.listenerCollector = NewListenerCollector(enabled)
+	}
+}
+
+// WithSystemd 设置systemd单元采集选项
+func WithSystemd(opts SystemdOptions) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.systemdCollector = NewSystemdCollector(opts)
+	}
+}
+
+// WithTimeSync 设置是否采集时间同步状态
+func WithTimeSync(enabled bool) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.timeSyncCollector = NewTimeSyncCollector(enabled)
+	}
+}
+
+// WithSensors 设置是否采集温度及风扇传感器
+func WithSensors(enabled bool) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.sensorCollector = NewSensorCollector(enabled)
+	}
+}
+
+// WithPlugins 设置自定义脚本插件
+func WithPlugins(plugins []PluginConfig) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.pluginCollector = NewPluginCollector(plugins)
+	}
+}
+
+// WithScrape 设置Prometheus exporter抓取目标及textfile目录
+func WithScrape(opts ScrapeOptions) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.scrapeCollector = NewScrapeCollector(opts)
+	}
+}
+
+// WithProbes 设置本地配置的主动探测
+func WithProbes(probes []ProbeConfig) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.probeCollector = NewProbeCollector(probes)
+	}
+}
+
+// WithLogWatch 设置需要跟踪并按模式计数的日志文件
+func WithLogWatch(opts LogWatchOptions) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.logWatchCollector = NewLogWatchCollector(opts)
+	}
+}
+
+// WithCertificates 设置要扫描的证书文件及TLS端点
+func WithCertificates(opts CertificateOptions) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.certificateCollector = NewCertificateCollector(opts)
+	}
+}
+
+// WithIntegrity 设置需要监控完整性的文件和目录
+func WithIntegrity(opts IntegrityOptions) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.integrityCollector = NewIntegrityCollector(opts)
+	}
+}
+
+// WithCollectorSettings 设置子收集器的启用状态、采集间隔和超时，键为子收集器名称
+func WithCollectorSettings(settings map[string]CollectorSettings) func(*SystemCollector) {
+	return func(sc *SystemCollector) {
+		sc.collectorSettings = settings
+	}
+}
+
+// ProcessOptions 返回当前的进程采集选项
+func (sc *SystemCollector) ProcessOptions() ProcessOptions {
+	return sc.processCollector.Options()
+}
+
+// SetProcessOptions 在运行时更新进程采集选项
+func (sc *SystemCollector) SetProcessOptions(opts ProcessOptions) {
+	sc.processCollector.SetOptions(opts)
+}
+
+// SetSystemdUnits 在运行时应用主控端下发的systemd单元（如固定服务依赖的单元）
+func (sc *SystemCollector) SetSystemdUnits(units []string) {
+	sc.systemdCollector.SetRemoteUnits(units)
+}
+
+// SetRemoteProbes 在运行时应用主控端下发的探测配置，与本地配置的探测合并
+func (sc *SystemCollector) SetRemoteProbes(probes []ProbeConfig) {
+	sc.probeCollector.SetRemoteProbes(probes)
+}
+
+// collectorEnabled 检查子收集器是否启用，未单独配置的子收集器默认启用
+func (sc *SystemCollector) collectorEnabled(name string) bool {
+	settings, ok := sc.collectorSettings[name]
+	return !ok || settings.Enabled
+}
+
+// Collect 按顺序采集系统指标，跳过WithCollectorSettings中未启用的子收集器
+// 子收集器的采集间隔和超时只在ParallelCollector中生效
 func (sc *SystemCollector) Collect() (*SystemStats, error) {
+	ctx := context.Background()
 	now := time.Now()
-	stats := &SystemStats{
-		Timestamp:   now,
-		CurrentTime: now.Format(time.RFC3339),
-		CPU:         make(map[string]float64),
-		Disk:        make(map[string]DiskStats),
-		Network: NetworkStats{
-			Interfaces:  make(map[string]InterfaceStats),
-			PublicIPv4:  []string{},
-			PublicIPv6:  []string{},
-			PrivateIPv4: []string{},
-			PrivateIPv6: []string{},
-		},
-	}
+	stats := newSystemStats(now)
 
 	// 收集主机基本信息
-	if hostInfo, err := host.Info(); err == nil {
+	if hostInfo, err := sc.sources.hostInfo(ctx); err == nil {
 		stats.Hostname = hostInfo.Hostname
 		stats.Platform = hostInfo.Platform + " " + hostInfo.PlatformVersion + " " + runtime.GOARCH
 		stats.Uptime = hostInfo.Uptime
@@ -164,52 +479,55 @@ func (sc *SystemCollector) Collect() (*SystemStats, error) {
 	// 收集硬件信息
 	stats.Hardware = sc.collectHardwareInfo()
 
-	// 收集CPU使用率
-	if cpuPercent, err := cpu.Percent(time.Second, false); err == nil && len(cpuPercent) > 0 {
-		stats.CPU["usage"] = cpuPercent[0]
+	if sc.collectorEnabled(CollectorCPU) {
+		// 收集CPU使用率及各类CPU时间占比
+		if cpuStats, err := sc.collectCPUStats(ctx, time.Second); err == nil {
+			cpuStats.applyTo(stats)
+		}
+
+		// 收集系统负载
+		if loadAvg, err := sc.sources.loadAvg(ctx); err == nil {
+			stats.LoadAvg = LoadAvgStats{
+				Load1:  loadAvg.Load1,
+				Load5:  loadAvg.Load5,
+				Load15: loadAvg.Load15,
+			}
+		}
 	}
 
-	// 收集CPU详细信息
-	if cpuInfo, err := cpu.Info(); err == nil && len(cpuInfo) > 0 {
-		stats.Hardware.CPUModel = cpuInfo[0].ModelName
-		stats.Hardware.CPUCores = len(cpuInfo)
+	// 收集硬件清单及CPU拓扑
+	if sc.collectorEnabled(CollectorHardware) {
+		stats.Events = append(stats.Events, sc.collectInventory(stats, now)...)
 	}
 
-	// 收集系统负载
-	if loadAvg, err := load.Avg(); err == nil {
-		stats.LoadAvg = LoadAvgStats{
-			Load1:  loadAvg.Load1,
-			Load5:  loadAvg.Load5,
-			Load15: loadAvg.Load15,
+	if sc.collectorEnabled(CollectorMemory) {
+		// 收集内存信息
+		if memStat, err := sc.sources.virtualMemory(ctx); err == nil {
+			stats.Memory.Total = memStat.Total
+			stats.Memory.Used = memStat.Used
+			stats.Memory.Free = memStat.Free
+			stats.Memory.UsedPercent = memStat.UsedPercent
 		}
-	}
 
-	// 收集内存信息
-	if memStat, err := mem.VirtualMemory(); err == nil {
-		stats.Memory.Total = memStat.Total
-		stats.Memory.Used = memStat.Used
-		stats.Memory.Free = memStat.Free
-		stats.Memory.UsedPercent = memStat.UsedPercent
+		// 收集交换分区信息
+		if swapStat, err := sc.sources.swapMemory(ctx); err == nil {
+			stats.Memory.SwapTotal = swapStat.Total
+			stats.Memory.SwapUsed = swapStat.Used
+			stats.Memory.SwapPercent = swapStat.UsedPercent
+		}
 	}
 
-	// 收集交换分区信息
-	if swapStat, err := mem.SwapMemory(); err == nil {
-		stats.Memory.SwapTotal = swapStat.Total
-		stats.Memory.SwapUsed = swapStat.Used
-		stats.Memory.SwapPercent = swapStat.UsedPercent
+	// 收集资源压力信息和vmstat计数器
+	if sc.collectorEnabled(CollectorPressure) {
+		stats.Pressure = sc.collectPressureStats()
+		if vmstat, err := sc.collectVMStat(now); err == nil {
+			stats.VMStat = vmstat
+		}
 	}
 
 	// 收集磁盘信息
-	for _, mountPoint := range sc.mountPoints {
-		if diskStat, err := disk.Usage(mountPoint); err == nil {
-			stats.Disk[mountPoint] = DiskStats{
-				Total:       diskStat.Total,
-				Used:        diskStat.Used,
-				Free:        diskStat.Free,
-				UsedPercent: diskStat.UsedPercent,
-				FSType:      diskStat.Fstype,
-			}
-		}
+	if sc.collectorEnabled(CollectorDisk) {
+		stats.Disk = sc.collectFilesystemStats(ctx)
 	}
 
 	// 计算总磁盘容量
@@ -219,124 +537,111 @@ func (sc *SystemCollector) Collect() (*SystemStats, error) {
 	}
 	stats.Hardware.DiskTotal = totalDiskSpace
 
-	// 收集网络接口信息和统计
-	if netIOCounters, err := psnet.IOCounters(true); err == nil {
-		var totalSent, totalRecv uint64
-		var prevNetIOCounters map[string]psnet.IOCountersStat
-
-		// 获取上次采集的网络数据，计算速率
-		if sc.lastNetworkStats != nil {
-			prevNetIOCounters = sc.lastNetworkStats
-			timeDiff := now.Sub(sc.lastCollectTime).Seconds()
-
-			if timeDiff > 0 {
-				for _, netIO := range netIOCounters {
-					if len(sc.interfaces) == 0 || containsString(sc.interfaces, netIO.Name) {
-						prev, exists := prevNetIOCounters[netIO.Name]
-
-						// 计算网络速率
-						uploadSpeed := uint64(0)
-						downloadSpeed := uint64(0)
-
-						if exists && timeDiff > 0 {
-							uploadSpeed = uint64(float64(netIO.BytesSent-prev.BytesSent) / timeDiff)
-							downloadSpeed = uint64(float64(netIO.BytesRecv-prev.BytesRecv) / timeDiff)
-						}
-
-						stats.Network.Interfaces[netIO.Name] = InterfaceStats{
-							BytesSent:     netIO.BytesSent,
-							BytesRecv:     netIO.BytesRecv,
-							UploadSpeed:   uploadSpeed,
-							DownloadSpeed: downloadSpeed,
-						}
-
-						totalSent += netIO.BytesSent
-						totalRecv += netIO.BytesRecv
-					}
-				}
-			}
-		} else {
-			// 首次采集，无法计算速率
-			for _, netIO := range netIOCounters {
-				if len(sc.interfaces) == 0 || containsString(sc.interfaces, netIO.Name) {
-					stats.Network.Interfaces[netIO.Name] = InterfaceStats{
-						BytesSent:     netIO.BytesSent,
-						BytesRecv:     netIO.BytesRecv,
-						UploadSpeed:   0,
-						DownloadSpeed: 0,
-					}
-
-					totalSent += netIO.BytesSent
-					totalRecv += netIO.BytesRecv
-				}
-			}
+	// 收集磁盘I/O统计
+	if sc.collectorEnabled(CollectorDiskIO) {
+		if diskIOStats, err := sc.collectDiskIOStats(ctx, now); err == nil {
+			stats.DiskIO = diskIOStats
+		}
+	}
+
+	if sc.collectorEnabled(CollectorNetwork) {
+		// 收集网络接口信息和统计
+		if netIOCounters, err := sc.sources.netIOCounters(ctx, true); err == nil {
+			stats.Network.Interfaces, stats.Network.TotalSent, stats.Network.TotalReceived = sc.netRates.update(netIOCounters, sc.interfaces, now)
+		}
+
+		// 收集IP地址信息
+		if addresses, err := collectIPAddresses(); err == nil {
+			addresses.applyTo(&stats.Network)
 		}
 
-		stats.Network.TotalSent = totalSent
-		stats.Network.TotalReceived = totalRecv
+		// 收集TCP和UDP连接数及TCP连接状态分布
+		if connections, err := sc.sources.connections(ctx, "all"); err == nil {
+			stats.Network.TCPConnCount, stats.Network.UDPConnCount, stats.Network.TCPStates = countConnections(connections)
 
-		// 保存当前采集结果，用于下次计算速率
-		sc.lastNetworkStats = make(map[string]psnet.IOCountersStat)
-		for _, netIO := range netIOCounters {
-			sc.lastNetworkStats[netIO.Name] = netIO
+			// 复用连接列表提取监听端口
+			listeners, events := sc.listenerCollector.Collect(connections)
+			stats.Listeners = listeners
+			stats.Events = append(stats.Events, events...)
+		}
+
+		// 收集TCP重传及监听队列溢出统计
+		if tcpStats, err := sc.collectTCPStats(now); err == nil {
+			stats.Network.TCP = tcpStats
 		}
-		sc.lastCollectTime = now
 	}
 
-	// 收集IP地址信息
-	interfaces, err := net.Interfaces()
-	if err == nil {
-		for _, iface := range interfaces {
-			addrs, err := iface.Addrs()
-			if err != nil {
-				continue
-			}
+	// 收集cgroup信息
+	if sc.collectorEnabled(CollectorCgroups) {
+		if cgroupStats, err := sc.cgroupCollector.Collect(); err == nil {
+			stats.Cgroups = cgroupStats
+		}
+	}
 
-			for _, addr := range addrs {
-				ipnet, ok := addr.(*net.IPNet)
-				if !ok {
-					continue
-				}
-
-				ip := ipnet.IP
-				if ip.IsLoopback() {
-					continue
-				}
-
-				if ip.To4() != nil {
-					// IPv4地址
-					if isPrivateIP(ip) {
-						stats.Network.PrivateIPv4 = append(stats.Network.PrivateIPv4, ip.String())
-					} else {
-						stats.Network.PublicIPv4 = append(stats.Network.PublicIPv4, ip.String())
-					}
-				} else {
-					// IPv6地址
-					if isPrivateIP(ip) {
-						stats.Network.PrivateIPv6 = append(stats.Network.PrivateIPv6, ip.String())
-					} else {
-						stats.Network.PublicIPv6 = append(stats.Network.PublicIPv6, ip.String())
-					}
-				}
-			}
+	// 收集systemd单元信息
+	if sc.collectorEnabled(CollectorSystemd) {
+		if units, events, err := sc.systemdCollector.Collect(now); err == nil {
+			stats.Systemd = units
+			stats.Events = append(stats.Events, events...)
 		}
 	}
 
-	// 收集TCP和UDP连接数
-	if connections, err := psnet.Connections("all"); err == nil {
-		tcpCount := 0
-		udpCount := 0
+	// 收集传感器信息
+	if sc.collectorEnabled(CollectorSensors) {
+		if sensors, err := sc.sensorCollector.Collect(sc.sysRoot); err == nil {
+			stats.Sensors = sensors
+		}
+	}
 
-		for _, conn := range connections {
-			if conn.Type == syscall.SOCK_STREAM {
-				tcpCount++
-			} else if conn.Type == syscall.SOCK_DGRAM {
-				udpCount++
+	// 收集时间同步状态
+	if sc.collectorEnabled(CollectorTimeSync) {
+		if timeSync, err := sc.timeSyncCollector.Collect(); err == nil {
+			stats.TimeSync = timeSync
+		}
+	}
+
+	// 收集自定义插件及Prometheus抓取结果
+	if sc.collectorEnabled(CollectorCustom) {
+		stats.Custom = sc.pluginCollector.Collect(now)
+	}
+	if sc.collectorEnabled(CollectorScrape) {
+		for name, result := range sc.scrapeCollector.Collect(now) {
+			if stats.Custom == nil {
+				stats.Custom = make(map[string]PluginResult)
 			}
+			stats.Custom[name] = result
 		}
+	}
 
-		stats.Network.TCPConnCount = tcpCount
-		stats.Network.UDPConnCount = udpCount
+	// 收集主动探测结果
+	if sc.collectorEnabled(CollectorProbes) {
+		probes, probeEvents := sc.probeCollector.Collect(now)
+		stats.Probes = probes
+		stats.Events = append(stats.Events, probeEvents...)
+	}
+
+	// 收集日志文件的模式计数
+	if sc.collectorEnabled(CollectorLogs) {
+		stats.Logs = sc.logWatchCollector.Collect(ctx, now)
+	}
+
+	// 收集证书信息
+	if sc.collectorEnabled(CollectorCertificates) {
+		stats.Certificates = sc.certificateCollector.Collect(now)
+	}
+
+	// 收集文件完整性变化
+	if sc.collectorEnabled(CollectorIntegrity) {
+		integrity, integrityEvents := sc.integrityCollector.Collect(now)
+		stats.Integrity = integrity
+		stats.Events = append(stats.Events, integrityEvents...)
+	}
+
+	// 收集进程信息
+	if sc.collectorEnabled(CollectorProcesses) {
+		if processStats, err := sc.processCollector.Collect(); err == nil {
+			stats.Processes = processStats
+		}
 	}
 
 	return stats, nil
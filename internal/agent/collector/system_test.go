@@ -1,9 +1,36 @@
 package collector
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	psnet "github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
 )
 
 func TestSystemCollector(t *testing.T) {
@@ -35,6 +62,12 @@ func TestSystemCollector(t *testing.T) {
 			t.Errorf("CPU使用率异常: %v", usage)
 		}
 
+		for _, key := range []string{"user", "system", "idle", "iowait", "steal"} {
+			if _, ok := stats.CPU[key]; !ok {
+				t.Errorf("缺少CPU时间占比: %s", key)
+			}
+		}
+
 		if stats.Hardware.CPUModel == "" {
 			t.Error("CPU型号为空")
 		}
@@ -79,6 +112,18 @@ func TestSystemCollector(t *testing.T) {
 		}
 	})
 
+	// 磁盘I/O信息验证
+	t.Run("磁盘I/O信息验证", func(t *testing.T) {
+		for device, info := range stats.DiskIO {
+			if info.Utilization < 0 || info.Utilization > 100 {
+				t.Errorf("设备 %s 利用率异常: %.2f", device, info.Utilization)
+			}
+
+			fmt.Printf("设备 %s: 读次数=%d, 写次数=%d, 读字节=%d, 写字节=%d\n",
+				device, info.ReadCount, info.WriteCount, info.ReadBytes, info.WriteBytes)
+		}
+	})
+
 	// 网络信息验证
 	t.Run("网络信息验证", func(t *testing.T) {
 		if len(stats.Network.Interfaces) == 0 {
@@ -90,3 +135,1484 @@ func TestSystemCollector(t *testing.T) {
 			stats.Network.PublicIPv4, stats.Network.PrivateIPv4)
 	})
 }
+
+func TestFilterPartitions(t *testing.T) {
+	partitions := []disk.PartitionStat{
+		{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+		{Device: "/dev/sda2", Mountpoint: "/data", Fstype: "xfs"},
+		{Device: "/dev/sda2", Mountpoint: "/data/bind", Fstype: "xfs"},
+		{Device: "tmpfs", Mountpoint: "/tmp", Fstype: "tmpfs"},
+		{Device: "overlay", Mountpoint: "/var/lib/docker/overlay2/abc/merged", Fstype: "overlay"},
+		{Device: "/dev/loop0", Mountpoint: "/snap/core/1", Fstype: "squashfs"},
+		{Device: "/dev/sdb1", Mountpoint: "/run/media/usb", Fstype: "vfat"},
+	}
+
+	mountsOf := func(targets []mountTarget) []string {
+		mounts := make([]string, 0, len(targets))
+		for _, target := range targets {
+			mounts = append(mounts, target.mountPoint)
+		}
+		return mounts
+	}
+
+	t.Run("默认过滤规则", func(t *testing.T) {
+		targets := filterPartitions(partitions, normalizeFilesystemOptions(FilesystemOptions{}))
+		mounts := mountsOf(targets)
+		if len(mounts) != 2 || mounts[0] != "/" || mounts[1] != "/data" {
+			t.Errorf("自动发现结果异常: %v", mounts)
+		}
+	})
+
+	t.Run("按类型和路径过滤", func(t *testing.T) {
+		targets := filterPartitions(partitions, normalizeFilesystemOptions(FilesystemOptions{
+			IncludeFSTypes: []string{"ext4", "tmpfs"},
+			ExcludeFSTypes: []string{"overlay"},
+			ExcludeMounts:  []string{"/t*"},
+		}))
+		mounts := mountsOf(targets)
+		if len(mounts) != 1 || mounts[0] != "/" {
+			t.Errorf("过滤结果异常: %v", mounts)
+		}
+	})
+
+	t.Run("显式配置的挂载点", func(t *testing.T) {
+		sc := NewSystemCollector(
+			WithMountPoints([]string{"/", "/data/", "/mnt/backup"}),
+			WithFilesystemOptions(FilesystemOptions{IncludeInactive: true}),
+		)
+		sc.sources.diskPartitions = func(ctx context.Context, all bool) ([]disk.PartitionStat, error) {
+			return partitions, nil
+		}
+		// 未挂载的路径上返回的是上级文件系统的容量
+		sc.sources.diskUsage = func(ctx context.Context, path string) (*disk.UsageStat, error) {
+			return &disk.UsageStat{Path: path, Total: 100 << 30, Fstype: "ext4"}, nil
+		}
+
+		stats := sc.collectFilesystemStats(context.Background())
+		if len(stats) != 3 {
+			t.Fatalf("挂载点数量异常: %+v", stats)
+		}
+		if data := stats["/data/"]; !data.Mounted || data.Device != "/dev/sda2" || data.FSType != "xfs" || data.Total != 100<<30 {
+			t.Errorf("已挂载的挂载点信息异常: %+v", data)
+		}
+		if backup := stats["/mnt/backup"]; backup.Mounted || backup.Total != 0 {
+			t.Errorf("未挂载的挂载点应标记为未挂载: %+v", backup)
+		}
+	})
+}
+
+func TestCalculateCPUTimes(t *testing.T) {
+	prev := cpu.TimesStat{CPU: "cpu-total", User: 100, System: 50, Idle: 800, Iowait: 20, Steal: 10, Guest: 5}
+	cur := cpu.TimesStat{CPU: "cpu-total", User: 130, System: 60, Idle: 840, Iowait: 30, Steal: 20, Guest: 10}
+
+	// 区间内: user=25(扣除guest), guest=5, system=10, idle=40, iowait=10, steal=10, 总计100
+	stats := calculateCPUTimes(prev, cur)
+	expected := map[string][2]float64{
+		"user":   {stats.User, 25},
+		"guest":  {stats.Guest, 5},
+		"system": {stats.System, 10},
+		"idle":   {stats.Idle, 40},
+		"iowait": {stats.Iowait, 10},
+		"steal":  {stats.Steal, 10},
+		"usage":  {stats.Usage, 50},
+	}
+	for name, values := range expected {
+		if math.Abs(values[0]-values[1]) > 1e-9 {
+			t.Errorf("%s占比异常: 期望=%.2f, 实际=%.2f", name, values[1], values[0])
+		}
+	}
+}
+
+func TestCalculateDiskIORates(t *testing.T) {
+	prev := disk.IOCountersStat{
+		ReadCount: 1000, WriteCount: 2000, ReadBytes: 4 << 20, WriteBytes: 8 << 20,
+		ReadTime: 500, WriteTime: 3000, IoTime: 10000, WeightedIO: 20000,
+	}
+	cases := []struct {
+		name     string
+		cur      disk.IOCountersStat
+		timeDiff float64
+		expected DiskIOStats
+	}{
+		{
+			// 2秒内: 读200次/2MiB/耗时400ms，写100次/1MiB/耗时800ms，繁忙1000ms，加权等待3000ms
+			name: "正常区间",
+			cur: disk.IOCountersStat{
+				ReadCount: 1200, WriteCount: 2100, ReadBytes: 6 << 20, WriteBytes: 9 << 20,
+				ReadTime: 900, WriteTime: 3800, IoTime: 11000, WeightedIO: 23000,
+			},
+			timeDiff: 2,
+			expected: DiskIOStats{
+				ReadIOPS: 100, WriteIOPS: 50, ReadBytesRate: 1 << 20, WriteBytesRate: 512 << 10,
+				ReadAwait: 2, WriteAwait: 8, Await: 4, Utilization: 50, QueueDepth: 1.5,
+			},
+		},
+		{
+			name: "利用率上限",
+			cur: disk.IOCountersStat{
+				ReadCount: 1000, WriteCount: 2000, ReadBytes: 4 << 20, WriteBytes: 8 << 20,
+				ReadTime: 500, WriteTime: 3000, IoTime: 12500, WeightedIO: 20000,
+			},
+			timeDiff: 2,
+			expected: DiskIOStats{Utilization: 100},
+		},
+		{
+			name: "计数器回绕",
+			cur: disk.IOCountersStat{
+				ReadCount: 10, WriteCount: 2100, ReadBytes: 6 << 20, WriteBytes: 9 << 20,
+				ReadTime: 900, WriteTime: 3800, IoTime: 11000, WeightedIO: 23000,
+			},
+			timeDiff: 2,
+		},
+		{
+			name: "时间计数器回绕",
+			cur: disk.IOCountersStat{
+				ReadCount: 1200, WriteCount: 2000, ReadBytes: 5 << 20, WriteBytes: 8 << 20,
+				ReadTime: 100, WriteTime: 3000, IoTime: 5000, WeightedIO: 100,
+			},
+			timeDiff: 1,
+			expected: DiskIOStats{ReadIOPS: 200, ReadBytesRate: 1 << 20},
+		},
+		{
+			name: "零间隔",
+			cur: disk.IOCountersStat{
+				ReadCount: 1200, WriteCount: 2100, ReadBytes: 6 << 20, WriteBytes: 9 << 20,
+				ReadTime: 900, WriteTime: 3800, IoTime: 11000, WeightedIO: 23000,
+			},
+			timeDiff: 0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stats DiskIOStats
+			calculateDiskIORates(&stats, prev, tc.cur, tc.timeDiff)
+			if stats != tc.expected {
+				t.Errorf("I/O速率异常:\n期望=%+v\n实际=%+v", tc.expected, stats)
+			}
+		})
+	}
+}
+
+func TestReadKernelCounters(t *testing.T) {
+	procRoot := t.TempDir()
+	content := "cpu  100 0 50 800 20 0 0 10 5 0\nintr 12345 1 2 3\nctxt 67890\nbtime 1700000000\n"
+	if err := os.WriteFile(filepath.Join(procRoot, "stat"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+
+	ctxt, intr, err := readKernelCounters(procRoot)
+	if err != nil {
+		t.Fatalf("读取内核计数器失败: %v", err)
+	}
+	if ctxt != 67890 || intr != 12345 {
+		t.Errorf("内核计数器异常: ctxt=%d, intr=%d", ctxt, intr)
+	}
+}
+
+func TestCollectPressureStats(t *testing.T) {
+	procRoot := t.TempDir()
+	sc := NewSystemCollector(WithProcRoot(procRoot))
+
+	// 内核不支持PSI时应返回空结果而不是报错
+	if pressure := sc.collectPressureStats(); pressure != nil {
+		t.Errorf("PSI不可用时应返回nil: %v", pressure)
+	}
+
+	if err := os.Mkdir(filepath.Join(procRoot, "pressure"), 0755); err != nil {
+		t.Fatalf("创建测试目录失败: %v", err)
+	}
+	content := "some avg10=1.50 avg60=0.75 avg300=0.10 total=123456\nfull avg10=0.50 avg60=0.25 avg300=0.05 total=65432\n"
+	if err := os.WriteFile(filepath.Join(procRoot, "pressure", "memory"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+
+	pressure := sc.collectPressureStats()
+	memory, ok := pressure["memory"]
+	if !ok || len(pressure) != 1 {
+		t.Fatalf("PSI数据异常: %v", pressure)
+	}
+	if memory.SomeAvg10 != 1.5 || memory.SomeTotal != 123456 || memory.FullAvg60 != 0.25 || memory.FullTotal != 65432 {
+		t.Errorf("内存PSI解析异常: %+v", memory)
+	}
+}
+
+func TestProcessCollector(t *testing.T) {
+	samples := []*processSample{
+		{proc: &process.Process{Pid: 1}, name: "systemd", cpuPercent: 0.1, rss: 10 << 20},
+		{proc: &process.Process{Pid: 2}, name: "nginx", cpuPercent: 30, rss: 50 << 20},
+		{proc: &process.Process{Pid: 3}, name: "nginx", cpuPercent: 5, rss: 40 << 20},
+		{proc: &process.Process{Pid: 4}, name: "mysqld", cpuPercent: 20, rss: 800 << 20},
+		{proc: &process.Process{Pid: 5}, name: "bash", cpuPercent: 0, rss: 2 << 20},
+	}
+	detailed := make(map[int32]bool)
+	detail := func(s *processSample) ProcessStats {
+		detailed[s.proc.Pid] = true
+		return ProcessStats{PID: s.proc.Pid, Name: s.name, CPUPercent: s.cpuPercent, MemoryRSS: s.rss}
+	}
+	pids := func(list []ProcessStats) []int32 {
+		var result []int32
+		for _, ps := range list {
+			result = append(result, ps.PID)
+		}
+		return result
+	}
+
+	opts := normalizeProcessOptions(ProcessOptions{Enabled: true, MaxProcesses: 2, Targets: []string{"nginx", "redis-server"}})
+	result := selectProcesses(samples, opts, detail)
+	if result.Total != 5 {
+		t.Errorf("进程总数异常: %d", result.Total)
+	}
+	if got := pids(result.TopCPU); fmt.Sprint(got) != "[2 4]" {
+		t.Errorf("CPU TopN异常: %v", got)
+	}
+	if got := pids(result.TopMemory); fmt.Sprint(got) != "[4 2]" {
+		t.Errorf("内存TopN异常: %v", got)
+	}
+	if got := pids(result.Targets["nginx"]); fmt.Sprint(got) != "[2 3]" {
+		t.Errorf("重点监控进程匹配异常: %v", got)
+	}
+	if running, ok := result.Targets["redis-server"]; !ok || len(running) != 0 {
+		t.Errorf("未运行的重点监控进程应保留空列表: %v", result.Targets)
+	}
+	if result.All != nil {
+		t.Errorf("未启用collect_all时不应上报全量明细: %v", result.All)
+	}
+	if _, ok := detailed[5]; ok {
+		t.Errorf("未入选的进程不应采集完整信息")
+	}
+
+	// 全量明细不受TopN数量限制
+	opts.CollectAll = true
+	result = selectProcesses(samples, opts, detail)
+	if got := pids(result.All); fmt.Sprint(got) != "[2 4 3 1 5]" {
+		t.Errorf("全量明细异常: %v", got)
+	}
+	if len(result.TopCPU) != 2 {
+		t.Errorf("启用collect_all后CPU TopN数量异常: %d", len(result.TopCPU))
+	}
+
+	// 全量明细的安全上限
+	many := make([]*processSample, maxAllProcesses+10)
+	for i := range many {
+		many[i] = &processSample{proc: &process.Process{Pid: int32(i + 1)}, name: "worker"}
+	}
+	if result := selectProcesses(many, opts, detail); len(result.All) != maxAllProcesses || result.Total != len(many) {
+		t.Errorf("全量明细应限制为 %d 条: %d", maxAllProcesses, len(result.All))
+	}
+
+	// 实际采集：命令行参数仅在include_args开启时上报
+	cmd := exec.Command("sleep", "37")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("无法启动测试进程: %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	findSleep := func(stats *ProcessesStats) *ProcessStats {
+		for _, ps := range stats.Targets["sleep"] {
+			if ps.PID == int32(cmd.Process.Pid) {
+				return &ps
+			}
+		}
+		return nil
+	}
+
+	pc := NewProcessCollector(ProcessOptions{Enabled: true, Targets: []string{"sleep"}})
+	stats, err := pc.Collect()
+	if err != nil {
+		t.Fatalf("进程采集失败: %v", err)
+	}
+	ps := findSleep(stats)
+	if ps == nil {
+		t.Fatalf("未找到重点监控进程: %v", stats.Targets)
+	}
+	if ps.Cmdline != "" || ps.NumThreads == 0 || ps.State == "" {
+		t.Errorf("进程信息异常: %+v", ps)
+	}
+
+	pc.SetOptions(ProcessOptions{Enabled: true, Targets: []string{"sleep"}, IncludeArgs: true, CollectAll: true})
+	if stats, err = pc.Collect(); err != nil {
+		t.Fatalf("进程采集失败: %v", err)
+	}
+	if ps = findSleep(stats); ps == nil || ps.Cmdline != "sleep 37" {
+		t.Errorf("开启include_args后应上报命令行参数: %+v", ps)
+	}
+	if len(stats.All) != min(stats.Total, maxAllProcesses) {
+		t.Errorf("全量明细应包含所有进程: %d/%d", len(stats.All), stats.Total)
+	}
+
+	// 未启用时不采集
+	pc.SetOptions(ProcessOptions{})
+	if stats, err := pc.Collect(); err != nil || stats != nil {
+		t.Errorf("未启用进程采集时应返回nil: %v %v", stats, err)
+	}
+}
+
+func TestCgroupCollector(t *testing.T) {
+	root := t.TempDir()
+	containerID := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	containerDir := filepath.Join(root, "system.slice", "docker-"+containerID+".scope")
+	if err := os.MkdirAll(containerDir, 0755); err != nil {
+		t.Fatalf("创建测试目录失败: %v", err)
+	}
+
+	files := map[string]string{
+		filepath.Join(root, "cgroup.controllers"):         "cpu io memory pids\n",
+		filepath.Join(containerDir, "cpu.stat"):           "usage_usec 1000000\nuser_usec 600000\nsystem_usec 400000\nnr_periods 100\nnr_throttled 10\nthrottled_usec 5000\n",
+		filepath.Join(containerDir, "memory.current"):     "104857600\n",
+		filepath.Join(containerDir, "memory.max"):         "209715200\n",
+		filepath.Join(containerDir, "memory.events"):      "low 0\nhigh 0\nmax 3\noom 2\noom_kill 1\n",
+		filepath.Join(containerDir, "io.stat"):            "8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0\n\n8:16 rbytes=1024 wbytes=0 rios=1 wios=0 dbytes=0 dios=0\n",
+		filepath.Join(containerDir, "pids.current"):       "12\n",
+		filepath.Join(containerDir, "pids.max"):           "max\n",
+		filepath.Join(root, "system.slice", "memory.max"): "max\n",
+	}
+	for file, content := range files {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("写入测试文件失败: %v", err)
+		}
+	}
+
+	cc := NewCgroupCollector(CgroupOptions{
+		Enabled:        true,
+		Root:           root,
+		RuntimeSockets: []string{filepath.Join(root, "missing.sock")},
+	})
+	cgroups, err := cc.Collect()
+	if err != nil {
+		t.Fatalf("cgroup采集失败: %v", err)
+	}
+	if len(cgroups) != 2 {
+		t.Fatalf("cgroup数量异常: %d", len(cgroups))
+	}
+
+	slice, container := cgroups[0], cgroups[1]
+	if slice.Labels["type"] != "slice" || slice.MemoryMax != 0 {
+		t.Errorf("slice信息异常: %+v", slice)
+	}
+	if container.Labels["type"] != "container" || container.Labels["container_id"] != containerID[:12] || container.Labels["runtime"] != "docker" {
+		t.Errorf("容器标签异常: %v", container.Labels)
+	}
+	if container.CPUUsageUsec != 1000000 || container.NrThrottled != 10 {
+		t.Errorf("CPU统计异常: %+v", container)
+	}
+	if container.MemoryUsedPercent != 50 || container.MemoryOOMKills != 1 {
+		t.Errorf("内存统计异常: %+v", container)
+	}
+	if container.IOReadBytes != 2048 || container.IOWriteOps != 2 {
+		t.Errorf("I/O统计异常: %+v", container)
+	}
+	if container.PidsCurrent != 12 || container.PidsMax != 0 {
+		t.Errorf("进程数统计异常: %+v", container)
+	}
+}
+
+func TestCollectTCPStats(t *testing.T) {
+	procRoot := t.TempDir()
+	if err := os.Mkdir(filepath.Join(procRoot, "net"), 0755); err != nil {
+		t.Fatalf("创建测试目录失败: %v", err)
+	}
+
+	writeCounters := func(outSegs, retrans, overflows int) {
+		snmp := fmt.Sprintf("Ip: Forwarding DefaultTTL\nIp: 1 64\n"+
+			"Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors\n"+
+			"Tcp: 1 200 120000 -1 11 10 0 10 2 5249 %d %d 0 4 0\n", outSegs, retrans)
+		netstat := fmt.Sprintf("TcpExt: SyncookiesSent ListenOverflows ListenDrops\nTcpExt: 0 %d %d\n", overflows, overflows)
+		if err := os.WriteFile(filepath.Join(procRoot, "net", "snmp"), []byte(snmp), 0644); err != nil {
+			t.Fatalf("写入测试文件失败: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(procRoot, "net", "netstat"), []byte(netstat), 0644); err != nil {
+			t.Fatalf("写入测试文件失败: %v", err)
+		}
+	}
+
+	sc := NewSystemCollector(WithProcRoot(procRoot))
+	start := time.Now()
+
+	writeCounters(1000, 10, 5)
+	first, err := sc.collectTCPStats(start)
+	if err != nil {
+		t.Fatalf("采集TCP统计失败: %v", err)
+	}
+	if first.CurrEstab != 2 || first.OutSegs != 1000 || first.ListenOverflows != 5 || first.RetransRate != 0 {
+		t.Errorf("首次采集结果异常: %+v", first)
+	}
+
+	writeCounters(2000, 60, 25)
+	second, err := sc.collectTCPStats(start.Add(10 * time.Second))
+	if err != nil {
+		t.Fatalf("采集TCP统计失败: %v", err)
+	}
+	if second.RetransRate != 5 || second.RetransPercent != 5 || second.ListenOverflowsRate != 2 {
+		t.Errorf("TCP速率计算异常: %+v", second)
+	}
+}
+
+func TestListenerCollector(t *testing.T) {
+	tcpListener := func(ip string, port uint32) psnet.ConnectionStat {
+		return psnet.ConnectionStat{
+			Family: syscall.AF_INET,
+			Type:   syscall.SOCK_STREAM,
+			Laddr:  psnet.Addr{IP: ip, Port: port},
+			Status: "LISTEN",
+		}
+	}
+	baseline := []psnet.ConnectionStat{
+		tcpListener("0.0.0.0", 22),
+		tcpListener("127.0.0.1", 8080),
+		// 已建立的连接和已连接的UDP套接字不属于监听端口
+		{Family: syscall.AF_INET, Type: syscall.SOCK_STREAM, Laddr: psnet.Addr{IP: "10.0.0.1", Port: 22}, Raddr: psnet.Addr{IP: "10.0.0.2", Port: 50000}, Status: "ESTABLISHED"},
+		{Family: syscall.AF_INET6, Type: syscall.SOCK_DGRAM, Laddr: psnet.Addr{IP: "::", Port: 53}},
+		{Family: syscall.AF_INET, Type: syscall.SOCK_DGRAM, Laddr: psnet.Addr{IP: "10.0.0.1", Port: 40000}, Raddr: psnet.Addr{IP: "10.0.0.53", Port: 53}},
+	}
+
+	lc := NewListenerCollector(true)
+	listeners, events := lc.Collect(baseline)
+	if len(listeners) != 3 {
+		t.Fatalf("监听端口数量异常: %+v", listeners)
+	}
+	if len(events) != 0 {
+		t.Errorf("首次采集不应产生事件: %+v", events)
+	}
+
+	// 关闭8080并新开4444
+	changed := []psnet.ConnectionStat{baseline[0], baseline[3], tcpListener("0.0.0.0", 4444)}
+	_, events = lc.Collect(changed)
+	if len(events) != 2 {
+		t.Fatalf("事件数量异常: %+v", events)
+	}
+
+	eventTypes := map[string]uint32{}
+	for _, event := range events {
+		eventTypes[event.Type] = event.Details["port"].(uint32)
+	}
+	if eventTypes[EventListenerAdded] != 4444 || eventTypes[EventListenerRemoved] != 8080 {
+		t.Errorf("事件内容异常: %+v", events)
+	}
+}
+
+func TestBuildHardwareInventory(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"class/dmi/id/sys_vendor":                "Acme\n",
+		"class/dmi/id/product_name":              "Server 1000\n",
+		"devices/system/node/node0/cpulist":      "0-3\n",
+		"devices/system/node/node0/meminfo":      "Node 0 MemTotal:       16384 kB\nNode 0 MemFree:        8192 kB\n",
+		"block/sda/size":                         "2048\n",
+		"block/sda/queue/rotational":             "1\n",
+		"block/sda/device/model":                 "DISK-1\n",
+		"block/loop0/size":                       "8\n",
+		"class/net/eth0/address":                 "52:54:00:12:34:56\n",
+		"class/net/eth0/mtu":                     "1500\n",
+		"class/net/eth0/speed":                   "1000\n",
+		"class/net/eth0/operstate":               "up\n",
+		"class/net/eth0/device/vendor":           "0x8086\n",
+		"class/net/eth0/device/device":           "0x100e\n",
+		"class/net/docker0/address":              "02:42:00:00:00:01\n",
+		"class/net/docker0/mtu":                  "1500\n",
+		"devices/pci0000:00/drivers/e1000/.keep": "",
+		"class/net/lo/address":                   "00:00:00:00:00:00\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("创建测试目录失败: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("写入测试文件失败: %v", err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "devices/pci0000:00/drivers/e1000"), filepath.Join(root, "class/net/eth0/device/driver")); err != nil {
+		t.Fatalf("创建驱动链接失败: %v", err)
+	}
+
+	now := time.Now()
+	inventory := buildHardwareInventory(root, now)
+
+	if inventory.System.Vendor != "Acme" || inventory.System.Product != "Server 1000" || inventory.System.Serial != "" {
+		t.Errorf("DMI信息异常: %+v", inventory.System)
+	}
+	if len(inventory.NUMANodes) != 1 || inventory.NUMANodes[0].CPUs != "0-3" || inventory.NUMANodes[0].MemoryTotal != 16384*1024 {
+		t.Errorf("NUMA节点信息异常: %+v", inventory.NUMANodes)
+	}
+	if len(inventory.BlockDevices) != 1 {
+		t.Fatalf("块设备数量异常: %+v", inventory.BlockDevices)
+	}
+	if dev := inventory.BlockDevices[0]; dev.Name != "sda" || dev.Size != 2048*512 || !dev.Rotational || dev.Model != "DISK-1" {
+		t.Errorf("块设备信息异常: %+v", dev)
+	}
+
+	nics := make(map[string]NICInfo)
+	for _, nic := range inventory.NICs {
+		nics[nic.Name] = nic
+	}
+	if _, ok := nics["lo"]; ok || len(nics) != 2 {
+		t.Fatalf("网卡列表异常: %+v", inventory.NICs)
+	}
+	if eth := nics["eth0"]; eth.Virtual || eth.Driver != "e1000" || eth.PCIID != "8086:100e" || eth.SpeedMbps != 1000 || eth.MTU != 1500 {
+		t.Errorf("物理网卡信息异常: %+v", eth)
+	}
+	if bridge := nics["docker0"]; !bridge.Virtual || bridge.SpeedMbps != -1 {
+		t.Errorf("虚拟网卡信息异常: %+v", bridge)
+	}
+
+	// 链路状态变化不影响摘要，硬件变化时摘要改变
+	if inventory.Fingerprint == "" {
+		t.Fatal("硬件清单摘要为空")
+	}
+	if err := os.WriteFile(filepath.Join(root, "class/net/eth0/operstate"), []byte("down\n"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	if again := buildHardwareInventory(root, now); again.Fingerprint != inventory.Fingerprint {
+		t.Error("链路状态变化导致摘要改变")
+	}
+
+	// 容器启停带来的虚拟网卡变化不影响摘要
+	vethDir := filepath.Join(root, "devices/virtual/net/veth1234")
+	if err := os.MkdirAll(vethDir, 0755); err != nil {
+		t.Fatalf("创建测试目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vethDir, "mtu"), []byte("1500\n"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	if err := os.Symlink(vethDir, filepath.Join(root, "class/net/veth1234")); err != nil {
+		t.Fatalf("创建网卡链接失败: %v", err)
+	}
+	again := buildHardwareInventory(root, now)
+	if len(again.NICs) != 3 || again.Fingerprint != inventory.Fingerprint {
+		t.Errorf("虚拟网卡变化导致摘要改变: %+v", again.NICs)
+	}
+	if err := os.Remove(filepath.Join(root, "class/net/veth1234")); err != nil {
+		t.Fatalf("删除网卡链接失败: %v", err)
+	}
+
+	ic := NewInventoryCollector(time.Nanosecond)
+	if _, changed, events := ic.Collect(root, now); changed == nil || len(events) != 0 {
+		t.Errorf("首次采集应上报清单且不产生事件: %v, %v", changed, events)
+	}
+	if _, changed, _ := ic.Collect(root, now.Add(time.Second)); changed != nil {
+		t.Error("硬件未变化时不应重复上报清单")
+	}
+	if err := os.WriteFile(filepath.Join(root, "block/sda/size"), []byte("4096\n"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	_, changed, events := ic.Collect(root, now.Add(2*time.Second))
+	if changed == nil || len(events) != 1 || events[0].Type != EventHardwareChanged {
+		t.Errorf("硬件变化未被检测: %v, %v", changed, events)
+	}
+}
+
+func TestSensorCollector(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"devices/platform/coretemp.0/hwmon/hwmon1/name":        "coretemp\n",
+		"devices/platform/coretemp.0/hwmon/hwmon1/temp1_input": "45000\n",
+		"devices/platform/coretemp.0/hwmon/hwmon1/temp1_label": "Package id 0\n",
+		"devices/platform/coretemp.0/hwmon/hwmon1/temp1_max":   "80000\n",
+		"devices/platform/coretemp.0/hwmon/hwmon1/temp1_crit":  "100000\n",
+		"devices/platform/coretemp.0/hwmon/hwmon1/temp2_input": "101000\n",
+		"devices/platform/coretemp.0/hwmon/hwmon1/temp2_crit":  "100000\n",
+		"devices/platform/nct6775/hwmon/hwmon2/name":           "nct6775\n",
+		"devices/platform/nct6775/hwmon/hwmon2/fan1_input":     "1200\n",
+		"devices/platform/nct6775/hwmon/hwmon2/fan1_min":       "300\n",
+		"devices/platform/nct6775/hwmon/hwmon2/fan1_alarm":     "0\n",
+		"class/thermal/thermal_zone0/type":                     "acpitz\n",
+		"class/thermal/thermal_zone0/temp":                     "27800\n",
+		"class/thermal/thermal_zone0/trip_point_0_type":        "critical\n",
+		"class/thermal/thermal_zone0/trip_point_0_temp":        "119000\n",
+		"class/thermal/cooling_device0/type":                   "Processor\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("创建测试目录失败: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("写入测试文件失败: %v", err)
+		}
+	}
+	// 与真实sysfs一致，class/hwmon下为指向设备目录的链接
+	hwmonDir := filepath.Join(root, "class", "hwmon")
+	if err := os.MkdirAll(hwmonDir, 0755); err != nil {
+		t.Fatalf("创建测试目录失败: %v", err)
+	}
+	for name, device := range map[string]string{"hwmon1": "coretemp.0", "hwmon2": "nct6775"} {
+		chipDir := filepath.Join(root, "devices", "platform", device, "hwmon", name)
+		if err := os.Symlink(chipDir, filepath.Join(hwmonDir, name)); err != nil {
+			t.Fatalf("创建hwmon链接失败: %v", err)
+		}
+		if err := os.Symlink(filepath.Join(root, "devices", "platform", device), filepath.Join(chipDir, "device")); err != nil {
+			t.Fatalf("创建设备链接失败: %v", err)
+		}
+	}
+
+	if sensors, err := NewSensorCollector(false).Collect(root); err != nil || sensors != nil {
+		t.Fatalf("未启用时应返回空结果: %v, %v", sensors, err)
+	}
+
+	sensors, err := NewSensorCollector(true).Collect(root)
+	if err != nil {
+		t.Fatalf("传感器采集失败: %v", err)
+	}
+	if len(sensors) != 4 {
+		t.Fatalf("传感器数量异常: %+v", sensors)
+	}
+
+	pkg, core, fan, zone := sensors[0], sensors[1], sensors[2], sensors[3]
+	if pkg.Chip != "coretemp" || pkg.Sensor != "Package id 0" || pkg.Kind != SensorKindTemperature ||
+		pkg.Value != 45 || pkg.Max != 80 || pkg.Critical != 100 || pkg.Alarm || pkg.Labels["device"] != "coretemp.0" {
+		t.Errorf("温度传感器信息异常: %+v", pkg)
+	}
+	if core.Sensor != "temp2" || !core.Alarm {
+		t.Errorf("超过临界值的传感器应告警: %+v", core)
+	}
+	if fan.Chip != "nct6775" || fan.Kind != SensorKindFan || fan.Value != 1200 || fan.Min != 300 || fan.Alarm {
+		t.Errorf("风扇传感器信息异常: %+v", fan)
+	}
+	if zone.Source != "thermal" || zone.Chip != "acpitz" || zone.Value != 27.8 || zone.Critical != 119 {
+		t.Errorf("温度区域信息异常: %+v", zone)
+	}
+
+	// 没有传感器的节点（如虚拟机）返回空结果
+	if sensors, err := NewSensorCollector(true).Collect(t.TempDir()); err != nil || len(sensors) != 0 {
+		t.Errorf("没有传感器时应返回空结果: %v, %v", sensors, err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	var cpuRuns int
+	var slowRuns atomic.Int32
+	registry.Register("cpu", func(ctx context.Context, stats *SystemStats, now time.Time) error {
+		cpuRuns++
+		stats.CPU["usage"] = 12.5
+		stats.Events = append(stats.Events, Event{Type: "test"})
+		return nil
+	}, CollectorSettings{Enabled: true, Interval: time.Minute})
+
+	release := make(chan struct{})
+	registry.Register("disk", func(ctx context.Context, stats *SystemStats, now time.Time) error {
+		if slowRuns.Add(1) > 1 {
+			<-release // 模拟卡住的NFS挂载点
+		}
+		stats.Disk["/"] = DiskStats{Total: 100}
+		return nil
+	}, CollectorSettings{Enabled: true, Timeout: 50 * time.Millisecond})
+
+	registry.Register("memory", func(ctx context.Context, stats *SystemStats, now time.Time) error {
+		t.Error("未启用的子收集器不应执行")
+		return nil
+	}, CollectorSettings{Enabled: false})
+
+	// 失败的子收集器已写入的部分结果仍会合并
+	registry.Register("sensors", func(ctx context.Context, stats *SystemStats, now time.Time) error {
+		stats.Sensors = []SensorStats{}
+		return fmt.Errorf("读取hwmon失败")
+	}, CollectorSettings{Enabled: true})
+
+	if names := registry.Names(); len(names) != 4 || names[0] != "cpu" {
+		t.Errorf("子收集器列表异常: %v", names)
+	}
+	if err := registry.Configure("missing", CollectorSettings{}); err == nil {
+		t.Error("配置未知的子收集器应返回错误")
+	}
+
+	now := time.Now()
+	stats := newSystemStats(now)
+	err := registry.Run(stats, now)
+	if stats.CPU["usage"] != 12.5 || stats.Disk["/"].Total != 100 || len(stats.Events) != 1 || stats.Sensors == nil {
+		t.Fatalf("首次合并结果异常: %+v", stats)
+	}
+	if err == nil || !strings.Contains(err.Error(), "sensors: 读取hwmon失败") {
+		t.Errorf("应返回子收集器的错误: %v", err)
+	}
+	if len(stats.CollectorErrors) != 1 || stats.CollectorErrors["sensors"] != "读取hwmon失败" {
+		t.Errorf("子收集器错误记录异常: %v", stats.CollectorErrors)
+	}
+
+	// 未到间隔的子收集器沿用上次结果但不重复上报事件；超时的子收集器沿用上次结果
+	start := time.Now()
+	stats = newSystemStats(now.Add(time.Second))
+	err = registry.Run(stats, now.Add(time.Second))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("超时未生效，耗时: %v", elapsed)
+	}
+	if cpuRuns != 1 || stats.CPU["usage"] != 12.5 || len(stats.Events) != 0 {
+		t.Errorf("沿用的结果异常: runs=%d, %+v", cpuRuns, stats)
+	}
+	if stats.Disk["/"].Total != 100 {
+		t.Errorf("超时后应沿用上次的磁盘结果: %+v", stats.Disk)
+	}
+	if _, ok := stats.CollectorErrors["disk"]; !ok || err == nil {
+		t.Errorf("超时应记录为子收集器错误: %v, %v", stats.CollectorErrors, err)
+	}
+
+	// 卡住的采集结束前不会再次启动
+	stats = newSystemStats(now.Add(2 * time.Second))
+	registry.Run(stats, now.Add(2*time.Second))
+	if runs := slowRuns.Load(); runs != 2 {
+		t.Errorf("卡住的子收集器被重复启动: %d", runs)
+	}
+	close(release)
+}
+
+func TestSystemCollectorSettings(t *testing.T) {
+	settings := make(map[string]CollectorSettings)
+	for _, name := range []string{
+		CollectorCPU, CollectorPressure, CollectorDisk, CollectorDiskIO, CollectorNetwork, CollectorProcesses,
+		CollectorCgroups, CollectorSystemd, CollectorSensors, CollectorTimeSync, CollectorHardware, CollectorCustom,
+		CollectorScrape, CollectorProbes, CollectorLogs, CollectorCertificates, CollectorIntegrity,
+	} {
+		settings[name] = CollectorSettings{Enabled: false}
+	}
+
+	// 只有内存采集未被禁用
+	sc := NewSystemCollector(WithCollectorSettings(settings))
+	sc.sources.virtualMemory = func(ctx context.Context) (*mem.VirtualMemoryStat, error) {
+		return &mem.VirtualMemoryStat{Total: 8 << 30, Used: 2 << 30, UsedPercent: 25}, nil
+	}
+	sc.sources.diskPartitions = func(ctx context.Context, all bool) ([]disk.PartitionStat, error) {
+		t.Error("未启用的磁盘采集不应执行")
+		return nil, nil
+	}
+	sc.sources.netIOCounters = func(ctx context.Context, pernic bool) ([]psnet.IOCountersStat, error) {
+		t.Error("未启用的网络采集不应执行")
+		return nil, nil
+	}
+
+	stats, err := sc.Collect()
+	if err != nil {
+		t.Fatalf("采集失败: %v", err)
+	}
+	if stats.Memory.Total != 8<<30 {
+		t.Errorf("内存信息异常: %+v", stats.Memory)
+	}
+	if len(stats.CPU) != 0 || len(stats.Disk) != 0 || stats.Processes != nil || len(stats.Events) != 0 {
+		t.Errorf("未启用的子收集器不应上报结果: cpu=%v disk=%v", stats.CPU, stats.Disk)
+	}
+}
+
+func TestParallelCollectorConcurrent(t *testing.T) {
+	procRoot := t.TempDir()
+	if err := os.Mkdir(filepath.Join(procRoot, "net"), 0755); err != nil {
+		t.Fatalf("创建测试目录失败: %v", err)
+	}
+	files := map[string]string{
+		"net/snmp": "Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors\n" +
+			"Tcp: 1 200 120000 -1 11 10 0 10 2 5249 1000 10 0 4 0\n",
+		"net/netstat": "TcpExt: ListenOverflows ListenDrops\nTcpExt: 0 0\n",
+		"vmstat":      "pgfault 100\npgmajfault 1\npswpin 0\npswpout 0\noom_kill 0\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(procRoot, name), []byte(content), 0644); err != nil {
+			t.Fatalf("写入测试文件失败: %v", err)
+		}
+	}
+
+	pc := NewParallelCollector(WithProcRoot(procRoot), WithSysRoot(t.TempDir()))
+
+	// 模拟的数据源，计数器每次读取都会增长
+	var ticks atomic.Uint64
+	pc.sources = systemSources{
+		hostInfo: func(ctx context.Context) (*host.InfoStat, error) {
+			return &host.InfoStat{Hostname: "fake-host", Platform: "linux"}, nil
+		},
+		cpuTimes: func(ctx context.Context, percpu bool) ([]cpu.TimesStat, error) {
+			n := float64(ticks.Add(1))
+			return []cpu.TimesStat{{CPU: "cpu-total", User: 30 * n, System: 10 * n, Idle: 60 * n}}, nil
+		},
+		loadAvg: func(ctx context.Context) (*load.AvgStat, error) {
+			return &load.AvgStat{Load1: 1.5, Load5: 1, Load15: 0.5}, nil
+		},
+		virtualMemory: func(ctx context.Context) (*mem.VirtualMemoryStat, error) {
+			return &mem.VirtualMemoryStat{Total: 8 << 30, Used: 2 << 30, UsedPercent: 25}, nil
+		},
+		swapMemory: func(ctx context.Context) (*mem.SwapMemoryStat, error) {
+			return &mem.SwapMemoryStat{}, nil
+		},
+		diskPartitions: func(ctx context.Context, all bool) ([]disk.PartitionStat, error) {
+			return []disk.PartitionStat{{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"}}, nil
+		},
+		diskUsage: func(ctx context.Context, path string) (*disk.UsageStat, error) {
+			return &disk.UsageStat{Path: path, Total: 100 << 30, Used: 40 << 30, UsedPercent: 40}, nil
+		},
+		diskIOCounters: func(ctx context.Context, names ...string) (map[string]disk.IOCountersStat, error) {
+			n := ticks.Add(1)
+			return map[string]disk.IOCountersStat{"sda": {ReadCount: n, ReadBytes: n * 4096}}, nil
+		},
+		netIOCounters: func(ctx context.Context, pernic bool) ([]psnet.IOCountersStat, error) {
+			n := ticks.Add(1)
+			return []psnet.IOCountersStat{{Name: "eth0", BytesSent: n * 1000, BytesRecv: n * 2000}}, nil
+		},
+		connections: func(ctx context.Context, kind string) ([]psnet.ConnectionStat, error) {
+			return []psnet.ConnectionStat{
+				{Type: syscall.SOCK_STREAM, Status: "ESTABLISHED"},
+				{Type: syscall.SOCK_DGRAM},
+			}, nil
+		},
+	}
+
+	// 硬件清单读取真实的系统信息，不在此测试
+	if err := pc.Registry().Configure(CollectorHardware, CollectorSettings{Enabled: false}); err != nil {
+		t.Fatalf("配置子收集器失败: %v", err)
+	}
+	pc.Registry().Register("failing", func(ctx context.Context, stats *SystemStats, now time.Time) error {
+		return fmt.Errorf("模拟的采集错误")
+	}, CollectorSettings{Enabled: true})
+	pc.Registry().Register("stuck", func(ctx context.Context, stats *SystemStats, now time.Time) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, CollectorSettings{Enabled: true, Timeout: 20 * time.Millisecond})
+
+	// 多次并发采集，各子收集器的结果在全部结束后才合并，-race下不应出现数据竞争
+	var wg sync.WaitGroup
+	results := make([]*SystemStats, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			stats, err := pc.Collect()
+			if err != nil {
+				t.Errorf("采集失败: %v", err)
+				return
+			}
+			results[i] = stats
+		}(i)
+	}
+	wg.Wait()
+
+	// 所有并发采集结束后再执行一次，确保每个子收集器都已运行过
+	time.Sleep(50 * time.Millisecond)
+	stats, err := pc.Collect()
+	if err != nil {
+		t.Fatalf("采集失败: %v", err)
+	}
+
+	if stats.Hostname != "fake-host" || stats.LoadAvg.Load1 != 1.5 || stats.Memory.Total != 8<<30 {
+		t.Errorf("基本指标合并异常: %+v", stats)
+	}
+	if stats.CPU["usage"] != 40 {
+		t.Errorf("CPU使用率计算异常: %v", stats.CPU)
+	}
+	if stats.Disk["/"].Total != 100<<30 || stats.Hardware.DiskTotal != 100<<30 {
+		t.Errorf("磁盘结果合并异常: %+v", stats.Disk)
+	}
+	if _, ok := stats.DiskIO["sda"]; !ok {
+		t.Errorf("磁盘I/O结果合并异常: %+v", stats.DiskIO)
+	}
+	eth0, ok := stats.Network.Interfaces["eth0"]
+	if !ok || stats.Network.TotalSent != eth0.BytesSent || eth0.BytesRecv != 2*eth0.BytesSent {
+		t.Errorf("网络接口结果合并异常: %+v", stats.Network)
+	}
+	if stats.Network.TCPConnCount != 1 || stats.Network.UDPConnCount != 1 || stats.Network.TCP == nil {
+		t.Errorf("连接统计合并异常: %+v", stats.Network)
+	}
+	if stats.CollectorErrors["failing"] != "模拟的采集错误" {
+		t.Errorf("失败的子收集器未记录: %v", stats.CollectorErrors)
+	}
+	if _, ok := stats.CollectorErrors["stuck"]; !ok {
+		t.Errorf("超时的子收集器未记录: %v", stats.CollectorErrors)
+	}
+	for _, name := range []string{CollectorCPU, CollectorMemory, CollectorDisk, CollectorDiskIO, CollectorNetwork} {
+		if msg, ok := stats.CollectorErrors[name]; ok {
+			t.Errorf("子收集器 %s 不应失败: %s", name, msg)
+		}
+	}
+
+	// 网卡速率按两次计数器的差值计算
+	rates := &interfaceRates{}
+	start := time.Now()
+	rates.update([]psnet.IOCountersStat{{Name: "eth0", BytesSent: 1000}, {Name: "lo", BytesSent: 50}}, []string{"eth0"}, start)
+	interfaces, totalSent, _ := rates.update([]psnet.IOCountersStat{{Name: "eth0", BytesSent: 21000}, {Name: "lo", BytesSent: 100}}, []string{"eth0"}, start.Add(10*time.Second))
+	if len(interfaces) != 1 || interfaces["eth0"].UploadSpeed != 2000 || totalSent != 21000 {
+		t.Errorf("网卡速率计算异常: %+v, %d", interfaces, totalSent)
+	}
+}
+
+func TestPluginOutputParsers(t *testing.T) {
+	message, metrics := parseNagiosOutput("QUEUE WARNING - 120 messages | depth=120;100;200;0 'lag time'=1.5s;;\nconsumer offline\nsecond line | consumers=3\n")
+	if message != "QUEUE WARNING - 120 messages" || len(metrics) != 3 {
+		t.Fatalf("Nagios输出解析异常: %q, %+v", message, metrics)
+	}
+	if metrics[1].Name != "lag time" || metrics[1].Value != 1.5 || metrics[1].Unit != "s" || metrics[2].Name != "consumers" {
+		t.Errorf("Nagios性能数据解析异常: %+v", metrics)
+	}
+
+	metrics, err := parseJSONMetrics([]byte(`{"depth": 12, "replication": {"lag": 0.5, "ok": true}, "host": "db1"}`))
+	if err != nil || len(metrics) != 3 || metrics[0].Name != "depth" || metrics[1].Name != "replication_lag" || metrics[2].Value != 1 {
+		t.Errorf("JSON对象解析异常: %+v, %v", metrics, err)
+	}
+	metrics, err = parseJSONMetrics([]byte(`[{"name": "depth", "value": 7, "labels": {"queue": "orders"}}]`))
+	if err != nil || len(metrics) != 1 || metrics[0].Labels["queue"] != "orders" {
+		t.Errorf("JSON数组解析异常: %+v, %v", metrics, err)
+	}
+
+	metrics, err = parsePrometheusMetrics("# HELP q_depth Queue depth\n# TYPE q_depth gauge\nq_depth{queue=\"a\\\"b\",shard=\"1\"} 42 1700000000000\nq_lag NaN\nq_total 3\n")
+	if err != nil || len(metrics) != 2 || metrics[0].Labels["queue"] != `a"b` || metrics[0].Value != 42 || metrics[1].Name != "q_total" {
+		t.Errorf("Prometheus文本解析异常: %+v, %v", metrics, err)
+	}
+	if _, err := parsePrometheusMetrics("broken{label=1} 3\n"); err == nil {
+		t.Error("无效的Prometheus文本应返回错误")
+	}
+}
+
+func TestRunPlugin(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "check.sh")
+	content := "#!/bin/sh\necho \"QUEUE CRITICAL - token=${AGENT_SECRET:-none} | depth=${DEPTH}\"\nexit 2\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("写入测试脚本失败: %v", err)
+	}
+
+	// 插件不继承代理的环境变量
+	t.Setenv("AGENT_SECRET", "leaked")
+	cfg, err := normalizePluginConfig(PluginConfig{
+		Name:    "queue",
+		Command: script,
+		Env:     map[string]string{"DEPTH": "250"},
+		Labels:  map[string]string{"team": "payments"},
+	})
+	if err != nil {
+		t.Fatalf("插件配置无效: %v", err)
+	}
+
+	result := runPlugin(context.Background(), cfg)
+	if result.Status != PluginStatusCritical || result.ExitCode != 2 || result.Labels["team"] != "payments" {
+		t.Fatalf("插件执行结果异常: %+v", result)
+	}
+	if result.Message != "QUEUE CRITICAL - token=none" || len(result.Metrics) != 1 || result.Metrics[0].Value != 250 {
+		t.Errorf("插件输出解析异常: %+v", result)
+	}
+
+	// 超时的插件被终止
+	cfg.Command = "sleep"
+	cfg.Args = []string{"5"}
+	cfg.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	result = runPlugin(context.Background(), cfg)
+	if result.Status != PluginStatusError || result.Error == "" || time.Since(start) > 2*time.Second {
+		t.Errorf("插件超时处理异常: %+v, 耗时: %v", result, time.Since(start))
+	}
+}
+
+func TestScrapeCollector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "# TYPE http_requests_total counter\n"+
+			"http_requests_total{code=\"200\",method=\"get\"} 1027\n"+
+			"http_requests_total{code=\"500\",method=\"get\"} 3\n"+
+			"go_goroutines 12\n"+
+			"queue_depth{queue=\"orders\"} 42\n")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "backup.prom"), []byte("backup_last_success_timestamp 1.7e+09\n"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ignored.txt"), []byte("ignored 1\n"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+
+	sc := NewScrapeCollector(ScrapeOptions{
+		Targets: []ScrapeTarget{
+			{Name: "app", URL: server.URL + "/metrics", MaxSeries: 2, Labels: map[string]string{"service": "app"}},
+			{Name: "missing", URL: server.URL + "/missing"},
+		},
+		TextfileDir:    dir,
+		ExcludeMetrics: []string{"go_*"},
+	})
+
+	app := sc.scrape(context.Background(), sc.targets[0].target)
+	if app.Status != PluginStatusOK || len(app.Metrics) != 2 || app.Error == "" {
+		t.Fatalf("抓取结果异常: %+v", app)
+	}
+	if m := app.Metrics[0]; m.Name != "http_requests_total" || m.Type != "counter" || m.Labels["code"] != "200" || m.Value != 1027 {
+		t.Errorf("指标及标签转换异常: %+v", m)
+	}
+	if app.Labels["service"] != "app" {
+		t.Errorf("目标标签丢失: %+v", app.Labels)
+	}
+
+	if missing := sc.scrape(context.Background(), sc.targets[1].target); missing.Status != PluginStatusError {
+		t.Errorf("抓取失败的目标状态异常: %+v", missing)
+	}
+
+	results := sc.Collect(time.Now())
+	textfile, ok := results["textfile:backup"]
+	if !ok || len(results) != 1 || len(textfile.Metrics) != 1 || textfile.Metrics[0].Value != 1.7e9 {
+		t.Errorf("textfile读取异常: %+v", results)
+	}
+
+	// 合计序列数超出上限时截断
+	limited := map[string]PluginResult{"a": {Metrics: make([]CustomMetric, 3)}, "b": {Metrics: make([]CustomMetric, 3)}}
+	(&ScrapeCollector{options: ScrapeOptions{MaxTotalSeries: 4}}).limitTotalSeries(limited)
+	if len(limited["a"].Metrics) != 3 || len(limited["b"].Metrics) != 1 || limited["b"].Error == "" {
+		t.Errorf("合计序列数限制异常: %+v", limited)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"status":"ok"}`)
+	}))
+	defer server.Close()
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer tlsServer.Close()
+
+	run := func(cfg ProbeConfig) *ProbeResult {
+		t.Helper()
+		cfg, err := normalizeProbeConfig(cfg)
+		if err != nil {
+			t.Fatalf("探测配置无效: %v", err)
+		}
+		return runProbe(context.Background(), cfg)
+	}
+
+	if r := run(ProbeConfig{Type: ProbeTypeHTTP, Target: server.URL + "/health", BodyRegex: `"status":"ok"`}); !r.Success || r.StatusCode != 200 || r.LatencyMs <= 0 {
+		t.Errorf("HTTP探测应成功: %+v", r)
+	}
+	if r := run(ProbeConfig{Type: ProbeTypeHTTP, Target: server.URL + "/health", BodyRegex: "degraded"}); r.Success || r.Error == "" {
+		t.Errorf("响应体不匹配时探测应失败: %+v", r)
+	}
+	if r := run(ProbeConfig{Type: ProbeTypeHTTP, Target: server.URL + "/missing"}); r.Success || r.StatusCode != 404 {
+		t.Errorf("状态码不符合预期时探测应失败: %+v", r)
+	}
+	if r := run(ProbeConfig{Type: ProbeTypeHTTP, Target: server.URL + "/missing", ExpectedStatus: []int{404}}); !r.Success {
+		t.Errorf("状态码符合期望时探测应成功: %+v", r)
+	}
+
+	// 测试证书的有效期很长，阈值设置得更长时探测失败
+	if r := run(ProbeConfig{Type: ProbeTypeHTTP, Target: tlsServer.URL, TLSSkipVerify: true}); !r.Success || r.CertExpiry == nil || r.CertDaysLeft <= 0 {
+		t.Errorf("HTTPS探测应返回证书有效期: %+v", r)
+	}
+	if r := run(ProbeConfig{Type: ProbeTypeHTTP, Target: tlsServer.URL, TLSSkipVerify: true, TLSExpiryThreshold: 200 * 365 * 24 * time.Hour}); r.Success {
+		t.Errorf("证书剩余有效期低于阈值时探测应失败: %+v", r)
+	}
+	if r := run(ProbeConfig{Type: ProbeTypeHTTP, Target: tlsServer.URL}); r.Success {
+		t.Errorf("证书不受信任时探测应失败: %+v", r)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	address := listener.Addr().String()
+	if r := run(ProbeConfig{Type: ProbeTypeTCP, Target: address}); !r.Success {
+		t.Errorf("TCP探测应成功: %+v", r)
+	}
+	listener.Close()
+	if r := run(ProbeConfig{Type: ProbeTypeTCP, Target: address, Timeout: time.Second}); r.Success || r.Error == "" {
+		t.Errorf("端口关闭时TCP探测应失败: %+v", r)
+	}
+
+	if r := run(ProbeConfig{Type: ProbeTypeDNS, Target: "localhost", ExpectedAnswers: []string{"127.0.0.1"}}); !r.Success {
+		t.Errorf("DNS探测应成功: %+v", r)
+	}
+
+	// ping套接字受net.ipv4.ping_group_range限制，无权限时跳过ICMP探测
+	if conn, _, err := listenICMP(true); err == nil {
+		conn.Close()
+		if r := run(ProbeConfig{Type: ProbeTypeICMP, Target: "127.0.0.1", PingCount: 2}); !r.Success || r.PacketsRecv != 2 || r.PacketLoss != 0 {
+			t.Errorf("ICMP探测应成功: %+v", r)
+		}
+	} else {
+		t.Logf("跳过ICMP探测: %v", err)
+	}
+
+	if _, err := normalizeProbeConfig(ProbeConfig{Type: "ftp", Target: "example.com"}); err == nil {
+		t.Error("不支持的探测类型应返回错误")
+	}
+
+	// 状态变化时产生事件
+	cfg := ProbeConfig{Name: "web", Type: ProbeTypeHTTP, Target: server.URL}
+	if _, ok := probeEvent(cfg, nil, &ProbeResult{Success: true}); ok {
+		t.Error("首次探测成功不应产生事件")
+	}
+	if event, ok := probeEvent(cfg, &ProbeResult{Success: true}, &ProbeResult{Error: "超时"}); !ok || event.Type != EventProbeFailed {
+		t.Errorf("探测失败应产生事件: %+v", event)
+	}
+	if event, ok := probeEvent(cfg, &ProbeResult{}, &ProbeResult{Success: true}); !ok || event.Type != EventProbeRecovered {
+		t.Errorf("探测恢复应产生事件: %+v", event)
+	}
+
+	// 下发的探测覆盖同名的本地探测
+	pc := NewProbeCollector([]ProbeConfig{{Name: "web", Type: ProbeTypeHTTP, Target: server.URL}, {Name: "db", Type: ProbeTypeTCP, Target: address}})
+	pc.SetRemoteProbes([]ProbeConfig{{Name: "web", Type: ProbeTypeHTTP, Target: server.URL + "/health"}})
+	if len(pc.probes) != 2 || pc.probes[1].config.Target != server.URL+"/health" {
+		t.Errorf("下发的探测合并异常: %+v", pc.probes)
+	}
+}
+
+func TestCertificateScan(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	notAfter := time.Now().Add(10 * 24 * time.Hour)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "app.example.com"},
+		DNSNames:     []string{"app.example.com", "www.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("生成证书失败: %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("ignored")})
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"nginx/certs/app.pem":   append(keyPEM, certPEM...),
+		"nginx/certs/copy.pem":  certPEM,
+		"nginx/certs/notes.txt": []byte("not a certificate"),
+		"app.pem":               []byte("garbage"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("写入测试文件失败: %v", err)
+		}
+	}
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+
+	certs := scanCertificates(context.Background(), CertificateOptions{
+		Paths:     []string{filepath.Join(dir, "**", "*.pem")},
+		Endpoints: []string{tlsServer.Listener.Addr().String(), "127.0.0.1:1"},
+		Timeout:   time.Second,
+	})
+
+	var fileCerts, endpointCerts, failed int
+	for _, cert := range certs {
+		switch {
+		case cert.Error != "":
+			failed++
+		case cert.Source == CertificateSourceFile:
+			fileCerts++
+			if cert.Path != filepath.Join(dir, "nginx/certs/app.pem") || cert.Subject != "CN=app.example.com" || len(cert.SANs) != 2 {
+				t.Errorf("证书文件信息异常: %+v", cert)
+			}
+			if cert.DaysLeft < 9.9 || cert.DaysLeft > 10 || !cert.NotAfter.Equal(notAfter.Truncate(time.Second)) {
+				t.Errorf("证书剩余天数异常: %+v", cert)
+			}
+		case cert.Source == CertificateSourceEndpoint:
+			endpointCerts++
+		}
+	}
+	// 重复的证书只保留一个，garbage不是证书，未监听的端点返回错误
+	if fileCerts != 1 || endpointCerts == 0 || failed != 1 {
+		t.Errorf("扫描结果异常: 文件=%d, 端点=%d, 失败=%d, %+v", fileCerts, endpointCerts, failed, certs)
+	}
+
+	if matches, _ := globCertificateFiles(filepath.Join(dir, "**")); len(matches) != 4 {
+		t.Errorf("**应匹配所有文件: %v", matches)
+	}
+}
+
+func TestSystemdCollector(t *testing.T) {
+	state := "active"
+	sc := NewSystemdCollector(SystemdOptions{Enabled: true, Units: []string{"nginx"}, IncludeFailed: true})
+	sc.runCommand = func(ctx context.Context, args ...string) ([]byte, error) {
+		if args[0] == "list-units" {
+			return []byte("backup.service loaded failed failed Nightly backup\n"), nil
+		}
+		if len(args) != 4 || args[2] != "nginx.service" || args[3] != "backup.service" {
+			return nil, fmt.Errorf("unexpected args: %v", args)
+		}
+		return []byte("Id=nginx.service\nDescription=nginx web server\nLoadState=loaded\nActiveState=" + state +
+			"\nSubState=running\nResult=success\nMainPID=812\nNRestarts=2\nMemoryCurrent=52428800\nCPUUsageNSec=1000000000\nTasksCurrent=[not set]\n\n" +
+			"Id=backup.service\nLoadState=loaded\nActiveState=failed\nSubState=failed\nResult=exit-code\nMainPID=0\nNRestarts=0\n" +
+			"MemoryCurrent=18446744073709551615\nCPUUsageNSec=[not set]\n"), nil
+	}
+
+	now := time.Now()
+	units, events, err := sc.Collect(now)
+	if err != nil {
+		t.Fatalf("采集systemd单元失败: %v", err)
+	}
+	if len(units) != 2 || units[0].Name != "backup.service" || units[1].Name != "nginx.service" {
+		t.Fatalf("单元列表异常: %+v", units)
+	}
+	backup, nginx := units[0], units[1]
+	if backup.Declared || backup.MemoryCurrent != 0 || backup.Result != "exit-code" {
+		t.Errorf("failed单元解析异常: %+v", backup)
+	}
+	if !nginx.Declared || nginx.NRestarts != 2 || nginx.MainPID != 812 || nginx.MemoryCurrent != 52428800 || nginx.TasksCurrent != 0 {
+		t.Errorf("声明的单元解析异常: %+v", nginx)
+	}
+	if len(events) != 1 || events[0].Type != EventSystemdUnitFailed || events[0].Details["unit"] != "backup.service" {
+		t.Errorf("首次采集到failed单元应产生事件: %+v", events)
+	}
+
+	// nginx进入failed状态
+	state = "failed"
+	units, events, _ = sc.Collect(now.Add(10 * time.Second))
+	if len(events) != 1 || events[0].Details["unit"] != "nginx.service" {
+		t.Errorf("单元进入failed状态应产生事件: %+v", events)
+	}
+	if units[1].CPUPercent != 0 {
+		t.Errorf("CPU时间未变化时使用率应为0: %+v", units[1])
+	}
+
+	// 没有systemctl的节点不再重试
+	missing := NewSystemdCollector(SystemdOptions{Enabled: true, Units: []string{"nginx"}})
+	missing.runCommand = func(ctx context.Context, args ...string) ([]byte, error) {
+		return nil, &exec.Error{Name: "systemctl", Err: exec.ErrNotFound}
+	}
+	if units, _, err := missing.Collect(now); err != nil || units != nil || !missing.unavailable {
+		t.Errorf("缺少systemctl时应静默跳过: %v %v", units, err)
+	}
+}
+
+// TestLogWatchCollector 测试日志文件的跟踪、轮转、截断及读取位置的持久化
+func TestLogWatchCollector(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	stateFile := filepath.Join(dir, "state", "offsets.json")
+	appendLog := func(content string) {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatalf("写入日志失败: %v", err)
+		}
+		defer f.Close()
+		f.WriteString(content)
+	}
+	appendLog("ERROR 历史内容不应被统计\n")
+
+	opts := LogWatchOptions{
+		StateFile: stateFile,
+		Files: []LogWatchConfig{{
+			Name:       "app",
+			Path:       path,
+			MaxSamples: 2,
+			Patterns:   []LogPattern{{Name: "error", Regex: "ERROR"}, {Name: "oom", Regex: "(?i)out of memory"}},
+		}},
+	}
+	lc := NewLogWatchCollector(opts)
+
+	ctx := context.Background()
+	now := time.Now()
+	stats := lc.Collect(ctx, now)["app"]
+	if stats.Lines != 0 || stats.Counts["error"] != 0 {
+		t.Errorf("首次采集应从文件末尾开始: %+v", stats)
+	}
+
+	appendLog("ERROR one\nINFO ok\nERROR two\nERROR three\nkernel: Out of memory\nERROR partial")
+	stats = lc.Collect(ctx, now.Add(time.Minute))["app"]
+	if stats.Lines != 5 || stats.Counts["error"] != 3 || stats.Counts["oom"] != 1 {
+		t.Errorf("计数异常: %+v", stats)
+	}
+	if len(stats.Samples) != 2 || stats.Samples[0].Line != "ERROR one" || stats.IntervalSeconds != 60 {
+		t.Errorf("样本异常: %+v", stats)
+	}
+
+	// 不完整的行在换行符写入后才统计
+	appendLog(" done\n")
+	stats = lc.Collect(ctx, now.Add(2*time.Minute))["app"]
+	if stats.Lines != 1 || stats.Counts["error"] != 1 || stats.Samples[0].Line != "ERROR partial done" {
+		t.Errorf("不完整的行处理异常: %+v", stats)
+	}
+
+	// copytruncate
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatalf("截断日志失败: %v", err)
+	}
+	appendLog("ERROR after truncate\n")
+	stats = lc.Collect(ctx, now.Add(3*time.Minute))["app"]
+	if !stats.Truncated || stats.Counts["error"] != 1 {
+		t.Errorf("截断处理异常: %+v", stats)
+	}
+
+	// 重命名后重新创建，旧文件中未读取的内容也应被统计
+	appendLog("ERROR before rotate\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("轮转日志失败: %v", err)
+	}
+	appendLog("ERROR new file\n")
+	stats = lc.Collect(ctx, now.Add(4*time.Minute))["app"]
+	if !stats.Rotated || stats.Counts["error"] != 2 {
+		t.Errorf("轮转处理异常: %+v", stats)
+	}
+
+	// 重启后从保存的位置继续读取
+	lc.Collect(ctx, now.Add(5*time.Minute))
+	appendLog("ERROR while stopped\n")
+	restarted := NewLogWatchCollector(opts)
+	stats = restarted.Collect(ctx, now.Add(6*time.Minute))["app"]
+	if stats.Counts["error"] != 1 || stats.Lines != 1 {
+		t.Errorf("重启后应从保存的位置继续读取: %+v", stats)
+	}
+
+	// 读取中途被取消时不上报，已读取部分的计数在下一次采集时一起上报
+	appendLog(strings.Repeat("ERROR burst\n", 1000))
+	cancelled := &cancelAfterContext{Context: ctx, remaining: 3}
+	if logs := restarted.Collect(cancelled, now.Add(7*time.Minute)); logs != nil {
+		t.Errorf("采集被取消时不应返回结果: %+v", logs)
+	}
+	if w := restarted.watches[0]; w.offset == 0 || restarted.pending["app"].Lines == 0 || restarted.pending["app"].Lines == 1000 {
+		t.Errorf("取消前应已读取部分内容: offset=%d pending=%+v", w.offset, restarted.pending["app"])
+	}
+	stats = restarted.Collect(ctx, now.Add(8*time.Minute))["app"]
+	if stats.Lines != 1000 || stats.Counts["error"] != 1000 || stats.IntervalSeconds != 120 {
+		t.Errorf("取消前读取的计数应保留到下一次采集: %+v", stats)
+	}
+
+	missing := NewLogWatchCollector(LogWatchOptions{Files: []LogWatchConfig{{Path: filepath.Join(dir, "missing.log")}}})
+	if stats := missing.Collect(ctx, now)[filepath.Join(dir, "missing.log")]; stats.Error == "" {
+		t.Errorf("文件不存在时应返回错误: %+v", stats)
+	}
+}
+
+// cancelAfterContext 在Err被调用指定次数后返回已取消，用于模拟读取中途超时
+type cancelAfterContext struct {
+	context.Context
+	remaining int
+}
+
+func (c *cancelAfterContext) Err() error {
+	if c.remaining <= 0 {
+		return context.Canceled
+	}
+	c.remaining--
+	return nil
+}
+
+// TestFileIntegrity 测试文件完整性监控的基线建立、变化检测及基线持久化
+func TestFileIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	passwd := filepath.Join(dir, "passwd")
+	sudoers := filepath.Join(dir, "sudoers.d")
+	stateFile := filepath.Join(dir, "state", "baseline.json")
+	if err := os.WriteFile(passwd, []byte("root:x:0:0::/root:/bin/bash\n"), 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if err := os.MkdirAll(sudoers, 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sudoers, "admin"), []byte("admin ALL=(ALL) ALL\n"), 0440); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	opts := IntegrityOptions{Paths: []string{passwd, sudoers, filepath.Join(dir, "missing")}, Interval: time.Hour, StateFile: stateFile}
+	ic := NewIntegrityCollector(opts)
+	// 同步执行扫描，Collect只取结果
+	scan := func(ic *IntegrityCollector) (*IntegrityStats, []Event) {
+		ic.scan(context.Background())
+		return ic.Collect(ic.lastRun)
+	}
+	ic.lastRun = time.Now()
+
+	stats, events := scan(ic)
+	if stats == nil || stats.Files != 2 || len(events) != 0 {
+		t.Fatalf("首次扫描应只建立基线: %+v %+v", stats, events)
+	}
+	if _, err := os.Stat(stateFile); err != nil {
+		t.Fatalf("基线未保存: %v", err)
+	}
+
+	oldHash := ic.baseline[passwd].Hash
+	if err := os.WriteFile(passwd, []byte("root:x:0:0::/root:/bin/bash\nevil:x:0:0::/:/bin/sh\n"), 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(sudoers, "admin"), 0666); err != nil {
+		t.Fatalf("修改权限失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sudoers, "backdoor"), []byte("evil ALL=(ALL) NOPASSWD: ALL\n"), 0440); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	stats, events = scan(ic)
+	if stats.Added != 1 || stats.Modified != 2 || stats.Removed != 0 || len(events) != 3 {
+		t.Fatalf("变化检测异常: %+v %+v", stats, events)
+	}
+	// 事件按路径排序：passwd、sudoers.d/admin、sudoers.d/backdoor
+	if events[0].Type != EventFileModified || events[0].Details["old_hash"] != oldHash || events[0].Details["new_hash"] == oldHash {
+		t.Errorf("内容修改事件异常: %+v", events[0])
+	}
+	if changes := events[1].Details["changes"].([]string); len(changes) != 1 || changes[0] != "mode" || events[1].Details["new_mode"] != "-rw-rw-rw-" {
+		t.Errorf("权限修改事件异常: %+v", events[1])
+	}
+	if events[2].Type != EventFileAdded || events[2].Source != "integrity" {
+		t.Errorf("新增文件事件异常: %+v", events[2])
+	}
+	if _, events = ic.Collect(ic.lastRun); len(events) != 0 {
+		t.Errorf("事件只应上报一次: %+v", events)
+	}
+
+	// 代理停止期间删除的文件在重启后与保存的基线比较时被检测到
+	if err := os.Remove(filepath.Join(sudoers, "backdoor")); err != nil {
+		t.Fatalf("删除文件失败: %v", err)
+	}
+	restarted := NewIntegrityCollector(opts)
+	restarted.lastRun = time.Now()
+	stats, events = scan(restarted)
+	if stats.Removed != 1 || len(events) != 1 || events[0].Type != EventFileRemoved || events[0].Details["old_mode"] != "-r--r-----" {
+		t.Errorf("重启后删除检测异常: %+v %+v", stats, events)
+	}
+}
+
+// TestTimeSyncCollector 测试chronyc和timedatectl输出的解析及回退
+func TestTimeSyncCollector(t *testing.T) {
+	tc := NewTimeSyncCollector(true)
+	chrony := true
+	tc.runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		switch {
+		case name == "chronyc" && chrony:
+			return []byte("A29FC87B,ntp1.example.com,3,1697450000.123456,0.000250000,-0.000012,0.000030,-12.345,0.001,0.050,0.012000,0.001500,64.2,Normal\n"), nil
+		case name == "chronyc":
+			return []byte("506 Cannot talk to daemon\n"), fmt.Errorf("exit status 1")
+		case len(args) > 0 && args[0] == "show":
+			return []byte("NTP=yes\nNTPSynchronized=yes\n"), nil
+		default:
+			return []byte("       Server: 185.125.190.56 (ntp.ubuntu.com)\nPoll interval: 34min 8s (min: 32s; max 34min 8s)\n         Leap: normal\n      Stratum: 2\n       Offset: -1.131ms\n"), nil
+		}
+	}
+
+	stats, err := tc.Collect()
+	if err != nil {
+		t.Fatalf("采集时间同步状态失败: %v", err)
+	}
+	// chrony的校正量为正表示本地时钟偏慢
+	if stats.Source != TimeSyncSourceChrony || !stats.Synchronized || stats.Stratum != 3 || stats.OffsetMs == nil ||
+		math.Abs(*stats.OffsetMs+0.25) > 1e-9 || stats.Server != "ntp1.example.com" || math.Abs(stats.RootDelayMs-12) > 1e-9 {
+		t.Errorf("chrony状态解析异常: %+v", stats)
+	}
+
+	chrony = false
+	stats, err = tc.Collect()
+	if err != nil {
+		t.Fatalf("采集时间同步状态失败: %v", err)
+	}
+	if stats.Source != TimeSyncSourceTimedatectl || !stats.Synchronized || !stats.NTPEnabled || stats.Stratum != 2 ||
+		stats.OffsetMs == nil || math.Abs(*stats.OffsetMs-1.131) > 1e-9 || stats.Server != "185.125.190.56 (ntp.ubuntu.com)" {
+		t.Errorf("timedatectl状态解析异常: %+v", stats)
+	}
+
+	// 两个命令都不存在时不再重试
+	tc.runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	if stats, err := tc.Collect(); err != nil || stats != nil || !tc.unavailable {
+		t.Errorf("缺少chronyc和timedatectl时应静默跳过: %v %v", stats, err)
+	}
+}
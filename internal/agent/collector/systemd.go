@@ -0,0 +1,330 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// systemd单元状态变化的事件类型
+const (
+	EventSystemdUnitFailed    = "systemd_unit_failed"
+	EventSystemdUnitRecovered = "systemd_unit_recovered"
+)
+
+// 执行systemctl的超时时间
+const systemctlTimeout = 10 * time.Second
+
+// systemctl show读取的属性
+var systemdUnitProperties = []string{
+	"Id", "Description", "LoadState", "ActiveState", "SubState", "Result",
+	"MainPID", "NRestarts", "MemoryCurrent", "CPUUsageNSec", "TasksCurrent",
+}
+
+// SystemdOptions systemd单元采集选项
+type SystemdOptions struct {
+	// 是否启用systemd单元采集
+	Enabled bool
+	// 需要监控的单元，不带后缀时视为.service
+	Units []string
+	// 是否同时上报所有处于failed状态的单元
+	IncludeFailed bool
+}
+
+// SystemdUnitStats 包含单个systemd单元的状态及资源使用信息
+type SystemdUnitStats struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	LoadState   string `json:"load_state"`
+	ActiveState string `json:"active_state"`
+	SubState    string `json:"sub_state"`
+	// 上次运行的结果，如success、exit-code、oom-kill
+	Result  string `json:"result,omitempty"`
+	MainPID int    `json:"main_pid,omitempty"`
+	// systemd自动重启该单元的次数
+	NRestarts int `json:"n_restarts"`
+
+	// 资源统计，需要开启对应的Accounting，未开启时为0
+	MemoryCurrent uint64 `json:"memory_current"`
+	CPUUsageNSec  uint64 `json:"cpu_usage_nsec"`
+	// CPU使用率，以单核为100%
+	CPUPercent   float64 `json:"cpu_percent"`
+	TasksCurrent uint64  `json:"tasks_current"`
+
+	// 是否由配置或主控端声明（否则为自动发现的failed单元）
+	Declared bool `json:"declared"`
+}
+
+// systemdCPUSample 单元上次的CPU使用时间
+type systemdCPUSample struct {
+	usage uint64
+	time  time.Time
+}
+
+// SystemdCollector systemd单元收集器，通过解析systemctl show的输出采集单元状态
+type SystemdCollector struct {
+	mu      sync.Mutex
+	options SystemdOptions
+	// 主控端下发的单元（如固定服务依赖的单元）
+	remoteUnits []string
+
+	lastCPU     map[string]systemdCPUSample
+	lastActive  map[string]string
+	runCommand  func(ctx context.Context, args ...string) ([]byte, error)
+	unavailable bool
+}
+
+// NewSystemdCollector 创建新的systemd单元收集器
+func NewSystemdCollector(opts SystemdOptions) *SystemdCollector {
+	return &SystemdCollector{
+		options:    opts,
+		lastCPU:    make(map[string]systemdCPUSample),
+		lastActive: make(map[string]string),
+		runCommand: runSystemctl,
+	}
+}
+
+// SetRemoteUnits 应用主控端下发的单元列表，与本地配置的单元合并
+// 下发了单元时即使本地未启用也会开始采集
+func (sc *SystemdCollector) SetRemoteUnits(units []string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.remoteUnits = units
+}
+
+// Collect 采集声明的单元及failed单元的状态，未启用或没有systemd时返回nil
+func (sc *SystemdCollector) Collect(now time.Time) ([]SystemdUnitStats, []Event, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if (!sc.options.Enabled && len(sc.remoteUnits) == 0) || sc.unavailable {
+		return nil, nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), systemctlTimeout)
+	defer cancel()
+
+	declared := make(map[string]bool)
+	var units []string
+	addUnit := func(name string, isDeclared bool) {
+		name = normalizeUnitName(name)
+		if name == "" {
+			return
+		}
+		if _, exists := declared[name]; !exists {
+			units = append(units, name)
+		}
+		declared[name] = declared[name] || isDeclared
+	}
+	if sc.options.Enabled {
+		for _, unit := range sc.options.Units {
+			addUnit(unit, true)
+		}
+	}
+	for _, unit := range sc.remoteUnits {
+		addUnit(unit, true)
+	}
+
+	if sc.options.Enabled && sc.options.IncludeFailed {
+		output, err := sc.runCommand(ctx, "list-units", "--state=failed", "--no-legend", "--plain", "--full", "--all")
+		if err != nil {
+			return nil, nil, sc.commandError(err)
+		}
+		for _, name := range parseFailedUnits(output) {
+			addUnit(name, false)
+		}
+	}
+
+	if len(units) == 0 {
+		return []SystemdUnitStats{}, nil, nil
+	}
+
+	args := append([]string{"show", "--property=" + strings.Join(systemdUnitProperties, ",")}, units...)
+	output, err := sc.runCommand(ctx, args...)
+	if err != nil {
+		return nil, nil, sc.commandError(err)
+	}
+
+	stats := parseSystemctlShow(output)
+	var events []Event
+	seen := make(map[string]bool, len(stats))
+	for i := range stats {
+		unit := &stats[i]
+		unit.Declared = declared[unit.Name]
+		seen[unit.Name] = true
+
+		// 根据两次采集之间的CPU时间计算使用率
+		if last, ok := sc.lastCPU[unit.Name]; ok && unit.CPUUsageNSec >= last.usage {
+			if elapsed := now.Sub(last.time); elapsed > 0 {
+				unit.CPUPercent = float64(unit.CPUUsageNSec-last.usage) / float64(elapsed.Nanoseconds()) * 100
+			}
+		}
+		sc.lastCPU[unit.Name] = systemdCPUSample{usage: unit.CPUUsageNSec, time: now}
+
+		if event, ok := systemdUnitEvent(*unit, sc.lastActive[unit.Name], now); ok {
+			events = append(events, event)
+		}
+		sc.lastActive[unit.Name] = unit.ActiveState
+	}
+
+	for name := range sc.lastCPU {
+		if !seen[name] {
+			delete(sc.lastCPU, name)
+			delete(sc.lastActive, name)
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats, events, nil
+}
+
+// commandError 处理systemctl执行失败，节点没有systemctl时不再重试
+func (sc *SystemdCollector) commandError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		sc.unavailable = true
+		return nil
+	}
+	return fmt.Errorf("执行systemctl失败: %w", err)
+}
+
+// systemdUnitEvent 根据单元状态变化生成事件，首次采集即处于failed状态也会产生事件
+func systemdUnitEvent(unit SystemdUnitStats, lastActive string, now time.Time) (Event, bool) {
+	failed := unit.ActiveState == "failed"
+	wasFailed := lastActive == "failed"
+	if failed == wasFailed || (!failed && lastActive == "") {
+		return Event{}, false
+	}
+
+	event := Event{
+		Type:      EventSystemdUnitFailed,
+		Severity:  EventSeverityCritical,
+		Source:    "systemd",
+		Message:   fmt.Sprintf("systemd单元 %s 进入failed状态 (%s)", unit.Name, unit.Result),
+		Timestamp: now,
+		Details: map[string]interface{}{
+			"unit":         unit.Name,
+			"active_state": unit.ActiveState,
+			"sub_state":    unit.SubState,
+			"result":       unit.Result,
+			"n_restarts":   unit.NRestarts,
+		},
+	}
+	if !failed {
+		event.Type = EventSystemdUnitRecovered
+		event.Severity = EventSeverityInfo
+		event.Message = fmt.Sprintf("systemd单元 %s 已恢复为 %s", unit.Name, unit.ActiveState)
+	}
+	return event, true
+}
+
+// normalizeUnitName 为不带后缀的单元名称补充.service后缀
+func normalizeUnitName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return name + ".service"
+}
+
+// parseFailedUnits 解析systemctl list-units --plain --no-legend的输出，第一列为单元名称
+func parseFailedUnits(output []byte) []string {
+	var units []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		// 部分版本在行首输出状态标记
+		name := fields[0]
+		if name == "●" || name == "*" {
+			if len(fields) < 2 {
+				continue
+			}
+			name = fields[1]
+		}
+		units = append(units, name)
+	}
+	return units
+}
+
+// parseSystemctlShow 解析systemctl show的输出，每个单元的属性之间以空行分隔
+func parseSystemctlShow(output []byte) []SystemdUnitStats {
+	var units []SystemdUnitStats
+	var current *SystemdUnitStats
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			current = nil
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		if current == nil {
+			units = append(units, SystemdUnitStats{})
+			current = &units[len(units)-1]
+		}
+
+		switch key {
+		case "Id":
+			current.Name = value
+		case "Description":
+			current.Description = value
+		case "LoadState":
+			current.LoadState = value
+		case "ActiveState":
+			current.ActiveState = value
+		case "SubState":
+			current.SubState = value
+		case "Result":
+			current.Result = value
+		case "MainPID":
+			current.MainPID, _ = strconv.Atoi(value)
+		case "NRestarts":
+			current.NRestarts, _ = strconv.Atoi(value)
+		case "MemoryCurrent":
+			current.MemoryCurrent = parseSystemdUint(value)
+		case "CPUUsageNSec":
+			current.CPUUsageNSec = parseSystemdUint(value)
+		case "TasksCurrent":
+			current.TasksCurrent = parseSystemdUint(value)
+		}
+	}
+
+	// 过滤掉没有Id的块（如空输出）
+	valid := units[:0]
+	for _, unit := range units {
+		if unit.Name != "" {
+			valid = append(valid, unit)
+		}
+	}
+	return valid
+}
+
+// parseSystemdUint 解析systemd的数值属性，未开启统计时为"[not set]"或uint64最大值
+func parseSystemdUint(value string) uint64 {
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || v == ^uint64(0) {
+		return 0
+	}
+	return v
+}
+
+// runSystemctl 执行systemctl命令并返回标准输出
+func runSystemctl(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "systemctl", args...)
+	cmd.Env = []string{"PATH=" + pluginDefaultPath, "LANG=C", "SYSTEMD_PAGER=", "SYSTEMD_COLORS=0"}
+	return cmd.Output()
+}
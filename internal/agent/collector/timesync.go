@@ -0,0 +1,180 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 时间同步状态的来源
+const (
+	TimeSyncSourceChrony      = "chrony"
+	TimeSyncSourceTimedatectl = "timedatectl"
+)
+
+// 执行时间同步查询命令的超时时间
+const timeSyncCommandTimeout = 5 * time.Second
+
+// TimeSyncStats 包含节点的时间同步状态
+type TimeSyncStats struct {
+	// 状态来源：chrony或timedatectl
+	Source string `json:"source"`
+	// 时钟是否已与NTP服务器同步
+	Synchronized bool `json:"synchronized"`
+	// 是否启用了NTP同步服务（仅timedatectl提供）
+	NTPEnabled bool `json:"ntp_enabled,omitempty"`
+	// 本地时钟相对参考时钟的偏差(毫秒)，正数表示本地时钟偏快，无法获取时为空
+	OffsetMs *float64 `json:"offset_ms,omitempty"`
+	// 参考服务器及其层级
+	Server  string `json:"server,omitempty"`
+	Stratum int    `json:"stratum,omitempty"`
+	// 到参考时钟的往返延迟和离散度(毫秒)，仅chrony提供
+	RootDelayMs      float64 `json:"root_delay_ms,omitempty"`
+	RootDispersionMs float64 `json:"root_dispersion_ms,omitempty"`
+	// 闰秒状态，如Normal、Not synchronised
+	LeapStatus string `json:"leap_status,omitempty"`
+}
+
+// TimeSyncCollector 时间同步状态收集器
+// 优先读取chronyc tracking，chronyd未运行时通过timedatectl读取systemd-timesyncd的状态
+type TimeSyncCollector struct {
+	mu          sync.Mutex
+	enabled     bool
+	runCommand  func(ctx context.Context, name string, args ...string) ([]byte, error)
+	unavailable bool
+}
+
+// NewTimeSyncCollector 创建新的时间同步状态收集器
+func NewTimeSyncCollector(enabled bool) *TimeSyncCollector {
+	return &TimeSyncCollector{
+		enabled:    enabled,
+		runCommand: runTimeSyncCommand,
+	}
+}
+
+// Collect 采集时间同步状态，未启用或节点没有chronyc和timedatectl时返回nil
+func (tc *TimeSyncCollector) Collect() (*TimeSyncStats, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if !tc.enabled || tc.unavailable {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeSyncCommandTimeout)
+	defer cancel()
+
+	output, chronyErr := tc.runCommand(ctx, "chronyc", "-c", "tracking")
+	if chronyErr == nil {
+		stats, err := parseChronyTracking(output)
+		if err == nil {
+			return stats, nil
+		}
+		chronyErr = err
+	}
+
+	// chronyd未安装或未运行时读取timedatectl
+	output, err := tc.runCommand(ctx, "timedatectl", "show", "--property=NTP", "--property=NTPSynchronized")
+	if err != nil {
+		if errors.Is(chronyErr, exec.ErrNotFound) && errors.Is(err, exec.ErrNotFound) {
+			tc.unavailable = true
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取时间同步状态失败: chronyc: %v, timedatectl: %w", chronyErr, err)
+	}
+	stats := parseTimedatectlShow(output)
+
+	// 偏差只有systemd-timesyncd在运行时才能获取
+	if output, err := tc.runCommand(ctx, "timedatectl", "timesync-status"); err == nil {
+		parseTimesyncStatus(output, stats)
+	}
+	return stats, nil
+}
+
+// parseChronyTracking 解析chronyc -c tracking的CSV输出
+// 字段依次为：参考ID、参考服务器、层级、参考时间、系统时间校正量、上次偏差、RMS偏差、频率、
+// 剩余频率、频率误差、根延迟、根离散度、更新间隔、闰秒状态，时间单位均为秒
+func parseChronyTracking(output []byte) (*TimeSyncStats, error) {
+	fields := strings.Split(strings.TrimSpace(string(output)), ",")
+	if len(fields) < 14 {
+		return nil, fmt.Errorf("chronyc输出格式异常: %q", strings.TrimSpace(string(output)))
+	}
+
+	parse := func(i int) float64 {
+		v, _ := strconv.ParseFloat(fields[i], 64)
+		return v
+	}
+
+	// 校正量为正表示本地时钟偏慢，与偏差的符号相反
+	offset := -parse(4) * 1000
+	stats := &TimeSyncStats{
+		Source:           TimeSyncSourceChrony,
+		Server:           fields[1],
+		OffsetMs:         &offset,
+		RootDelayMs:      parse(10) * 1000,
+		RootDispersionMs: parse(11) * 1000,
+		LeapStatus:       fields[13],
+	}
+	stats.Stratum, _ = strconv.Atoi(fields[2])
+	stats.Synchronized = stats.LeapStatus != "Not synchronised" && stats.Stratum > 0
+	return stats, nil
+}
+
+// parseTimedatectlShow 解析timedatectl show输出的NTP和NTPSynchronized属性
+func parseTimedatectlShow(output []byte) *TimeSyncStats {
+	stats := &TimeSyncStats{Source: TimeSyncSourceTimedatectl}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "NTP":
+			stats.NTPEnabled = value == "yes"
+		case "NTPSynchronized":
+			stats.Synchronized = value == "yes"
+		}
+	}
+	return stats
+}
+
+// parseTimesyncStatus 解析timedatectl timesync-status的输出，补充服务器、层级、闰秒状态和偏差
+func parseTimesyncStatus(output []byte, stats *TimeSyncStats) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Server":
+			stats.Server = value
+		case "Stratum":
+			stats.Stratum, _ = strconv.Atoi(value)
+		case "Leap":
+			stats.LeapStatus = value
+		case "Offset":
+			// timesyncd的偏差为服务器时间减本地时间，如"-1.131ms"、"+250us"
+			if d, err := time.ParseDuration(value); err == nil {
+				offset := -float64(d) / float64(time.Millisecond)
+				stats.OffsetMs = &offset
+			}
+		}
+	}
+}
+
+// runTimeSyncCommand 执行时间同步查询命令并返回标准输出
+func runTimeSyncCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = []string{"PATH=" + pluginDefaultPath, "LANG=C", "SYSTEMD_PAGER=", "SYSTEMD_COLORS=0"}
+	return cmd.Output()
+}
@@ -0,0 +1,93 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// 异步上报队列的默认长度
+	defaultQueueSize = 100
+	// 单次重试退避的默认最大值
+	defaultMaxRetryInterval = time.Minute
+)
+
+// ErrReporterClosed 上报器关闭后不再接受新的数据
+var ErrReporterClosed = errors.New("上报器已关闭")
+
+// WithAsync 启用异步上报，Report只将数据放入队列，由后台goroutine发送
+// 队列已满时数据直接写入本地缓冲（未配置缓冲时丢弃），采集不会因上报阻塞
+func WithAsync(queueSize int) func(*HTTPReporter) {
+	return func(r *HTTPReporter) {
+		if queueSize <= 0 {
+			queueSize = defaultQueueSize
+		}
+		r.queue = make(chan []byte, queueSize)
+	}
+}
+
+// enqueue 将数据放入异步上报队列
+func (r *HTTPReporter) enqueue(jsonData []byte) error {
+	r.closeMu.RLock()
+	defer r.closeMu.RUnlock()
+	if r.closed {
+		return ErrReporterClosed
+	}
+
+	select {
+	case r.queue <- jsonData:
+		return nil
+	default:
+		r.spoolSamples([][]byte{jsonData})
+		return fmt.Errorf("上报队列已满(%d条)，服务端可能响应缓慢", cap(r.queue))
+	}
+}
+
+// run 依次发送队列中的数据，直到队列关闭
+func (r *HTTPReporter) run() {
+	defer r.wg.Done()
+	for jsonData := range r.queue {
+		if err := r.deliver(jsonData); err != nil {
+			log.Printf("上报失败: %v", err)
+		}
+	}
+}
+
+// Close 停止接受新数据和健康检查，等待队列中的数据发送完成并发送批量上报中剩余的样本
+// ctx到期时中断重试等待，尚未发送的数据按失败处理写入本地缓冲
+func (r *HTTPReporter) Close(ctx context.Context) error {
+	r.closeMu.Lock()
+	if r.closed {
+		r.closeMu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.stop)
+	if r.queue != nil {
+		close(r.queue)
+	}
+	r.closeMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		close(r.done)
+		<-drained
+		err = fmt.Errorf("等待上报队列发送完成超时: %w", ctx.Err())
+	}
+
+	if flushErr := r.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+	return err
+}
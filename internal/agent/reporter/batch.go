@@ -0,0 +1,116 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/syslens/syslens-api/internal/common/utils"
+)
+
+const (
+	// 每批默认的最大样本数
+	defaultBatchMaxSamples = 20
+	// 样本在缓冲中默认的最长等待时间
+	defaultBatchMaxDelay = 10 * time.Second
+)
+
+// BatchOptions 批量上报选项
+type BatchOptions struct {
+	// 每批最多的样本数，达到时立即发送
+	MaxSamples int
+	// 第一个样本进入缓冲后的最长等待时间，到达时发送已缓冲的样本
+	MaxDelay time.Duration
+	// 是否对样本做增量编码，只发送与前一个样本不同的字段
+	Delta bool
+}
+
+// WithBatch 启用批量上报，采集结果先在内存中缓冲，达到样本数或等待时间后一起发送
+func WithBatch(opts BatchOptions) func(*HTTPReporter) {
+	return func(r *HTTPReporter) {
+		if opts.MaxSamples <= 0 {
+			opts.MaxSamples = defaultBatchMaxSamples
+		}
+		if opts.MaxDelay <= 0 {
+			opts.MaxDelay = defaultBatchMaxDelay
+		}
+		r.batch = &opts
+	}
+}
+
+// bufferSample 将样本加入批次，样本数达到上限时立即发送
+func (r *HTTPReporter) bufferSample(sample []byte) error {
+	r.batchMu.Lock()
+	r.pending = append(r.pending, sample)
+	if len(r.pending) < r.batch.MaxSamples {
+		if len(r.pending) == 1 {
+			r.flushTimer = time.AfterFunc(r.batch.MaxDelay, func() {
+				if err := r.Flush(); err != nil {
+					log.Printf("批量上报失败: %v", err)
+				}
+			})
+		}
+		r.batchMu.Unlock()
+		return nil
+	}
+	samples := r.takePending()
+	r.batchMu.Unlock()
+
+	return r.sendBatch(samples)
+}
+
+// Flush 立即发送已缓冲的样本，代理退出前调用以免丢失数据
+func (r *HTTPReporter) Flush() error {
+	r.batchMu.Lock()
+	samples := r.takePending()
+	r.batchMu.Unlock()
+
+	if len(samples) == 0 {
+		return nil
+	}
+	return r.sendBatch(samples)
+}
+
+// takePending 取出已缓冲的样本并停止等待计时，调用方需持有batchMu
+func (r *HTTPReporter) takePending() [][]byte {
+	if r.flushTimer != nil {
+		r.flushTimer.Stop()
+		r.flushTimer = nil
+	}
+	samples := r.pending
+	r.pending = nil
+	return samples
+}
+
+// sendBatch 编码并发送一个批次，失败时样本写入本地缓冲
+// 整批被服务端拒绝时逐个样本重新发送，只丢弃被拒绝的样本
+func (r *HTTPReporter) sendBatch(samples [][]byte) error {
+	payload, err := utils.EncodeMetricsBatch(samples, r.batch.Delta, time.Now())
+	if err != nil {
+		return fmt.Errorf("批量数据编码失败: %w", err)
+	}
+
+	if err := r.send(payload, true, false, r.retryCount); err != nil {
+		if !errors.Is(err, ErrPayloadRejected) {
+			r.spoolSamples(samples)
+			return err
+		}
+		if len(samples) == 1 {
+			log.Printf("服务端拒绝了上报数据，已丢弃: %v", err)
+			return err
+		}
+
+		var errs []error
+		for _, sample := range samples {
+			if err := r.sendBatch([][]byte{sample}); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+	log.Printf("批量上报成功，样本数: %d，数据大小: %d 字节", len(samples), len(payload))
+
+	r.replaySpool()
+	return nil
+}
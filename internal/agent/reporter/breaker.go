@@ -0,0 +1,121 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器断开期间不发起请求，直接返回该错误
+var ErrCircuitOpen = errors.New("上报熔断中，暂停连接服务端")
+
+// circuitBreaker 上报熔断器
+// 连续失败达到阈值后断开，冷却期内不再发起请求；冷却结束后放行一个探测请求，
+// 探测成功则恢复，失败则重新进入冷却期
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int           // 断开前允许的连续失败次数
+	cooldown  time.Duration // 断开后的冷却时间
+	failures  int           // 当前连续失败次数
+	openUntil time.Time     // 冷却结束时间，零值表示闭合
+	probing   bool          // 冷却结束后是否已有探测请求在进行
+	now       func() time.Time
+}
+
+// WithCircuitBreaker 启用熔断器，连续失败threshold次后在cooldown内停止上报
+// 熔断期间的数据直接写入本地缓冲，避免大量节点同时重试压垮刚恢复的服务端
+func WithCircuitBreaker(threshold int, cooldown time.Duration) func(*HTTPReporter) {
+	return func(r *HTTPReporter) {
+		if threshold > 0 && cooldown > 0 {
+			r.breaker = &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+		}
+	}
+}
+
+// allow 判断当前是否可以发起请求
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if remaining := b.openUntil.Sub(b.now()); remaining > 0 {
+		return fmt.Errorf("%w，%v 后恢复", ErrCircuitOpen, remaining.Round(time.Second))
+	}
+	// 冷却结束，只放行一个探测请求
+	if b.probing {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// isOpen 判断熔断器是否处于冷却期
+func (b *circuitBreaker) isOpen() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openUntil.IsZero() && b.now().Before(b.openUntil)
+}
+
+// success 记录一次成功的请求，熔断器恢复闭合
+func (b *circuitBreaker) success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.openUntil.IsZero() {
+		log.Printf("上报已恢复，熔断器闭合")
+	}
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+// failure 记录一次失败的请求，连续失败达到阈值或探测失败时断开
+func (b *circuitBreaker) failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.probing || b.failures >= b.threshold {
+		b.open(b.cooldown)
+	}
+}
+
+// hold 服务端要求等待的时间超过冷却时间时，按服务端的要求断开
+func (b *circuitBreaker) hold(d time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if d < b.cooldown {
+		d = b.cooldown
+	}
+	b.open(d)
+}
+
+// open 断开熔断器，调用方需持有锁
+func (b *circuitBreaker) open(d time.Duration) {
+	until := b.now().Add(d)
+	if until.After(b.openUntil) {
+		b.openUntil = until
+	}
+	b.probing = false
+	log.Printf("上报连续失败 %d 次，熔断 %v", b.failures, d)
+}
@@ -0,0 +1,264 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EndpointAggregator 聚合服务器
+	EndpointAggregator = "aggregator"
+	// EndpointServer 主控服务器
+	EndpointServer = "server"
+
+	// 不可用目标默认的健康检查间隔
+	defaultHealthCheckInterval = 10 * time.Second
+)
+
+// Endpoint 上报目标，列表中的顺序即优先级
+type Endpoint struct {
+	URL  string
+	Kind string // EndpointAggregator或EndpointServer
+	// 请求超时时间，为0时使用上报器的超时配置
+	Timeout time.Duration
+	// 为true时表示WithWebSocket添加的WebSocket通道，不参与HTTP请求和健康检查
+	WebSocket bool
+}
+
+// endpointPool 按优先级排列的上报目标
+// 请求失败的目标被标记为不可用并切换到下一个目标，后台健康检查恢复后重新按优先级选择
+type endpointPool struct {
+	mu        sync.Mutex
+	endpoints []Endpoint
+	down      []bool // 与endpoints对应，目标是否不可用
+	active    int    // 最近一次上报成功的目标，-1表示尚未上报成功
+}
+
+func newEndpointPool(endpoints []Endpoint) *endpointPool {
+	return &endpointPool{
+		endpoints: endpoints,
+		down:      make([]bool, len(endpoints)),
+		active:    -1,
+	}
+}
+
+// WithEndpoints 设置按优先级排列的上报目标，替代NewHTTPReporter的serverURL
+// 当前目标请求失败时按顺序尝试下一个目标，通常为聚合服务器在前、主控服务器在后
+func WithEndpoints(endpoints []Endpoint) func(*HTTPReporter) {
+	return func(r *HTTPReporter) {
+		var valid []Endpoint
+		for _, ep := range endpoints {
+			if ep.URL == "" {
+				continue
+			}
+			ep.URL = strings.TrimRight(ep.URL, "/")
+			if ep.Kind == "" {
+				ep.Kind = EndpointServer
+			}
+			valid = append(valid, ep)
+		}
+		if len(valid) > 0 {
+			r.endpoints = newEndpointPool(valid)
+		}
+	}
+}
+
+// WithHealthCheckInterval 设置不可用目标的健康检查间隔，检查通过后自动切回更高优先级的目标
+func WithHealthCheckInterval(interval time.Duration) func(*HTTPReporter) {
+	return func(r *HTTPReporter) {
+		if interval > 0 {
+			r.healthInterval = interval
+		}
+	}
+}
+
+// WithEndpointChange 设置上报目标切换时的回调，priority为目标在列表中的位置(0为最高优先级)
+func WithEndpointChange(fn func(ep Endpoint, priority int)) func(*HTTPReporter) {
+	return func(r *HTTPReporter) {
+		r.onEndpointChange = fn
+	}
+}
+
+// ActiveEndpoint 返回最近一次上报成功的目标，尚未上报成功时ok为false
+func (r *HTTPReporter) ActiveEndpoint() (ep Endpoint, priority int, ok bool) {
+	p := r.endpoints
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active < 0 {
+		return Endpoint{}, -1, false
+	}
+	return p.endpoints[p.active], p.active, true
+}
+
+// candidates 返回本次HTTP请求依次尝试的目标序号
+// 按优先级返回可用的目标；全部不可用时返回所有目标，仍按优先级逐个尝试
+func (p *endpointPool) candidates() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var healthy, all []int
+	for i, ep := range p.endpoints {
+		if ep.WebSocket {
+			continue
+		}
+		all = append(all, i)
+		if !p.down[i] {
+			healthy = append(healthy, i)
+		}
+	}
+	if len(healthy) == 0 {
+		return all
+	}
+	return healthy
+}
+
+// markDown 标记目标不可用，之后的请求跳过该目标直到健康检查通过
+func (p *endpointPool) markDown(i int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.down[i] && len(p.endpoints) > 1 {
+		log.Printf("上报目标 %s (%s) 不可用，切换到下一个目标: %v", p.endpoints[i].URL, p.endpoints[i].Kind, err)
+	}
+	p.down[i] = true
+}
+
+// markActive 记录上报成功的目标，目标发生变化时调用回调
+func (p *endpointPool) markActive(i int, onChange func(ep Endpoint, priority int)) {
+	p.mu.Lock()
+	p.down[i] = false
+	changed := p.active != i
+	p.active = i
+	ep := p.endpoints[i]
+	p.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if i == 0 {
+		log.Printf("上报目标: %s (%s)", ep.URL, ep.Kind)
+	} else {
+		log.Printf("上报目标已切换为: %s (%s)，优先级: %d", ep.URL, ep.Kind, i)
+	}
+	if onChange != nil {
+		onChange(ep, i)
+	}
+}
+
+// downEndpoints 返回当前不可用的目标
+func (p *endpointPool) downEndpoints() map[int]Endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := make(map[int]Endpoint)
+	for i, down := range p.down {
+		if down {
+			result[i] = p.endpoints[i]
+		}
+	}
+	return result
+}
+
+// recover 健康检查通过后恢复目标，比当前目标优先级高时下一次请求即切回
+func (p *endpointPool) recover(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.down[i] {
+		log.Printf("上报目标 %s (%s) 健康检查通过，恢复可用", p.endpoints[i].URL, p.endpoints[i].Kind)
+		p.down[i] = false
+	}
+}
+
+// postAny 按优先级向可用的目标发送数据，目标不可用时切换到下一个目标
+// 启用WebSocket通道时先通过通道发送jsonData，失败时再通过HTTP发送processedData
+// 数据本身被拒绝(400/413)时不切换，其他目标同样会拒绝
+func (r *HTTPReporter) postAny(jsonData, processedData []byte, contentType string, batch, replayed bool) error {
+	if r.ws != nil {
+		err := r.ws.send(jsonData, batch, replayed)
+		if err == nil {
+			r.endpoints.markActive(0, r.onEndpointChange)
+			return nil
+		}
+		if !errors.Is(err, ErrNotConnected) {
+			log.Printf("WebSocket上报失败，改用HTTP上报: %v", err)
+		}
+	}
+
+	var lastErr error
+	for _, i := range r.endpoints.candidates() {
+		ep := r.endpoints.endpoints[i]
+		lastErr = r.post(ep, processedData, contentType, batch, replayed)
+		if lastErr == nil {
+			r.endpoints.markActive(i, r.onEndpointChange)
+			return nil
+		}
+		lastErr = fmt.Errorf("上报目标 %s: %w", ep.URL, lastErr)
+		if isPayloadRejected(lastErr) {
+			return lastErr
+		}
+		r.endpoints.markDown(i, lastErr)
+	}
+	return lastErr
+}
+
+// isPayloadRejected 判断错误是否为服务端拒绝了数据本身
+func isPayloadRejected(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	return statusErr.StatusCode == http.StatusBadRequest || statusErr.StatusCode == http.StatusRequestEntityTooLarge
+}
+
+// runHealthChecks 定期检查不可用的目标，直到上报器关闭
+func (r *HTTPReporter) runHealthChecks() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+		for i, ep := range r.endpoints.downEndpoints() {
+			if err := r.checkHealth(ep); err == nil {
+				r.endpoints.recover(i)
+			}
+		}
+	}
+}
+
+// checkHealth 请求目标的/health接口
+func (r *HTTPReporter) checkHealth(ep Endpoint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.endpointTimeout(ep))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ep.URL+"/health", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "SysLens-Agent")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// endpointTimeout 返回请求目标的超时时间
+func (r *HTTPReporter) endpointTimeout(ep Endpoint) time.Duration {
+	if ep.Timeout > 0 {
+		return ep.Timeout
+	}
+	return r.client.Timeout
+}
@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/syslens/syslens-api/internal/common/utils"
@@ -22,24 +27,50 @@ type Reporter interface {
 
 // HTTPReporter 实现了通过HTTP上报数据的Reporter
 type HTTPReporter struct {
-	serverURL     string        // 主控服务器URL
+	endpoints     *endpointPool // 按优先级排列的上报目标
 	nodeID        string        // 节点ID字段
 	client        *http.Client  // HTTP客户端
 	retryCount    int           // 重试次数
-	retryInterval time.Duration // 重试间隔
+	retryInterval time.Duration // 首次重试的退避上限，之后每次翻倍
+	maxInterval   time.Duration // 单次重试退避的最大值
 	authToken     string        // 认证令牌
 
 	securityConfig *config.SecurityConfig   // 安全配置
 	encryptionSvc  *utils.EncryptionService // 加密服务
+
+	spool    *Spool     // 上报失败数据的本地缓冲
+	replayMu sync.Mutex // 保证同一时间只有一个补发过程
+
+	batch      *BatchOptions // 批量上报选项，为nil时每次采集立即上报
+	batchMu    sync.Mutex
+	pending    [][]byte    // 等待批量发送的样本
+	flushTimer *time.Timer // 等待时间到达后发送批次
+
+	breaker *circuitBreaker // 熔断器，为nil时不熔断
+
+	queue   chan []byte   // 异步上报队列，为nil时在调用方的goroutine中同步上报
+	closeMu sync.RWMutex  // 保护closed，避免向已关闭的队列写入
+	closed  bool          // 上报器是否已关闭
+	done    chan struct{} // 关闭超时后中断重试等待
+	stop    chan struct{} // 关闭时停止健康检查
+	wg      sync.WaitGroup
+
+	healthInterval   time.Duration                   // 不可用目标的健康检查间隔
+	onEndpointChange func(ep Endpoint, priority int) // 上报目标切换时的回调
+
+	ws *WebSocketChannel // WebSocket通道，为nil时只通过HTTP上报
 }
 
 // NewHTTPReporter 创建一个新的HTTP上报器
 func NewHTTPReporter(serverURL string, nodeID string, options ...func(*HTTPReporter)) *HTTPReporter {
 	r := &HTTPReporter{
-		serverURL:     serverURL,
-		nodeID:        nodeID,
-		retryCount:    3,
-		retryInterval: 1 * time.Second,
+		nodeID:         nodeID,
+		retryCount:     3,
+		retryInterval:  1 * time.Second,
+		maxInterval:    defaultMaxRetryInterval,
+		done:           make(chan struct{}),
+		stop:           make(chan struct{}),
+		healthInterval: defaultHealthCheckInterval,
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
@@ -67,6 +98,27 @@ func NewHTTPReporter(serverURL string, nodeID string, options ...func(*HTTPRepor
 		r.encryptionSvc = utils.NewEncryptionService(r.securityConfig.Encryption.Algorithm)
 	}
 
+	// 未通过WithEndpoints设置上报目标时只向serverURL上报
+	if r.endpoints == nil {
+		r.endpoints = newEndpointPool([]Endpoint{{URL: strings.TrimRight(serverURL, "/"), Kind: EndpointServer}})
+	}
+	httpEndpoints := len(r.endpoints.endpoints)
+	// WebSocket通道作为最高优先级的上报目标
+	if r.ws != nil {
+		wsEndpoint := Endpoint{URL: r.ws.url, Kind: EndpointServer, WebSocket: true}
+		r.endpoints = newEndpointPool(append([]Endpoint{wsEndpoint}, r.endpoints.endpoints...))
+	}
+	if httpEndpoints > 1 {
+		r.wg.Add(1)
+		go r.runHealthChecks()
+	}
+
+	// 启动异步上报
+	if r.queue != nil {
+		r.wg.Add(1)
+		go r.run()
+	}
+
 	return r
 }
 
@@ -79,7 +131,7 @@ func WithRetryCount(count int) func(*HTTPReporter) {
 	}
 }
 
-// WithRetryInterval 设置重试间隔
+// WithRetryInterval 设置首次重试的退避上限，之后每次重试翻倍
 func WithRetryInterval(interval time.Duration) func(*HTTPReporter) {
 	return func(r *HTTPReporter) {
 		if interval > 0 {
@@ -88,6 +140,16 @@ func WithRetryInterval(interval time.Duration) func(*HTTPReporter) {
 	}
 }
 
+// WithMaxRetryInterval 设置单次重试退避的最大值
+// 服务端通过Retry-After要求等待更长时间时不再重试，由熔断器暂停上报
+func WithMaxRetryInterval(interval time.Duration) func(*HTTPReporter) {
+	return func(r *HTTPReporter) {
+		if interval > 0 {
+			r.maxInterval = interval
+		}
+	}
+}
+
 // WithTimeout 设置HTTP请求超时时间
 func WithTimeout(timeout time.Duration) func(*HTTPReporter) {
 	return func(r *HTTPReporter) {
@@ -117,18 +179,114 @@ func WithAuthToken(token string) func(*HTTPReporter) {
 	}
 }
 
+// WithSpool 设置本地缓冲，上报失败的数据写入缓冲并在上报恢复后补发
+func WithSpool(spool *Spool) func(*HTTPReporter) {
+	return func(r *HTTPReporter) {
+		r.spool = spool
+	}
+}
+
 // SetAuthToken 设置认证令牌
 func (r *HTTPReporter) SetAuthToken(token string) {
 	r.authToken = token
 }
 
 // Report 将数据上报到服务器
+// 启用异步上报时数据进入队列后立即返回，由后台goroutine发送，重试和熔断不影响采集节奏
+// 启用批量上报时数据先进入缓冲，达到样本数或等待时间后一起发送
+// 配置了本地缓冲时，重试后仍失败的数据写入缓冲，上报恢复后按写入顺序补发
+// 启用WebSocket通道时每次发送先尝试通道，通道不可用或未被确认时再通过HTTP发送
 func (r *HTTPReporter) Report(data interface{}) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("数据序列化失败: %w", err)
 	}
 
+	if r.queue != nil {
+		return r.enqueue(jsonData)
+	}
+	return r.deliver(jsonData)
+}
+
+// deliver 发送一条数据，失败时写入本地缓冲，成功后补发缓冲中的数据
+func (r *HTTPReporter) deliver(jsonData []byte) error {
+	if r.batch != nil {
+		return r.bufferSample(jsonData)
+	}
+
+	if err := r.send(jsonData, false, false, r.retryCount); err != nil {
+		if errors.Is(err, ErrPayloadRejected) {
+			log.Printf("服务端拒绝了上报数据，已丢弃: %v", err)
+			return err
+		}
+		r.spoolSamples([][]byte{jsonData})
+		return err
+	}
+
+	r.replaySpool()
+	return nil
+}
+
+// spoolSamples 将发送失败的样本写入本地缓冲，批量发送失败时逐个写入以便按条淘汰
+func (r *HTTPReporter) spoolSamples(samples [][]byte) {
+	if r.spool == nil {
+		return
+	}
+	for _, sample := range samples {
+		if err := r.spool.Append(sample); err != nil {
+			log.Printf("写入本地缓冲失败: %v", err)
+			return
+		}
+	}
+	log.Printf("上报失败的数据已写入本地缓冲，当前积压 %d 条", r.spool.Len())
+}
+
+// replaySpool 上报恢复后补发缓冲中的数据，补发失败时保留剩余数据等待下次上报成功
+func (r *HTTPReporter) replaySpool() {
+	if r.spool == nil || r.spool.Len() == 0 {
+		return
+	}
+	// 熔断期间不补发
+	if r.breaker.isOpen() {
+		return
+	}
+	// 已有补发在进行时跳过，避免重复发送同一批数据
+	if !r.replayMu.TryLock() {
+		return
+	}
+	defer r.replayMu.Unlock()
+
+	// 启用批量上报时按批次补发，补发不重试，失败时留到下次
+	batchSize := 1
+	if r.batch != nil {
+		batchSize = r.batch.MaxSamples
+	}
+	replayed, err := r.spool.Replay(batchSize, func(samples [][]byte) error {
+		if r.batch == nil {
+			return r.send(samples[0], false, true, 0)
+		}
+		payload, err := utils.EncodeMetricsBatch(samples, r.batch.Delta, time.Now())
+		if err != nil {
+			return fmt.Errorf("批量数据编码失败: %w", err)
+		}
+		return r.send(payload, true, true, 0)
+	})
+	if err != nil {
+		log.Printf("补发缓冲数据失败，已补发 %d 条，剩余 %d 条: %v", replayed, r.spool.Len(), err)
+		return
+	}
+	log.Printf("已补发缓冲数据 %d 条，剩余 %d 条", replayed, r.spool.Len())
+}
+
+// send 压缩、加密并发送一条数据，失败时最多重试retryCount次
+// 重试间隔为指数退避加全抖动，服务端返回Retry-After时按其要求等待；熔断期间直接返回ErrCircuitOpen
+// 服务端拒绝数据(400/413)时不重试也不计入熔断，直接返回包装了ErrPayloadRejected的错误
+// batch标记批量数据；replayed标记补发的历史数据，服务端据此跳过时钟偏差检测
+func (r *HTTPReporter) send(jsonData []byte, batch, replayed bool, retryCount int) error {
+	if err := r.breaker.allow(); err != nil {
+		return err
+	}
+
 	// 压缩和加密数据
 	processedData, contentType, err := r.processData(jsonData)
 	if err != nil {
@@ -137,97 +295,210 @@ func (r *HTTPReporter) Report(data interface{}) error {
 
 	// 发送数据，支持重试
 	var lastErr error
-	for i := 0; i <= r.retryCount; i++ {
+	attempts := 0
+	for i := 0; i <= retryCount; i++ {
 		if i > 0 {
 			// 重试前等待
-			retryDelay := r.retryInterval
-			log.Printf("上报重试 (%d/%d)，等待 %v 后重试...", i, r.retryCount, retryDelay)
-			time.Sleep(retryDelay)
-		}
-
-		// 构建请求URL
-		nodeID := r.nodeID
-		if nodeID == "" {
-			if hostname, err := os.Hostname(); err == nil {
-				nodeID = hostname
-			} else {
-				nodeID = "unknown-node"
+			retryDelay := r.backoff(i)
+			var statusErr *StatusError
+			if errors.As(lastErr, &statusErr) && statusErr.RetryAfter > 0 {
+				if statusErr.RetryAfter > r.maxInterval {
+					// 服务端要求等待的时间过长，不在此阻塞，由熔断器暂停上报
+					r.breaker.hold(statusErr.RetryAfter)
+					break
+				}
+				retryDelay = statusErr.RetryAfter
+			}
+			log.Printf("上报重试 (%d/%d)，等待 %v 后重试...", i, retryCount, retryDelay)
+			if !r.wait(retryDelay) {
+				break
+			}
+			// 重试期间熔断器可能已断开
+			if err := r.breaker.allow(); err != nil {
+				break
 			}
 		}
-		url := fmt.Sprintf("%s/api/v1/nodes/%s/metrics", r.serverURL, nodeID)
 
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(processedData))
-		if err != nil {
-			lastErr = fmt.Errorf("创建HTTP请求失败: %w", err)
-			log.Printf("重试失败: %v", lastErr)
-			continue
+		attempts++
+		lastErr = r.postAny(jsonData, processedData, contentType, batch, replayed)
+		if lastErr == nil {
+			r.breaker.success()
+			return nil
+		}
+		if isPayloadRejected(lastErr) {
+			// 服务端可用但拒绝了数据本身，重试或缓冲后补发都不会成功
+			r.breaker.success()
+			return fmt.Errorf("%w: %w", ErrPayloadRejected, lastErr)
 		}
+		r.breaker.failure()
+	}
 
-		// 设置适当的内容类型
-		req.Header.Set("Content-Type", contentType)
-		req.Header.Set("User-Agent", "SysLens-Agent")
+	// 构造详细的错误信息
+	detailedErr := fmt.Errorf("数据上报失败，已重试%d次，最后错误: %w", attempts-1, lastErr)
 
-		// 添加节点ID头部
-		req.Header.Set("X-Node-ID", nodeID)
+	return detailedErr
+}
 
-		// 添加认证令牌
-		if r.authToken != "" {
-			req.Header.Set("Authorization", "Bearer "+r.authToken)
-		}
+// backoff 返回第attempt次重试前的等待时间
+func (r *HTTPReporter) backoff(attempt int) time.Duration {
+	return jitterBackoff(attempt, r.retryInterval, r.maxInterval)
+}
 
-		// 添加数据处理标记
-		if r.securityConfig.Compression.Enabled {
-			req.Header.Set("X-Compressed", "gzip")
+// jitterBackoff 指数退避加全抖动
+// 上限从base开始每次翻倍，不超过max，实际等待时间在(0, 上限]内随机选取，
+// 避免大量节点在服务端重启后同时重试
+func jitterBackoff(attempt int, base, max time.Duration) time.Duration {
+	ceiling := max
+	if attempt <= 30 {
+		if d := base << (attempt - 1); d > 0 && d < ceiling {
+			ceiling = d
 		}
-		if r.securityConfig.Encryption.Enabled {
-			req.Header.Set("X-Encrypted", "true")
+	}
+	return time.Duration(rand.Int63n(int64(ceiling))) + 1
+}
+
+// wait 等待指定时间，上报器关闭超时时提前返回false
+func (r *HTTPReporter) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.done:
+		return false
+	}
+}
+
+// post 向指定目标发送一次上报请求
+func (r *HTTPReporter) post(ep Endpoint, processedData []byte, contentType string, batch, replayed bool) error {
+	// 构建请求URL
+	nodeID := r.nodeID
+	if nodeID == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			nodeID = hostname
+		} else {
+			nodeID = "unknown-node"
 		}
+	}
+	url := fmt.Sprintf("%s/api/v1/nodes/%s/metrics", ep.URL, nodeID)
 
-		// 添加请求上下文，带超时控制
-		ctx, cancel := context.WithTimeout(context.Background(), r.client.Timeout)
-		req = req.WithContext(ctx)
+	// 添加请求上下文，带超时控制
+	ctx, cancel := context.WithTimeout(context.Background(), r.endpointTimeout(ep))
+	defer cancel() // 释放上下文资源
 
-		startTime := time.Now()
-		resp, err := r.client.Do(req)
-		requestTime := time.Since(startTime)
-		cancel() // 释放上下文资源
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(processedData))
+	if err != nil {
+		err = fmt.Errorf("创建HTTP请求失败: %w", err)
+		log.Printf("重试失败: %v", err)
+		return err
+	}
 
-		if err != nil {
-			lastErr = fmt.Errorf("HTTP请求失败 (耗时: %v): %w", requestTime, err)
-			log.Printf("请求错误: %v", lastErr)
-			continue
-		}
+	// 设置适当的内容类型
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "SysLens-Agent")
 
-		defer resp.Body.Close()
+	// 添加节点ID头部
+	req.Header.Set("X-Node-ID", nodeID)
 
-		// 读取响应内容，用于详细日志
-		respBody, _ := io.ReadAll(resp.Body)
+	// 添加认证令牌
+	if r.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.authToken)
+	}
 
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			log.Printf("上报成功，响应码: %d，耗时: %v", resp.StatusCode, requestTime)
-			return nil // 成功
-		}
+	// 添加数据处理标记
+	if r.securityConfig.Compression.Enabled {
+		req.Header.Set("X-Compressed", "gzip")
+	}
+	if r.securityConfig.Encryption.Enabled {
+		req.Header.Set("X-Encrypted", "true")
+	}
+	if batch {
+		req.Header.Set("X-Batch", "true")
+	} else {
+		// 单条指标的发送时间，主控端据此扣除重试等造成的延迟后再计算时钟偏差；批量数据在sent_at中携带
+		req.Header.Set("X-Sent-At", time.Now().Format(time.RFC3339Nano))
+	}
+	if replayed {
+		req.Header.Set("X-Replayed", "true")
+	}
+
+	startTime := time.Now()
+	resp, err := r.client.Do(req)
+	requestTime := time.Since(startTime)
 
-		lastErr = fmt.Errorf("服务器返回错误状态码: %d，响应: %s", resp.StatusCode, string(respBody))
-		log.Printf("服务端错误: %v", lastErr)
+	if err != nil {
+		err = fmt.Errorf("HTTP请求失败 (耗时: %v): %w", requestTime, err)
+		log.Printf("请求错误: %v", err)
+		return err
 	}
+	defer resp.Body.Close()
 
-	// 构造详细的错误信息
-	detailedErr := fmt.Errorf("数据上报失败，已重试%d次，主控节点URL: %s，最后错误: %w",
-		r.retryCount, r.serverURL, lastErr)
+	// 读取响应内容，用于详细日志
+	respBody, _ := io.ReadAll(resp.Body)
 
-	return detailedErr
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		log.Printf("上报成功，响应码: %d，耗时: %v", resp.StatusCode, requestTime)
+		return nil // 成功
+	}
+
+	err = &StatusError{
+		StatusCode: resp.StatusCode,
+		Body:       string(respBody),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+	log.Printf("服务端错误: %v", err)
+	return err
+}
+
+// ErrPayloadRejected 服务端拒绝了上报的数据(400/413)，数据不会被重试或写入本地缓冲
+var ErrPayloadRejected = errors.New("服务端拒绝了上报数据")
+
+// StatusError 服务端返回的非2xx响应
+type StatusError struct {
+	StatusCode int
+	Body       string
+	// 服务端通过Retry-After要求的等待时间，未指定时为0
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("服务器返回错误状态码: %d，要求 %v 后重试，响应: %s", e.StatusCode, e.RetryAfter, e.Body)
+	}
+	return fmt.Sprintf("服务器返回错误状态码: %d，响应: %s", e.StatusCode, e.Body)
+}
+
+// parseRetryAfter 解析Retry-After头部，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		return 0
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
 }
 
 // processData 处理数据：压缩和加密
 func (r *HTTPReporter) processData(data []byte) ([]byte, string, error) {
+	return processPayload(data, r.securityConfig, r.encryptionSvc)
+}
+
+// processPayload 按安全配置压缩和加密数据，返回处理后的数据及对应的内容类型
+func processPayload(data []byte, securityConfig *config.SecurityConfig, encryptionSvc *utils.EncryptionService) ([]byte, string, error) {
 	processedData := data
 	contentType := "application/json"
 	var err error
 
 	// 步骤1：压缩
-	if r.securityConfig.Compression.Enabled {
-		processedData, err = utils.CompressData(processedData, r.securityConfig.Compression.Level)
+	if securityConfig.Compression.Enabled {
+		processedData, err = utils.CompressData(processedData, securityConfig.Compression.Level)
 		if err != nil {
 			return nil, contentType, fmt.Errorf("压缩失败: %w", err)
 		}
@@ -235,8 +506,8 @@ func (r *HTTPReporter) processData(data []byte) ([]byte, string, error) {
 	}
 
 	// 步骤2：加密
-	if r.securityConfig.Encryption.Enabled && r.encryptionSvc != nil {
-		processedData, err = r.encryptionSvc.Encrypt(processedData, r.securityConfig.Encryption.Key)
+	if securityConfig.Encryption.Enabled && encryptionSvc != nil {
+		processedData, err = encryptionSvc.Encrypt(processedData, securityConfig.Encryption.Key)
 		if err != nil {
 			return nil, contentType, fmt.Errorf("加密失败: %w", err)
 		}
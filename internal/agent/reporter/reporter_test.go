@@ -0,0 +1,690 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/syslens/syslens-api/internal/common/utils"
+)
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := OpenSpool(SpoolOptions{Dir: dir, MaxBytes: 30, ReplayBatch: 2})
+	if err != nil {
+		t.Fatalf("打开缓冲失败: %v", err)
+	}
+
+	// 超出容量时丢弃最旧的数据
+	for i := 0; i < 4; i++ {
+		if err := spool.Append([]byte(fmt.Sprintf(`{"seq":%d}`, i))); err != nil {
+			t.Fatalf("写入缓冲失败: %v", err)
+		}
+	}
+	if spool.Len() != 3 || spool.Size() != 27 {
+		t.Fatalf("淘汰结果异常: len=%d, size=%d", spool.Len(), spool.Size())
+	}
+
+	// 写入中途退出留下的临时文件在重新打开时被清理，已有数据按顺序加载
+	if err := os.WriteFile(filepath.Join(dir, "0000000000000000001.json.tmp"), []byte("{"), 0600); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	spool, err = OpenSpool(SpoolOptions{Dir: dir, MaxBytes: 30, ReplayBatch: 2})
+	if err != nil {
+		t.Fatalf("重新打开缓冲失败: %v", err)
+	}
+	if spool.Len() != 3 {
+		t.Fatalf("重新打开后的数据条数异常: %d", spool.Len())
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(matches) != 0 {
+		t.Errorf("临时文件未被清理: %v", matches)
+	}
+
+	// 按写入顺序补发，每次最多补发ReplayBatch条，发送失败时保留剩余数据
+	var sent []string
+	replayed, err := spool.Replay(1, func(samples [][]byte) error {
+		sent = append(sent, string(samples[0]))
+		return nil
+	})
+	if err != nil || replayed != 2 || len(sent) != 2 || sent[0] != `{"seq":1}` || sent[1] != `{"seq":2}` {
+		t.Fatalf("补发结果异常: %d, %v, %v", replayed, sent, err)
+	}
+	replayed, err = spool.Replay(1, func(samples [][]byte) error { return fmt.Errorf("连接被拒绝") })
+	if err == nil || replayed != 0 || spool.Len() != 1 {
+		t.Errorf("发送失败时应保留数据: %d, %d, %v", replayed, spool.Len(), err)
+	}
+
+	// 服务端拒绝的数据被丢弃，不阻塞之后的数据；整批被拒绝时逐条重发
+	rejecting, err := OpenSpool(SpoolOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("打开缓冲失败: %v", err)
+	}
+	for _, data := range []string{"a", "bad", "c", "d"} {
+		if err := rejecting.Append([]byte(data)); err != nil {
+			t.Fatalf("写入缓冲失败: %v", err)
+		}
+	}
+	var attempts []string
+	replayed, err = rejecting.Replay(2, func(samples [][]byte) error {
+		batch := string(bytes.Join(samples, []byte(",")))
+		attempts = append(attempts, batch)
+		if strings.Contains(batch, "bad") {
+			return &StatusError{StatusCode: http.StatusBadRequest}
+		}
+		return nil
+	})
+	if err != nil || replayed != 3 || rejecting.Len() != 0 || fmt.Sprint(attempts) != "[a,bad a bad c,d]" {
+		t.Errorf("被拒绝的数据处理异常: %d, %d, %v, %v", replayed, rejecting.Len(), attempts, err)
+	}
+
+	// 超过保留时间的数据被丢弃
+	spool, err = OpenSpool(SpoolOptions{Dir: dir, MaxAge: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("重新打开缓冲失败: %v", err)
+	}
+	if spool.Len() != 0 {
+		t.Errorf("过期数据未被丢弃: %d", spool.Len())
+	}
+}
+
+func TestHTTPReporterReplay(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		down     = true
+		received []string
+		replayed []bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var data map[string]string
+		json.Unmarshal(body, &data)
+		received = append(received, data["id"])
+		replayed = append(replayed, r.Header.Get("X-Replayed") == "true")
+		if _, err := time.Parse(time.RFC3339Nano, r.Header.Get("X-Sent-At")); err != nil {
+			t.Errorf("单条上报缺少发送时间: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	spool, err := OpenSpool(SpoolOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("打开缓冲失败: %v", err)
+	}
+	r := NewHTTPReporter(server.URL, "node-1", WithRetryCount(0), WithSpool(spool))
+
+	// 服务端不可用时数据写入缓冲
+	for _, id := range []string{"a", "b"} {
+		if err := r.Report(map[string]string{"id": id}); err == nil {
+			t.Fatal("服务端不可用时应返回错误")
+		}
+	}
+	if spool.Len() != 2 {
+		t.Fatalf("上报失败的数据未写入缓冲: %d", spool.Len())
+	}
+
+	// 恢复后先上报当前数据，再按顺序补发缓冲中的数据
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	if err := r.Report(map[string]string{"id": "c"}); err != nil {
+		t.Fatalf("上报失败: %v", err)
+	}
+	if spool.Len() != 0 {
+		t.Errorf("补发后缓冲应为空: %d", spool.Len())
+	}
+	if fmt.Sprint(received) != "[c a b]" || fmt.Sprint(replayed) != "[false true true]" {
+		t.Errorf("补发顺序或标记异常: %v, %v", received, replayed)
+	}
+}
+
+func TestHTTPReporterBatch(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Batch") != "true" {
+			t.Errorf("批量数据缺少X-Batch标记")
+		}
+		batch, samples, sampleErrors, err := utils.DecodeMetricsBatch(body)
+		if err != nil || len(sampleErrors) != 0 || !batch.Delta {
+			t.Errorf("批量数据解析失败: %v, %v", err, sampleErrors)
+		}
+		mu.Lock()
+		batches = append(batches, samples)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	r := NewHTTPReporter(server.URL, "node-1", WithBatch(BatchOptions{MaxSamples: 3, MaxDelay: 50 * time.Millisecond, Delta: true}))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sample := func(i int) map[string]interface{} {
+		s := map[string]interface{}{
+			"timestamp": start.Add(time.Duration(i) * time.Second).Format(time.RFC3339Nano),
+			"hostname":  "node-1",
+			"cpu":       map[string]interface{}{"usage": float64(i), "user": 1.5},
+		}
+		if i == 0 {
+			s["inventory"] = map[string]interface{}{"cpu_model": "test"}
+		}
+		return s
+	}
+
+	// 达到样本数时立即发送，增量编码后的样本能还原为完整数据
+	for i := 0; i < 3; i++ {
+		if err := r.Report(sample(i)); err != nil {
+			t.Fatalf("上报失败: %v", err)
+		}
+	}
+	mu.Lock()
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("批次数量异常: %v", batches)
+	}
+	last := batches[0][2]
+	if cpu := last["cpu"].(map[string]interface{}); cpu["usage"] != 2.0 || cpu["user"] != 1.5 || last["hostname"] != "node-1" {
+		t.Errorf("增量样本还原异常: %v", last)
+	}
+	if _, ok := last["inventory"]; ok {
+		t.Errorf("已删除的字段不应被还原: %v", last)
+	}
+	mu.Unlock()
+
+	// 未达到样本数时等待时间到达后发送
+	if err := r.Report(sample(3)); err != nil {
+		t.Fatalf("上报失败: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	if len(batches) != 2 || len(batches[1]) != 1 {
+		t.Errorf("等待时间到达后未发送: %d", len(batches))
+	}
+	mu.Unlock()
+
+	// 无效样本导致整批被拒绝，并返回对应的序号
+	invalid, _ := utils.EncodeMetricsBatch([][]byte{[]byte(`{"timestamp":"2024-01-01T00:00:00Z"}`), []byte(`{"hostname":"x"}`), []byte(`[]`)}, false, start)
+	_, _, sampleErrors, err := utils.DecodeMetricsBatch(invalid)
+	if err != nil || len(sampleErrors) != 2 || sampleErrors[0].Index != 1 || sampleErrors[1].Index != 2 {
+		t.Errorf("样本校验结果异常: %v, %v", sampleErrors, err)
+	}
+}
+
+func TestHTTPReporterRejected(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+		accepted []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var ids []string
+		if r.Header.Get("X-Batch") == "true" {
+			var batch struct {
+				Samples []map[string]string `json:"samples"`
+			}
+			json.Unmarshal(body, &batch)
+			for _, sample := range batch.Samples {
+				ids = append(ids, sample["id"])
+			}
+		} else {
+			var sample map[string]string
+			json.Unmarshal(body, &sample)
+			ids = append(ids, sample["id"])
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		for _, id := range ids {
+			if id == "bad" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		accepted = append(accepted, ids...)
+	}))
+	defer server.Close()
+
+	// 被拒绝的数据不重试、不计入熔断、不写入缓冲
+	spool, err := OpenSpool(SpoolOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("打开缓冲失败: %v", err)
+	}
+	r := NewHTTPReporter(server.URL, "node-1", WithRetryCount(3), WithRetryInterval(time.Millisecond),
+		WithCircuitBreaker(1, time.Hour), WithSpool(spool))
+	if err := r.Report(map[string]string{"id": "bad"}); !errors.Is(err, ErrPayloadRejected) {
+		t.Fatalf("被拒绝的数据应返回ErrPayloadRejected: %v", err)
+	}
+	if requests != 1 || spool.Len() != 0 || r.breaker.isOpen() {
+		t.Errorf("被拒绝的数据不应重试、缓冲或熔断: requests=%d, spool=%d, open=%v", requests, spool.Len(), r.breaker.isOpen())
+	}
+	if err := r.Report(map[string]string{"id": "a"}); err != nil {
+		t.Errorf("拒绝之后的上报失败: %v", err)
+	}
+
+	// 整批被拒绝时逐个样本重发，只丢弃被拒绝的样本
+	batched := NewHTTPReporter(server.URL, "node-1", WithBatch(BatchOptions{MaxSamples: 3, MaxDelay: time.Hour}), WithSpool(spool))
+	for _, id := range []string{"b", "bad"} {
+		if err := batched.Report(map[string]string{"id": id}); err != nil {
+			t.Fatalf("缓冲样本失败: %v", err)
+		}
+	}
+	if err := batched.Report(map[string]string{"id": "c"}); !errors.Is(err, ErrPayloadRejected) {
+		t.Errorf("批次中被拒绝的样本应返回ErrPayloadRejected: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(accepted) != "[a b c]" || spool.Len() != 0 {
+		t.Errorf("批次拒绝处理异常: %v, spool=%d", accepted, spool.Len())
+	}
+}
+
+func TestHTTPReporterBackoff(t *testing.T) {
+	r := NewHTTPReporter("http://localhost", "node-1", WithRetryInterval(100*time.Millisecond), WithMaxRetryInterval(time.Second))
+	for attempt := 1; attempt <= 64; attempt++ {
+		ceiling := time.Second
+		if attempt <= 4 {
+			ceiling = 100 * time.Millisecond << (attempt - 1)
+		}
+		if d := r.backoff(attempt); d <= 0 || d > ceiling {
+			t.Errorf("第%d次重试的退避时间超出范围: %v", attempt, d)
+		}
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"":                              0,
+		"5":                             5 * time.Second,
+		"-1":                            0,
+		"Mon, 01 Jan 2024 00:00:30 GMT": 30 * time.Second,
+		"Sun, 31 Dec 2023 23:00:00 GMT": 0,
+		"soon":                          0,
+	} {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("Retry-After %q 解析结果异常: %v", value, got)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := &circuitBreaker{threshold: 2, cooldown: 30 * time.Second, now: func() time.Time { return now }}
+
+	// 连续失败达到阈值后断开
+	b.failure()
+	if err := b.allow(); err != nil {
+		t.Fatalf("未达到阈值时不应断开: %v", err)
+	}
+	b.failure()
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("达到阈值后应断开: %v", err)
+	}
+
+	// 冷却结束后只放行一个探测请求，探测失败重新断开
+	now = now.Add(31 * time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("冷却结束后应放行探测请求: %v", err)
+	}
+	if err := b.allow(); err == nil {
+		t.Fatal("探测期间不应放行其他请求")
+	}
+	b.failure()
+	if !b.isOpen() {
+		t.Fatal("探测失败后应重新断开")
+	}
+
+	// 探测成功后恢复
+	now = now.Add(31 * time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("冷却结束后应放行探测请求: %v", err)
+	}
+	b.success()
+	if err := b.allow(); err != nil || b.isOpen() {
+		t.Fatalf("探测成功后应闭合: %v", err)
+	}
+
+	// 服务端要求等待的时间超过冷却时间时按服务端的要求断开
+	b.hold(5 * time.Minute)
+	now = now.Add(time.Minute)
+	if !b.isOpen() {
+		t.Error("应按Retry-After的时间断开")
+	}
+}
+
+func TestHTTPReporterAsync(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+		received []string
+	)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests <= 2 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var data map[string]string
+		json.Unmarshal(body, &data)
+		received = append(received, data["id"])
+	}))
+	defer server.Close()
+
+	spool, err := OpenSpool(SpoolOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("打开缓冲失败: %v", err)
+	}
+	r := NewHTTPReporter(server.URL, "node-1",
+		WithRetryInterval(time.Millisecond), WithMaxRetryInterval(10*time.Millisecond),
+		WithCircuitBreaker(5, 50*time.Millisecond), WithAsync(2), WithSpool(spool))
+
+	// 服务端阻塞时Report立即返回，队列已满的数据写入本地缓冲
+	start := time.Now()
+	for _, id := range []string{"a", "b", "c", "d"} {
+		r.Report(map[string]string{"id": id})
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("异步上报阻塞了调用方: %v", elapsed)
+	}
+	if spool.Len() == 0 {
+		t.Error("队列已满的数据应写入本地缓冲")
+	}
+
+	// Retry-After超过退避上限时不再重试，由熔断器暂停上报，数据写入缓冲
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for !r.breaker.isOpen() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !r.breaker.isOpen() {
+		t.Fatal("Retry-After超过退避上限时应熔断")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := r.Close(ctx); err != nil {
+		t.Fatalf("关闭上报器失败: %v", err)
+	}
+	if err := r.Report(map[string]string{"id": "e"}); !errors.Is(err, ErrReporterClosed) {
+		t.Errorf("关闭后应拒绝新数据: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 1 || spool.Len() != 4 {
+		t.Errorf("熔断期间不应继续请求: requests=%d, spooled=%d", requests, spool.Len())
+	}
+}
+
+func TestHTTPReporterFailover(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		primaryDown = true
+		hits        = map[string]int{}
+	)
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if name == "primary" && primaryDown {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if r.URL.Path == "/health" {
+				return
+			}
+			hits[name]++
+		}
+	}
+	primary := httptest.NewServer(handler("primary"))
+	defer primary.Close()
+	fallback := httptest.NewServer(handler("fallback"))
+	defer fallback.Close()
+
+	var changes []int
+	r := NewHTTPReporter("", "node-1",
+		WithRetryCount(0),
+		WithEndpoints([]Endpoint{
+			{URL: primary.URL, Kind: EndpointAggregator},
+			{URL: fallback.URL + "/", Kind: EndpointServer},
+		}),
+		WithHealthCheckInterval(20*time.Millisecond),
+		WithEndpointChange(func(ep Endpoint, priority int) {
+			mu.Lock()
+			changes = append(changes, priority)
+			mu.Unlock()
+		}))
+	defer r.Close(context.Background())
+
+	// 首选目标不可用时切换到下一个目标，之后的请求不再尝试不可用的目标
+	for i := 0; i < 2; i++ {
+		if err := r.Report(map[string]int{"seq": i}); err != nil {
+			t.Fatalf("切换目标后上报失败: %v", err)
+		}
+	}
+	if ep, priority, ok := r.ActiveEndpoint(); !ok || priority != 1 || ep.Kind != EndpointServer || ep.URL != fallback.URL {
+		t.Fatalf("当前上报目标异常: %v, %d", ep, priority)
+	}
+	mu.Lock()
+	if hits["fallback"] != 2 {
+		t.Errorf("备用目标的请求数异常: %v", hits)
+	}
+	primaryDown = false
+	mu.Unlock()
+
+	// 健康检查通过后切回首选目标
+	time.Sleep(100 * time.Millisecond)
+	if err := r.Report(map[string]int{"seq": 2}); err != nil {
+		t.Fatalf("上报失败: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["primary"] != 1 || fmt.Sprint(changes) != "[1 0]" {
+		t.Errorf("未切回首选目标: %v, %v", hits, changes)
+	}
+
+	// 数据本身被拒绝时不切换目标
+	if !isPayloadRejected(fmt.Errorf("上报目标 x: %w", &StatusError{StatusCode: http.StatusBadRequest})) {
+		t.Error("400应视为数据被拒绝")
+	}
+}
+
+// recordingReporter 记录收到的数据，用作WebSocketReporter的Fallback
+func TestHTTPReporterWebSocket(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		connections int
+		current     *websocket.Conn
+		messages    []utils.WebSocketMessage
+		httpPosts   []string
+	)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/ws/nodes" {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			httpPosts = append(httpPosts, r.Header.Get("X-Batch")+":"+string(body))
+			mu.Unlock()
+			return
+		}
+		if r.URL.Query().Get("node_id") != "node-1" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		mu.Lock()
+		connections++
+		current = conn
+		mu.Unlock()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg utils.WebSocketMessage
+			json.Unmarshal(data, &msg)
+			switch msg.Type {
+			case utils.WebSocketMessageCommand:
+				conn.WriteJSON(utils.WebSocketMessage{Type: utils.WebSocketMessageConfig, Data: json.RawMessage(`{"log_level":"debug"}`), Version: 1})
+			case utils.WebSocketMessageMetrics:
+				mu.Lock()
+				messages = append(messages, msg)
+				mu.Unlock()
+				ack := utils.WebSocketMessage{Type: utils.WebSocketMessageAck, ID: msg.ID}
+				if strings.Contains(string(msg.Data), "bad") {
+					ack.Error = "存储指标数据失败"
+				}
+				conn.WriteJSON(ack)
+			}
+		}
+	}))
+	defer server.Close()
+
+	configs := make(chan map[string]interface{}, 4)
+	ws, err := NewWebSocketChannel(server.URL, "node-1", WebSocketOptions{
+		AuthToken:    "secret",
+		PingInterval: 50 * time.Millisecond,
+		OnConfig:     func(nodeConfig map[string]interface{}) { configs <- nodeConfig },
+	})
+	if err != nil {
+		t.Fatalf("创建WebSocket通道失败: %v", err)
+	}
+
+	// 连接建立后请求并应用当前的节点配置
+	select {
+	case nodeConfig := <-configs:
+		if nodeConfig["log_level"] != "debug" {
+			t.Errorf("节点配置异常: %v", nodeConfig)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("未收到节点配置")
+	}
+
+	spool, err := OpenSpool(SpoolOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("打开缓冲失败: %v", err)
+	}
+	spool.Append([]byte(`{"id":"old"}`))
+
+	var priorities []int
+	r := NewHTTPReporter(server.URL, "node-1",
+		WithRetryCount(0),
+		WithSpool(spool),
+		WithBatch(BatchOptions{MaxSamples: 2, MaxDelay: time.Minute}),
+		WithWebSocket(ws),
+		WithEndpointChange(func(ep Endpoint, priority int) {
+			mu.Lock()
+			priorities = append(priorities, priority)
+			mu.Unlock()
+		}),
+	)
+	defer r.Close(context.Background())
+
+	ids := func(msg utils.WebSocketMessage) string {
+		var batch struct {
+			Samples []map[string]string `json:"samples"`
+		}
+		json.Unmarshal(msg.Data, &batch)
+		var result []string
+		for _, sample := range batch.Samples {
+			result = append(result, sample["id"])
+		}
+		return strings.Join(result, ",")
+	}
+	report := func(id string) {
+		t.Helper()
+		if err := r.Report(map[string]string{"id": id}); err != nil {
+			t.Fatalf("上报失败: %v", err)
+		}
+	}
+
+	// 批次通过WebSocket发送，成功后同样通过WebSocket补发缓冲中的数据
+	report("a")
+	report("b")
+	mu.Lock()
+	if len(messages) != 2 || !messages[0].Batch || messages[0].Replayed || ids(messages[0]) != "a,b" ||
+		!messages[1].Batch || !messages[1].Replayed || ids(messages[1]) != "old" {
+		t.Errorf("WebSocket批量上报或补发异常: %+v", messages)
+	}
+	if len(httpPosts) != 0 || spool.Len() != 0 {
+		t.Errorf("WebSocket可用时不应通过HTTP上报: %v, 缓冲剩余 %d 条", httpPosts, spool.Len())
+	}
+	mu.Unlock()
+	if ep, priority, ok := r.ActiveEndpoint(); !ok || !ep.WebSocket || priority != 0 || !strings.HasPrefix(ep.URL, "ws://") {
+		t.Errorf("当前上报目标应为WebSocket通道: %+v, %d", ep, priority)
+	}
+
+	// 主控端未能写入时改用HTTP上报，之后的数据仍优先通过WebSocket发送
+	report("bad")
+	report("c")
+	report("d")
+	report("e")
+	mu.Lock()
+	if len(httpPosts) != 1 || !strings.HasPrefix(httpPosts[0], "true:") || !strings.Contains(httpPosts[0], "bad") {
+		t.Errorf("未被写入的批次应通过HTTP上报: %v", httpPosts)
+	}
+	if len(messages) != 4 || ids(messages[3]) != "d,e" {
+		t.Errorf("恢复后应继续通过WebSocket上报: %d", len(messages))
+	}
+	if fmt.Sprint(priorities) != "[0 1 0]" {
+		t.Errorf("上报目标切换异常: %v", priorities)
+	}
+	mu.Unlock()
+
+	// 连接断开后自动重连，并重新请求配置；保持连接期间ping不会使连接超时
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	current.Close()
+	mu.Unlock()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		mu.Lock()
+		reconnected := connections == 2 && ws.Connected()
+		mu.Unlock()
+		if reconnected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("等待重新连接超时")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	<-configs
+
+	// 关闭后不再通过WebSocket发送
+	if err := ws.Close(context.Background()); err != nil {
+		t.Fatalf("关闭WebSocket通道失败: %v", err)
+	}
+	if err := ws.send([]byte(`{}`), false, false); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("关闭后应返回ErrNotConnected: %v", err)
+	}
+}
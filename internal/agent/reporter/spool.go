@@ -0,0 +1,297 @@
+package reporter
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 缓冲目录默认的容量上限
+	defaultSpoolMaxBytes = 256 * 1024 * 1024
+	// 缓冲数据默认的保留时长
+	defaultSpoolMaxAge = 7 * 24 * time.Hour
+	// 每次上报成功后默认最多补发的条数
+	defaultSpoolReplayBatch = 100
+
+	spoolFileSuffix = ".json"
+	spoolTempSuffix = ".tmp"
+)
+
+// SpoolOptions 本地缓冲选项
+type SpoolOptions struct {
+	// 缓冲目录，每条上报数据保存为一个文件
+	Dir string
+	// 缓冲数据的总大小上限(字节)，超出时从最旧的数据开始丢弃
+	MaxBytes int64
+	// 缓冲数据的最长保留时间，超过的数据被丢弃
+	MaxAge time.Duration
+	// 每次上报成功后最多补发的条数，避免积压过多时长时间阻塞采集
+	ReplayBatch int
+}
+
+// spoolEntry 缓冲中的一条数据，seq为写入时间的纳秒时间戳，同时作为文件名
+type spoolEntry struct {
+	seq  int64
+	size int64
+}
+
+// Spool 上报失败数据的本地缓冲（预写日志）
+// 每条数据先写入临时文件并fsync，再重命名为正式文件，代理异常退出时不会留下不完整的数据
+type Spool struct {
+	mu      sync.Mutex
+	options SpoolOptions
+	entries []spoolEntry // 按写入顺序排列，最旧的在前
+	size    int64
+	lastSeq int64
+}
+
+// OpenSpool 打开缓冲目录并加载已有的数据，目录不存在时自动创建
+func OpenSpool(opts SpoolOptions) (*Spool, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("未配置缓冲目录")
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultSpoolMaxBytes
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = defaultSpoolMaxAge
+	}
+	if opts.ReplayBatch <= 0 {
+		opts.ReplayBatch = defaultSpoolReplayBatch
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建缓冲目录失败: %w", err)
+	}
+
+	files, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取缓冲目录失败: %w", err)
+	}
+
+	s := &Spool{options: opts}
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasSuffix(name, spoolTempSuffix) {
+			// 写入中途退出留下的临时文件
+			os.Remove(filepath.Join(opts.Dir, name))
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimSuffix(name, spoolFileSuffix), 10, 64)
+		if err != nil || !strings.HasSuffix(name, spoolFileSuffix) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		s.entries = append(s.entries, spoolEntry{seq: seq, size: info.Size()})
+		s.size += info.Size()
+	}
+	sort.Slice(s.entries, func(i, j int) bool { return s.entries[i].seq < s.entries[j].seq })
+	if len(s.entries) > 0 {
+		s.lastSeq = s.entries[len(s.entries)-1].seq
+	}
+
+	s.mu.Lock()
+	s.evict(time.Now())
+	s.mu.Unlock()
+	return s, nil
+}
+
+// Append 将一条上报数据写入缓冲，超出容量时丢弃最旧的数据
+func (s *Spool) Append(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	seq := now.UnixNano()
+	if seq <= s.lastSeq {
+		seq = s.lastSeq + 1
+	}
+
+	path := s.path(seq)
+	tmp := path + spoolTempSuffix
+	if err := writeFileSync(tmp, data); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入缓冲文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入缓冲文件失败: %w", err)
+	}
+	syncDir(s.options.Dir)
+
+	s.entries = append(s.entries, spoolEntry{seq: seq, size: int64(len(data))})
+	s.size += int64(len(data))
+	s.lastSeq = seq
+	s.evict(now)
+	return nil
+}
+
+// Replay 按写入顺序补发缓冲中的数据，每次发送最多batchSize条，发送成功的数据从缓冲中删除
+// 服务端拒绝的数据(400/413)重发也不会成功，直接丢弃并继续补发；整批被拒绝时逐条重发以找出被拒绝的数据
+// 遇到其他错误时停止并返回错误，剩余数据留到下次补发；返回成功补发的条数
+func (s *Spool) Replay(batchSize int, send func(samples [][]byte) error) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	replayed, processed := 0, 0
+	for processed < s.options.ReplayBatch {
+		s.mu.Lock()
+		s.evict(time.Now())
+		n := len(s.entries)
+		if n > batchSize {
+			n = batchSize
+		}
+		if n > s.options.ReplayBatch-processed {
+			n = s.options.ReplayBatch - processed
+		}
+		batch := append([]spoolEntry(nil), s.entries[:n]...)
+		s.mu.Unlock()
+		if len(batch) == 0 {
+			return replayed, nil
+		}
+		processed += len(batch)
+
+		// 发送时不持有锁，补发期间仍可写入新的数据
+		entries := make([]spoolEntry, 0, len(batch))
+		samples := make([][]byte, 0, len(batch))
+		for _, entry := range batch {
+			data, err := os.ReadFile(s.path(entry.seq))
+			if err != nil {
+				log.Printf("读取缓冲文件失败，丢弃该条数据: %v", err)
+				s.remove(entry.seq)
+				continue
+			}
+			entries = append(entries, entry)
+			samples = append(samples, data)
+		}
+		if len(samples) == 0 {
+			continue
+		}
+
+		sent, err := s.sendEntries(entries, samples, send)
+		replayed += sent
+		if err != nil {
+			return replayed, err
+		}
+	}
+	return replayed, nil
+}
+
+// sendEntries 发送一组缓冲数据并删除已发送或被服务端拒绝的数据，返回成功发送的条数
+func (s *Spool) sendEntries(entries []spoolEntry, samples [][]byte, send func(samples [][]byte) error) (int, error) {
+	err := send(samples)
+	if err == nil {
+		for _, entry := range entries {
+			s.remove(entry.seq)
+		}
+		return len(samples), nil
+	}
+	if !isPayloadRejected(err) {
+		return 0, err
+	}
+
+	if len(samples) == 1 {
+		log.Printf("服务端拒绝了缓冲中的数据，丢弃该条数据: %v", err)
+		s.remove(entries[0].seq)
+		return 0, nil
+	}
+
+	sent := 0
+	for i := range samples {
+		n, err := s.sendEntries(entries[i:i+1], samples[i:i+1], send)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// Len 返回缓冲中的数据条数
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Size 返回缓冲中数据的总大小(字节)
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// remove 删除指定的数据，数据已被淘汰时忽略
+func (s *Spool) remove(seq int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, entry := range s.entries {
+		if entry.seq == seq {
+			s.drop(i)
+			return
+		}
+	}
+}
+
+// evict 丢弃超过保留时间的数据，并在超出容量时从最旧的数据开始丢弃，调用方需持有锁
+func (s *Spool) evict(now time.Time) {
+	expired := now.Add(-s.options.MaxAge).UnixNano()
+	dropped := 0
+	for len(s.entries) > 0 && (s.entries[0].seq < expired || s.size > s.options.MaxBytes) {
+		s.drop(0)
+		dropped++
+	}
+	if dropped > 0 {
+		log.Printf("本地缓冲超出容量或保留时间，已丢弃最旧的 %d 条数据", dropped)
+	}
+}
+
+// drop 删除第i条数据及其文件，调用方需持有锁
+func (s *Spool) drop(i int) {
+	entry := s.entries[i]
+	if err := os.Remove(s.path(entry.seq)); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除缓冲文件失败: %v", err)
+	}
+	s.entries = append(s.entries[:i], s.entries[i+1:]...)
+	s.size -= entry.size
+}
+
+// path 返回数据对应的文件路径，固定宽度的文件名保证按名称排序即为写入顺序
+func (s *Spool) path(seq int64) string {
+	return filepath.Join(s.options.Dir, fmt.Sprintf("%019d%s", seq, spoolFileSuffix))
+}
+
+// writeFileSync 写入文件并在关闭前fsync
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir fsync目录，确保重命名操作已落盘
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,416 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/syslens/syslens-api/internal/common/utils"
+	"github.com/syslens/syslens-api/internal/config"
+)
+
+const (
+	// 默认的ping间隔
+	defaultPingInterval = 20 * time.Second
+	// 默认的单条消息写入超时
+	defaultWriteTimeout = 10 * time.Second
+	// 指标消息默认的确认超时
+	defaultAckTimeout = 10 * time.Second
+	// 重连等待的初始上限和最大值
+	defaultReconnectInterval    = time.Second
+	defaultMaxReconnectInterval = time.Minute
+)
+
+var (
+	// ErrNotConnected WebSocket连接不可用，数据需要通过HTTP上报
+	ErrNotConnected = errors.New("WebSocket连接不可用")
+	// errConnectionLost 等待确认期间连接断开
+	errConnectionLost = errors.New("WebSocket连接已断开")
+)
+
+// WebSocketOptions WebSocket通道选项
+type WebSocketOptions struct {
+	// 节点令牌，建立连接时通过Authorization头部发送
+	AuthToken string
+	// 安全配置，启用加密或压缩时消息以二进制消息发送
+	Security *config.SecurityConfig
+	// ping间隔，超过两个间隔未收到pong时断开重连
+	PingInterval time.Duration
+	// 单条消息的写入超时
+	WriteTimeout time.Duration
+	// 指标消息的确认超时，超时未确认时改用HTTP上报
+	AckTimeout time.Duration
+	// 重连等待的最大值，重连间隔为指数退避加全抖动
+	MaxReconnectInterval time.Duration
+	// 收到主控端下发的节点配置时调用，连接建立后会先请求一次当前配置
+	OnConfig func(nodeConfig map[string]interface{})
+}
+
+// WebSocketChannel 与主控端之间的长连接，通过WithWebSocket交给HTTPReporter使用
+// 连接可用时指标数据优先通过该通道发送，同一通道接收配置推送；连接断开后自动重连
+type WebSocketChannel struct {
+	url           string
+	options       WebSocketOptions
+	dialer        *websocket.Dialer
+	encryptionSvc *utils.EncryptionService
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	nextID  uint64
+	pending map[uint64]chan error // 等待确认的指标消息，键为消息序号
+	writeMu sync.Mutex            // 保证同一时间只有一个写入
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewWebSocketChannel 创建WebSocket通道并在后台建立连接
+// serverURL为主控服务器地址(http或https)，连接路径为/api/v1/ws/nodes
+func NewWebSocketChannel(serverURL, nodeID string, opts WebSocketOptions) (*WebSocketChannel, error) {
+	wsURL, err := webSocketURL(serverURL, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = defaultPingInterval
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = defaultWriteTimeout
+	}
+	if opts.AckTimeout <= 0 {
+		opts.AckTimeout = defaultAckTimeout
+	}
+	if opts.MaxReconnectInterval <= 0 {
+		opts.MaxReconnectInterval = defaultMaxReconnectInterval
+	}
+	if opts.Security == nil {
+		opts.Security = &config.SecurityConfig{}
+	}
+
+	c := &WebSocketChannel{
+		url:     wsURL,
+		options: opts,
+		dialer: &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			HandshakeTimeout: opts.WriteTimeout,
+		},
+		pending: make(map[uint64]chan error),
+		stop:    make(chan struct{}),
+	}
+	if opts.Security.Encryption.Enabled {
+		c.encryptionSvc = utils.NewEncryptionService(opts.Security.Encryption.Algorithm)
+	}
+
+	c.wg.Add(1)
+	go c.run()
+	return c, nil
+}
+
+// WithWebSocket 启用WebSocket通道，连接可用时优先通过通道上报，不可用或未被确认时改用HTTP上报
+// 通道作为最高优先级的上报目标，批量上报、本地缓冲补发和熔断同样适用于通道
+func WithWebSocket(ws *WebSocketChannel) func(*HTTPReporter) {
+	return func(r *HTTPReporter) {
+		r.ws = ws
+	}
+}
+
+// webSocketURL 将主控服务器地址转换为WebSocket地址
+func webSocketURL(serverURL, nodeID string) (string, error) {
+	u, err := url.Parse(strings.TrimRight(serverURL, "/"))
+	if err != nil {
+		return "", fmt.Errorf("解析服务器地址失败: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "ws":
+		u.Scheme = "ws"
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("不支持的服务器地址: %s", serverURL)
+	}
+	u.Path += "/api/v1/ws/nodes"
+	u.RawQuery = url.Values{"node_id": {nodeID}}.Encode()
+	return u.String(), nil
+}
+
+// send 发送一条指标消息并等待主控端确认
+// 连接不可用时返回ErrNotConnected；发送失败、确认超时或主控端未能写入时返回错误
+func (c *WebSocketChannel) send(jsonData []byte, batch, replayed bool) error {
+	c.mu.Lock()
+	conn := c.conn
+	if conn == nil {
+		c.mu.Unlock()
+		return ErrNotConnected
+	}
+	c.nextID++
+	id := c.nextID
+	ack := make(chan error, 1)
+	c.pending[id] = ack
+	c.mu.Unlock()
+
+	msg := utils.WebSocketMessage{
+		Type:     utils.WebSocketMessageMetrics,
+		ID:       id,
+		Batch:    batch,
+		Replayed: replayed,
+		Data:     jsonData,
+	}
+	if !batch {
+		sentAt := time.Now()
+		msg.SentAt = &sentAt
+	}
+	if err := c.write(conn, msg); err != nil {
+		c.takePending(id)
+		conn.Close()
+		return fmt.Errorf("WebSocket发送失败: %w", err)
+	}
+
+	timer := time.NewTimer(c.options.AckTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-ack:
+		return err
+	case <-timer.C:
+		c.takePending(id)
+		return fmt.Errorf("主控端 %v 内未确认指标数据", c.options.AckTimeout)
+	}
+}
+
+// Connected 返回当前是否已建立连接
+func (c *WebSocketChannel) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+// Close 断开连接并停止重连，等待确认的消息以errConnectionLost结束
+// 应在HTTPReporter关闭之后调用，以便队列中剩余的数据仍可通过通道发送
+func (c *WebSocketChannel) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() { close(c.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待WebSocket连接关闭超时: %w", ctx.Err())
+	}
+}
+
+// run 维持连接，断开后按指数退避重连，直到通道关闭
+func (c *WebSocketChannel) run() {
+	defer c.wg.Done()
+
+	attempt := 0
+	for {
+		conn, err := c.dial()
+		if err != nil {
+			attempt++
+			delay := jitterBackoff(attempt, defaultReconnectInterval, c.options.MaxReconnectInterval)
+			log.Printf("连接主控端WebSocket失败，%v 后重连: %v", delay, err)
+			timer := time.NewTimer(delay)
+			select {
+			case <-c.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+
+		attempt = 0
+		log.Printf("已建立到主控端的WebSocket连接: %s", c.url)
+		c.serve(conn)
+
+		select {
+		case <-c.stop:
+			return
+		default:
+			log.Printf("WebSocket连接已断开，正在重连...")
+		}
+	}
+}
+
+// dial 建立连接
+func (c *WebSocketChannel) dial() (*websocket.Conn, error) {
+	header := http.Header{}
+	header.Set("User-Agent", "SysLens-Agent")
+	if c.options.AuthToken != "" {
+		header.Set("Authorization", "Bearer "+c.options.AuthToken)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	conn, resp, err := c.dialer.DialContext(ctx, c.url, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%w (状态码: %d)", err, resp.StatusCode)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// serve 处理一个连接上的消息，连接断开后返回
+func (c *WebSocketChannel) serve(conn *websocket.Conn) {
+	pongWait := 2*c.options.PingInterval + c.options.WriteTimeout
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	keepaliveDone := make(chan struct{})
+	go func() {
+		defer close(keepaliveDone)
+		c.keepalive(conn, done)
+	}()
+
+	// 连接建立后请求当前的节点配置
+	if err := c.write(conn, utils.WebSocketMessage{Type: utils.WebSocketMessageCommand, Command: utils.WebSocketCommandGetConfig}); err != nil {
+		log.Printf("请求节点配置失败: %v", err)
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("读取WebSocket消息失败: %v", err)
+			}
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.handleMessage(data)
+	}
+
+	close(done)
+	<-keepaliveDone
+	conn.Close()
+
+	// 连接断开后未确认的消息改用HTTP上报
+	c.mu.Lock()
+	c.conn = nil
+	pending := c.pending
+	c.pending = make(map[uint64]chan error)
+	c.mu.Unlock()
+	for _, ack := range pending {
+		ack <- errConnectionLost
+	}
+}
+
+// keepalive 定期发送ping，通道关闭时发送关闭帧
+func (c *WebSocketChannel) keepalive(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(c.options.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-c.stop:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "节点代理退出"),
+				time.Now().Add(c.options.WriteTimeout))
+			conn.Close()
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.options.WriteTimeout)); err != nil {
+				log.Printf("发送WebSocket ping失败: %v", err)
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// handleMessage 处理主控端发送的消息
+func (c *WebSocketChannel) handleMessage(data []byte) {
+	var msg utils.WebSocketMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("解析WebSocket消息失败: %v", err)
+		return
+	}
+
+	switch msg.Type {
+	case utils.WebSocketMessageAck:
+		ack, ok := c.takePending(msg.ID)
+		if !ok {
+			return
+		}
+		if msg.Error != "" {
+			ack <- fmt.Errorf("主控端未能写入指标数据: %s", msg.Error)
+			return
+		}
+		ack <- nil
+	case utils.WebSocketMessageConfig:
+		var nodeConfig map[string]interface{}
+		if err := json.Unmarshal(msg.Data, &nodeConfig); err != nil {
+			log.Printf("解析主控端下发的配置失败: %v", err)
+			return
+		}
+		log.Printf("收到主控端下发的节点配置，版本: %d", msg.Version)
+		if c.options.OnConfig != nil {
+			c.options.OnConfig(nodeConfig)
+		}
+	default:
+		log.Printf("未知的WebSocket消息类型: %s", msg.Type)
+	}
+}
+
+// write 发送一条消息，启用加密或压缩时以二进制消息发送
+func (c *WebSocketChannel) write(conn *websocket.Conn, msg utils.WebSocketMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	messageType := websocket.TextMessage
+	if c.options.Security.Encryption.Enabled || c.options.Security.Compression.Enabled {
+		data, _, err = processPayload(data, c.options.Security, c.encryptionSvc)
+		if err != nil {
+			return fmt.Errorf("数据处理失败: %w", err)
+		}
+		messageType = websocket.BinaryMessage
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(c.options.WriteTimeout))
+	return conn.WriteMessage(messageType, data)
+}
+
+// takePending 取出等待确认的消息
+func (c *WebSocketChannel) takePending(id uint64) (chan error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ack, ok := c.pending[id]
+	delete(c.pending, id)
+	return ack, ok
+}
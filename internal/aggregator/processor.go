@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/syslens/syslens-api/internal/common/utils"
 	"github.com/syslens/syslens-api/internal/config"
 	"go.uber.org/zap"
 )
@@ -129,7 +130,7 @@ func (p *DataProcessor) processMetricsData() {
 		metrics["processed_at"] = time.Now().Unix()
 
 		// 转发数据到主控平面 - 这里应该调用controlPlaneClient的方法
-		if err := p.forwardMetricsToControlPlane(nodeID, metrics); err != nil {
+		if err := p.forwardMetricsToControlPlane(nodeID, metrics, false); err != nil {
 			p.logger.Error("转发指标数据到主控平面失败",
 				zap.String("node_id", nodeID),
 				zap.Error(err))
@@ -140,17 +141,40 @@ func (p *DataProcessor) processMetricsData() {
 	}
 }
 
-// forwardMetricsToControlPlane 将指标数据转发到主控平面
-func (p *DataProcessor) forwardMetricsToControlPlane(nodeID string, metrics map[string]interface{}) error {
-	// 创建一个子上下文，设置5秒超时
-	ctx, cancel := context.WithTimeout(p.ctx, 5*time.Second)
-	defer cancel()
+// ForwardReplayedMetrics 立即转发代理补发的历史数据
+// 补发的数据时间戳早于缓存中的最新数据，不能进入缓存，否则会覆盖最新数据
+func (p *DataProcessor) ForwardReplayedMetrics(nodeID string, metrics map[string]interface{}) error {
+	metrics["processed_at"] = time.Now().Unix()
+	return p.forwardMetricsToControlPlane(nodeID, metrics, true)
+}
 
-	// 构建请求URL
-	url := fmt.Sprintf("%s/api/v1/nodes/%s/metrics", p.config.ControlPlane.URL, nodeID)
+// ForwardMetricsBatch 立即将一批样本转发到主控平面，保留代理的发送时间以便主控端计算时钟偏差
+func (p *DataProcessor) ForwardMetricsBatch(nodeID string, sentAt time.Time, samples []map[string]interface{}, delta, replayed bool) error {
+	processedAt := time.Now().Unix()
+	rawSamples := make([][]byte, 0, len(samples))
+	for _, sample := range samples {
+		sample["processed_at"] = processedAt
+		raw, err := json.Marshal(sample)
+		if err != nil {
+			return fmt.Errorf("序列化指标数据失败: %v", err)
+		}
+		rawSamples = append(rawSamples, raw)
+	}
+
+	body, err := utils.EncodeMetricsBatch(rawSamples, delta, sentAt)
+	if err != nil {
+		return fmt.Errorf("编码批量数据失败: %v", err)
+	}
+	p.logger.Info("准备向主控平面转发批量指标数据",
+		zap.String("node_id", nodeID),
+		zap.Int("samples", len(samples)))
+	return p.sendToControlPlane(nodeID, body, true, replayed)
+}
+
+// forwardMetricsToControlPlane 将指标数据转发到主控平面，replayed标记补发的历史数据
+func (p *DataProcessor) forwardMetricsToControlPlane(nodeID string, metrics map[string]interface{}, replayed bool) error {
 	p.logger.Info("准备向主控平面转发指标数据",
 		zap.String("node_id", nodeID),
-		zap.String("url", url),
 		zap.Int("metrics_count", len(metrics)))
 
 	// 构建请求体
@@ -165,6 +189,21 @@ func (p *DataProcessor) forwardMetricsToControlPlane(nodeID string, metrics map[
 		zap.String("node_id", nodeID),
 		zap.Int("body_size_bytes", len(body)))
 
+	return p.sendToControlPlane(nodeID, body, false, replayed)
+}
+
+// sendToControlPlane 向主控平面发送指标数据，batch标记批量数据
+func (p *DataProcessor) sendToControlPlane(nodeID string, body []byte, batch, replayed bool) error {
+	// 创建一个子上下文，设置5秒超时
+	ctx, cancel := context.WithTimeout(p.ctx, 5*time.Second)
+	defer cancel()
+
+	// 构建请求URL
+	url := fmt.Sprintf("%s/api/v1/nodes/%s/metrics", p.config.ControlPlane.URL, nodeID)
+	p.logger.Debug("向主控平面发送请求",
+		zap.String("node_id", nodeID),
+		zap.String("url", url))
+
 	// 创建请求
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
@@ -179,6 +218,12 @@ func (p *DataProcessor) forwardMetricsToControlPlane(nodeID string, metrics map[
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.ControlPlane.Token))
 	req.Header.Set("X-Node-ID", nodeID)
 	req.Header.Set("X-Aggregator-ID", "aggregator-1") // 可以设置聚合服务器的ID
+	if batch {
+		req.Header.Set("X-Batch", "true")
+	}
+	if replayed {
+		req.Header.Set("X-Replayed", "true")
+	}
 	p.logger.Debug("HTTP请求头设置完成",
 		zap.String("node_id", nodeID),
 		zap.Strings("headers", []string{
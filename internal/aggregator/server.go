@@ -69,6 +69,9 @@ type NodeConnection struct {
 
 	// 节点是否已通过验证
 	Verified bool
+
+	// 注册时上报的硬件清单
+	Inventory json.RawMessage
 }
 
 // NewServer 创建新的聚合服务器
@@ -410,6 +413,15 @@ func (s *Server) handleNodeMetrics(c *gin.Context) {
 		return
 	}
 
+	// 代理补发的历史数据
+	replayed := c.GetHeader("X-Replayed") == "true"
+
+	// 批量数据包含多个样本，全部校验通过后才转发
+	if c.GetHeader("X-Batch") == "true" {
+		s.handleNodeMetricsBatch(c, nodeID, processedData, replayed)
+		return
+	}
+
 	// 解析处理后的数据
 	var metrics map[string]interface{}
 	if err := json.Unmarshal(processedData, &metrics); err != nil {
@@ -422,8 +434,30 @@ func (s *Server) handleNodeMetrics(c *gin.Context) {
 		zap.String("node_id", nodeID),
 		zap.Int("metrics_size", len(metrics)))
 
+	// 硬件发生变化时指标中会附带新的硬件清单，补发的旧清单不覆盖当前清单
+	if inventory, ok := metrics["inventory"]; ok && inventory != nil && !replayed {
+		if raw, err := json.Marshal(inventory); err == nil {
+			s.updateNodeInventory(nodeID, raw)
+		}
+	}
+
 	// 添加接收时间戳 (Aggregator接收时间)
 	metrics["aggregator_received_at"] = time.Now().Unix()
+	// 保留代理的发送时间，主控端据此扣除样本在代理中缓冲的时间
+	if sentAt := c.GetHeader("X-Sent-At"); sentAt != "" {
+		metrics["agent_sent_at"] = sentAt
+	}
+
+	// 补发的数据直接转发，不进入只保留最新数据的缓存；转发失败时由代理保留并稍后重试
+	if replayed {
+		if err := s.processor.ForwardReplayedMetrics(nodeID, metrics); err != nil {
+			s.logger.Warn("转发补发的指标数据失败", zap.String("node_id", nodeID), zap.Error(err))
+			c.JSON(http.StatusBadGateway, gin.H{"error": "转发补发数据失败: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		return
+	}
 
 	// 将指标数据传递给处理器
 	s.processor.ProcessMetrics(nodeID, metrics)
@@ -432,6 +466,45 @@ func (s *Server) handleNodeMetrics(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// handleNodeMetricsBatch 处理批量上报的数据，任一样本校验失败时整批拒绝
+// 批量数据直接转发到主控端，不进入只保留最新数据的缓存；转发失败时由代理保留并稍后重试
+func (s *Server) handleNodeMetricsBatch(c *gin.Context, nodeID string, data []byte, replayed bool) {
+	batch, samples, sampleErrors, err := utils.DecodeMetricsBatch(data)
+	if err != nil {
+		s.logger.Error("解析批量数据失败", zap.String("node_id", nodeID), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(sampleErrors) > 0 {
+		s.logger.Warn("批量数据校验失败，整批未转发",
+			zap.String("node_id", nodeID),
+			zap.Int("samples", len(samples)),
+			zap.Int("invalid", len(sampleErrors)))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   fmt.Sprintf("%d个样本中有%d个无效，整批未写入", len(samples), len(sampleErrors)),
+			"details": sampleErrors,
+		})
+		return
+	}
+
+	receivedAt := time.Now().Unix()
+	for _, sample := range samples {
+		if inventory, ok := sample["inventory"]; ok && inventory != nil && !replayed {
+			if raw, err := json.Marshal(inventory); err == nil {
+				s.updateNodeInventory(nodeID, raw)
+			}
+		}
+		sample["aggregator_received_at"] = receivedAt
+	}
+
+	if err := s.processor.ForwardMetricsBatch(nodeID, batch.SentAt, samples, batch.Delta, replayed); err != nil {
+		s.logger.Warn("转发批量指标数据失败", zap.String("node_id", nodeID), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "转发批量数据失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "samples": len(samples)})
+}
+
 // processIncomingData 处理来自Agent的数据：解密和解压缩
 func (s *Server) processIncomingData(data []byte, isEncrypted, isCompressed bool) ([]byte, error) {
 	processedData := data
@@ -472,8 +545,9 @@ func (s *Server) processIncomingData(data []byte, isEncrypted, isCompressed bool
 func (s *Server) handleNodeRegister(c *gin.Context) {
 	// 解析请求体
 	var req struct {
-		NodeID string `json:"node_id" binding:"required"`
-		Token  string `json:"token" binding:"required"`
+		NodeID    string          `json:"node_id" binding:"required"`
+		Token     string          `json:"token" binding:"required"`
+		Inventory json.RawMessage `json:"inventory"` // 可选的硬件清单
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -500,6 +574,9 @@ func (s *Server) handleNodeRegister(c *gin.Context) {
 
 	// 注册节点到聚合服务器内部管理
 	s.registerOrUpdateNode(req.NodeID, true) // 标记为已验证
+	if len(req.Inventory) > 0 {
+		s.updateNodeInventory(req.NodeID, req.Inventory)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "ok",
@@ -538,6 +615,7 @@ func (s *Server) handleGetNodes(c *gin.Context) {
 			"verified":     conn.Verified,
 			"connected_at": conn.ConnectedAt.Format(time.RFC3339),
 			"last_active":  conn.LastActive.Format(time.RFC3339),
+			"inventory":    conn.Inventory,
 		})
 	}
 
@@ -579,6 +657,17 @@ func (s *Server) registerOrUpdateNode(nodeID string, verified bool) {
 	}
 }
 
+// updateNodeInventory 更新节点的硬件清单
+func (s *Server) updateNodeInventory(nodeID string, inventory json.RawMessage) {
+	s.connections.Lock()
+	defer s.connections.Unlock()
+
+	if conn, ok := s.connections.nodes[nodeID]; ok {
+		conn.Inventory = inventory
+		s.logger.Info("节点硬件清单已更新", zap.String("node_id", nodeID))
+	}
+}
+
 // updateNodeActivity 更新节点活动时间
 // 返回值表示节点是否存在
 func (s *Server) updateNodeActivity(nodeID string) bool {
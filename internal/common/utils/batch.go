@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// MetricsBatch 批量上报的数据格式，包含按采集时间排列的多个样本
+type MetricsBatch struct {
+	// 代理发送批次的时间，服务端据此计算时钟偏差
+	SentAt time.Time `json:"sent_at"`
+	// 为true时第一个样本为完整数据，之后的样本为相对前一个样本的JSON合并补丁(RFC 7386)
+	Delta bool `json:"delta,omitempty"`
+	// 样本列表
+	Samples []json.RawMessage `json:"samples"`
+}
+
+// BatchSampleError 批量数据中单个样本的校验错误
+type BatchSampleError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// EncodeMetricsBatch 将多个样本编码为批量数据，delta为true时对第一个之后的样本做增量编码
+func EncodeMetricsBatch(samples [][]byte, delta bool, sentAt time.Time) ([]byte, error) {
+	batch := MetricsBatch{
+		SentAt:  sentAt,
+		Delta:   delta,
+		Samples: make([]json.RawMessage, 0, len(samples)),
+	}
+
+	var prev map[string]interface{}
+	for i, sample := range samples {
+		if !delta {
+			batch.Samples = append(batch.Samples, sample)
+			continue
+		}
+
+		var current map[string]interface{}
+		if err := json.Unmarshal(sample, &current); err != nil {
+			return nil, fmt.Errorf("解析第%d个样本失败: %w", i, err)
+		}
+		if prev == nil {
+			batch.Samples = append(batch.Samples, sample)
+		} else {
+			patch, err := json.Marshal(CreateMergePatch(prev, current))
+			if err != nil {
+				return nil, fmt.Errorf("编码第%d个样本失败: %w", i, err)
+			}
+			batch.Samples = append(batch.Samples, patch)
+		}
+		prev = current
+	}
+
+	return json.Marshal(batch)
+}
+
+// DecodeMetricsBatch 解析批量数据并还原增量编码的样本
+// 批量数据本身无法解析时返回错误；单个样本的错误在sampleErrors中返回，对应位置的样本为nil
+func DecodeMetricsBatch(data []byte) (*MetricsBatch, []map[string]interface{}, []BatchSampleError, error) {
+	var batch MetricsBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, nil, nil, fmt.Errorf("解析批量数据失败: %w", err)
+	}
+	if len(batch.Samples) == 0 {
+		return nil, nil, nil, errors.New("批量数据中没有样本")
+	}
+
+	samples := make([]map[string]interface{}, len(batch.Samples))
+	var sampleErrors []BatchSampleError
+	var prev map[string]interface{}
+	for i, raw := range batch.Samples {
+		var sample map[string]interface{}
+		if err := json.Unmarshal(raw, &sample); err != nil || sample == nil {
+			sampleErrors = append(sampleErrors, BatchSampleError{Index: i, Error: "样本不是有效的JSON对象"})
+			prev = nil
+			continue
+		}
+
+		if batch.Delta && i > 0 {
+			if prev == nil {
+				// 基准样本无效，无法还原之后的增量样本
+				sampleErrors = append(sampleErrors, BatchSampleError{Index: i, Error: "增量样本的基准样本无效"})
+				continue
+			}
+			sample = ApplyMergePatch(prev, sample)
+		}
+
+		if err := validateMetricsSample(sample); err != nil {
+			sampleErrors = append(sampleErrors, BatchSampleError{Index: i, Error: err.Error()})
+		} else {
+			samples[i] = sample
+		}
+		prev = sample
+	}
+
+	return &batch, samples, sampleErrors, nil
+}
+
+// validateMetricsSample 检查样本包含有效的采集时间戳
+func validateMetricsSample(sample map[string]interface{}) error {
+	ts, ok := sample["timestamp"].(string)
+	if !ok {
+		return errors.New("缺少timestamp字段")
+	}
+	if _, err := time.Parse(time.RFC3339Nano, ts); err != nil {
+		return fmt.Errorf("timestamp格式无效: %q", ts)
+	}
+	return nil
+}
+
+// CreateMergePatch 生成从prev变为current的JSON合并补丁
+// 删除的键以null表示，对象递归比较，其他类型的值（包括数组）变化时整体替换
+func CreateMergePatch(prev, current map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for key, value := range current {
+		old, exists := prev[key]
+		if !exists {
+			patch[key] = value
+			continue
+		}
+		oldMap, oldIsMap := old.(map[string]interface{})
+		newMap, newIsMap := value.(map[string]interface{})
+		if oldIsMap && newIsMap {
+			if sub := CreateMergePatch(oldMap, newMap); len(sub) > 0 {
+				patch[key] = sub
+			}
+			continue
+		}
+		if !reflect.DeepEqual(old, value) {
+			patch[key] = value
+		}
+	}
+	for key := range prev {
+		if _, exists := current[key]; !exists {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
+
+// ApplyMergePatch 将JSON合并补丁应用到base上并返回新的对象，不修改base
+func ApplyMergePatch(base, patch map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(base))
+	for key, value := range base {
+		result[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		patchMap, patchIsMap := value.(map[string]interface{})
+		baseMap, baseIsMap := result[key].(map[string]interface{})
+		if patchIsMap && baseIsMap {
+			result[key] = ApplyMergePatch(baseMap, patchMap)
+		} else if patchIsMap {
+			result[key] = ApplyMergePatch(map[string]interface{}{}, patchMap)
+		} else {
+			result[key] = value
+		}
+	}
+	return result
+}
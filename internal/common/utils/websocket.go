@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebSocket通道的消息类型
+const (
+	// WebSocketMessageMetrics 代理上报的指标数据
+	WebSocketMessageMetrics = "metrics"
+	// WebSocketMessageAck 主控端对指标消息的确认，Error不为空表示未写入
+	WebSocketMessageAck = "ack"
+	// WebSocketMessageCommand 代理发送的命令，如get_config
+	WebSocketMessageCommand = "command"
+	// WebSocketMessageConfig 主控端下发的节点配置
+	WebSocketMessageConfig = "config"
+
+	// WebSocketCommandGetConfig 请求主控端下发当前的节点配置
+	WebSocketCommandGetConfig = "get_config"
+)
+
+// WebSocketMessage 代理与主控端之间WebSocket通道的消息
+// 代理启用加密或压缩时，发送的消息整体经过处理后以二进制消息发送
+type WebSocketMessage struct {
+	Type string `json:"type"`
+	// 代理为每条指标消息分配的序号，主控端在确认中原样返回
+	ID uint64 `json:"id,omitempty"`
+	// 为true时data为批量数据(MetricsBatch)
+	Batch bool `json:"batch,omitempty"`
+	// 为true时data为本地缓冲中补发的历史数据
+	Replayed bool `json:"replayed,omitempty"`
+	// 单条指标的发送时间，主控端据此计算样本在代理中缓冲的时间；批量数据在data的sent_at中携带
+	SentAt *time.Time `json:"sent_at,omitempty"`
+	// 命令名称，仅command消息使用
+	Command string `json:"command,omitempty"`
+	// 指标数据或节点配置
+	Data json.RawMessage `json:"data,omitempty"`
+	// 配置版本，仅config消息使用
+	Version int64 `json:"version,omitempty"`
+	// 写入失败的原因，仅ack消息使用
+	Error string `json:"error,omitempty"`
+	// 批量数据中各样本的校验错误，仅ack消息使用
+	Details []BatchSampleError `json:"details,omitempty"`
+}
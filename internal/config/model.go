@@ -8,6 +8,8 @@ type AgentConfig struct {
 	Collection CollectionConfig      `yaml:"collection"`
 	Logging    LoggingConfig         `yaml:"logging"`
 	Aggregator AgentAggregatorConfig `yaml:"aggregator"`
+	Spool      SpoolConfig           `yaml:"spool"`
+	Batch      BatchConfig           `yaml:"batch"`
 }
 
 // NodeConfig 节点信息配置
@@ -18,12 +20,40 @@ type NodeConfig struct {
 
 // ServerConnection 服务器连接配置
 type ServerConnection struct {
-	URL           string `yaml:"url"`
-	TLSVerify     bool   `yaml:"tls_verify"`
-	Token         string `yaml:"token"`
-	Timeout       int    `yaml:"timeout"`
-	RetryCount    int    `yaml:"retry_count"`
-	RetryInterval int    `yaml:"retry_interval"`
+	URL string `yaml:"url"`
+	// 按优先级排列的主控服务器地址，配置后替代url
+	URLs          []string `yaml:"urls"`
+	TLSVerify     bool     `yaml:"tls_verify"`
+	Token         string   `yaml:"token"`
+	Timeout       int      `yaml:"timeout"`
+	RetryCount    int      `yaml:"retry_count"`
+	RetryInterval int      `yaml:"retry_interval"`
+	// 重试退避的最大值(秒)，重试间隔从retry_interval开始每次翻倍直到该值
+	MaxRetryInterval int `yaml:"max_retry_interval"`
+	// 异步上报队列长度
+	QueueSize int `yaml:"queue_size"`
+	// 上报熔断配置
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+	// 不可用的上报目标的健康检查间隔(秒)，检查通过后切回更高优先级的目标
+	HealthCheckInterval int `yaml:"health_check_interval"`
+	// WebSocket上报通道配置
+	WebSocket WebSocketConfig `yaml:"websocket"`
+}
+
+// WebSocketConfig 节点与主控端之间的WebSocket上报通道配置
+type WebSocketConfig struct {
+	// 是否通过WebSocket长连接上报并接收配置推送，连接不可用时使用HTTP上报
+	Enabled bool `yaml:"enabled"`
+	// ping间隔(秒)
+	PingInterval int `yaml:"ping_interval"`
+}
+
+// CircuitBreakerConfig 上报熔断配置
+type CircuitBreakerConfig struct {
+	// 断开前允许的连续失败次数(0表示不熔断)
+	FailureThreshold int `yaml:"failure_threshold"`
+	// 断开后的冷却时间(秒)
+	Cooldown int `yaml:"cooldown"`
 }
 
 // SecurityConfig 安全配置
@@ -49,25 +79,156 @@ type CompressionConfig struct {
 // CollectionConfig 采集配置
 type CollectionConfig struct {
 	Interval int              `yaml:"interval"`
+	ProcRoot string           `yaml:"proc_root"`
+	SysRoot  string           `yaml:"sys_root"`
 	Enabled  EnabledCollector `yaml:"enabled"`
 	Disk     DiskConfig       `yaml:"disk"`
 	Network  NetworkConfig    `yaml:"network"`
 	Process  ProcessConfig    `yaml:"process"`
+	Cgroup   CgroupConfig     `yaml:"cgroup"`
+	Systemd  SystemdConfig    `yaml:"systemd"`
+	Hardware HardwareConfig   `yaml:"hardware"`
+	// 各子收集器的运行参数，键为子收集器名称
+	Collectors map[string]CollectorConfig `yaml:"collectors"`
+	// 自定义脚本插件
+	Plugins []PluginConfig `yaml:"plugins"`
+	// Prometheus exporter抓取配置
+	Scrape ScrapeConfig `yaml:"scrape"`
+	// 主动探测，主控端下发的探测与之合并
+	Probes []ProbeConfig `yaml:"probes"`
+	// 日志文件监控配置
+	Logs LogWatchConfig `yaml:"logs"`
+	// 证书过期扫描配置
+	Certificates CertificateConfig `yaml:"certificates"`
+	// 文件完整性监控配置
+	Integrity IntegrityConfig `yaml:"integrity"`
+}
+
+// LogWatchConfig 日志文件监控配置
+type LogWatchConfig struct {
+	StateFile string          `yaml:"state_file"` // 保存读取位置的文件，代理重启后从上次的位置继续读取
+	Files     []LogFileConfig `yaml:"files"`
+}
+
+// LogFileConfig 单个日志文件的监控配置
+type LogFileConfig struct {
+	Name       string             `yaml:"name"`
+	Path       string             `yaml:"path"`
+	Patterns   []LogPatternConfig `yaml:"patterns"`
+	MaxSamples int                `yaml:"max_samples"` // 每次采集最多上报的匹配行样本数，默认5
+	Labels     map[string]string  `yaml:"labels"`
+}
+
+// LogPatternConfig 日志计数模式
+type LogPatternConfig struct {
+	Name  string `yaml:"name"`
+	Regex string `yaml:"regex"`
+}
+
+// CertificateConfig 证书过期扫描配置
+type CertificateConfig struct {
+	Paths     []string `yaml:"paths"`     // 证书文件路径，支持通配符，"**"匹配任意层目录
+	Endpoints []string `yaml:"endpoints"` // TLS端点(host:port)
+	Interval  int      `yaml:"interval"`  // 扫描间隔(毫秒)，默认3600000
+	Timeout   int      `yaml:"timeout"`   // 端点连接超时(毫秒)，默认5000
+}
+
+// IntegrityConfig 文件完整性监控配置
+type IntegrityConfig struct {
+	Paths     []string `yaml:"paths"`      // 需要监控的文件或目录，目录会被递归扫描
+	Interval  int      `yaml:"interval"`   // 扫描间隔(毫秒)，默认300000
+	StateFile string   `yaml:"state_file"` // 保存基线的文件，代理重启后与保存的基线比较
+}
+
+// ProbeConfig 主动探测配置，主控端通过节点配置下发时使用相同的JSON字段
+type ProbeConfig struct {
+	Name     string            `yaml:"name" json:"name"`
+	Type     string            `yaml:"type" json:"type"`         // http、tcp、dns或icmp
+	Target   string            `yaml:"target" json:"target"`     // URL、host:port、域名或主机
+	Interval int               `yaml:"interval" json:"interval"` // 探测间隔(毫秒)，默认30000
+	Timeout  int               `yaml:"timeout" json:"timeout"`   // 探测超时(毫秒)，默认5000
+	Labels   map[string]string `yaml:"labels" json:"labels"`
+
+	// HTTP探测
+	Method         string            `yaml:"method" json:"method"`
+	Headers        map[string]string `yaml:"headers" json:"headers"`
+	ExpectedStatus []int             `yaml:"expected_status" json:"expected_status"` // 为空时2xx和3xx视为成功
+	BodyRegex      string            `yaml:"body_regex" json:"body_regex"`
+	TLSSkipVerify  bool              `yaml:"tls_skip_verify" json:"tls_skip_verify"`
+	TLSExpiryDays  int               `yaml:"tls_expiry_days" json:"tls_expiry_days"` // 证书剩余天数低于该值时探测失败，0表示不检查
+
+	// DNS探测
+	RecordType      string   `yaml:"record_type" json:"record_type"` // A、AAAA、CNAME、MX、TXT或NS
+	DNSServer       string   `yaml:"dns_server" json:"dns_server"`   // 为空时使用系统解析器
+	ExpectedAnswers []string `yaml:"expected_answers" json:"expected_answers"`
+
+	// ICMP探测
+	PingCount int `yaml:"ping_count" json:"ping_count"` // 默认3
+}
+
+// ScrapeConfig Prometheus exporter抓取配置
+type ScrapeConfig struct {
+	Targets        []ScrapeTargetConfig `yaml:"targets"`
+	TextfileDir    string               `yaml:"textfile_dir"`
+	IncludeMetrics []string             `yaml:"include_metrics"`
+	ExcludeMetrics []string             `yaml:"exclude_metrics"`
+	MaxTotalSeries int                  `yaml:"max_total_series"`
+}
+
+// ScrapeTargetConfig 抓取目标配置
+type ScrapeTargetConfig struct {
+	Name      string            `yaml:"name"`
+	URL       string            `yaml:"url"`
+	Interval  int               `yaml:"interval"` // 抓取间隔(毫秒)，默认15000
+	Timeout   int               `yaml:"timeout"`  // 抓取超时(毫秒)，默认5000
+	MaxSeries int               `yaml:"max_series"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+// PluginConfig 自定义脚本插件配置
+type PluginConfig struct {
+	Name     string            `yaml:"name"`
+	Command  string            `yaml:"command"`
+	Args     []string          `yaml:"args"`
+	Format   string            `yaml:"format"`   // nagios、json或prometheus
+	Interval int               `yaml:"interval"` // 执行间隔(毫秒)，默认60000
+	Timeout  int               `yaml:"timeout"`  // 执行超时(毫秒)，默认10000
+	Labels   map[string]string `yaml:"labels"`
+	Env      map[string]string `yaml:"env"` // 插件不继承代理的环境变量，只能看到这里配置的变量
+	Dir      string            `yaml:"dir"`
+}
+
+// CollectorConfig 子收集器配置
+type CollectorConfig struct {
+	Enabled  *bool `yaml:"enabled"`  // 未设置时沿用enabled中的开关
+	Interval int   `yaml:"interval"` // 采集间隔(毫秒)，0表示每个采集周期都执行
+	Timeout  int   `yaml:"timeout"`  // 单次采集超时(毫秒)，0表示使用默认值
 }
 
 // EnabledCollector 启用的采集项
+// CPU、内存、磁盘和网络是基础指标，未设置时默认开启，其余采集项默认关闭
 type EnabledCollector struct {
-	CPU       bool `yaml:"cpu"`
-	Memory    bool `yaml:"memory"`
-	Disk      bool `yaml:"disk"`
-	Network   bool `yaml:"network"`
-	Processes bool `yaml:"processes"`
+	CPU       *bool `yaml:"cpu"`
+	Memory    *bool `yaml:"memory"`
+	Disk      *bool `yaml:"disk"`
+	Network   *bool `yaml:"network"`
+	Processes bool  `yaml:"processes"`
+	Cgroups   bool  `yaml:"cgroups"`
+	Listeners bool  `yaml:"listeners"`
+	Sensors   bool  `yaml:"sensors"`
+	Systemd   bool  `yaml:"systemd"`
+	TimeSync  bool  `yaml:"time_sync"`
 }
 
 // DiskConfig 磁盘采集配置
 type DiskConfig struct {
 	MountPoints     []string `yaml:"mount_points"`
 	IncludeInactive bool     `yaml:"include_inactive"`
+	Devices         []string `yaml:"devices"`
+	IncludeFSTypes  []string `yaml:"include_fstypes"`
+	ExcludeFSTypes  []string `yaml:"exclude_fstypes"`
+	IncludeMounts   []string `yaml:"include_mounts"`
+	ExcludeMounts   []string `yaml:"exclude_mounts"`
 }
 
 // NetworkConfig 网络采集配置
@@ -80,6 +241,27 @@ type ProcessConfig struct {
 	CollectAll      bool     `yaml:"collect_all"`
 	TargetProcesses []string `yaml:"target_processes"`
 	MaxProcesses    int      `yaml:"max_processes"`
+	IncludeArgs     bool     `yaml:"include_args"`
+}
+
+// CgroupConfig cgroup采集配置
+type CgroupConfig struct {
+	Root           string   `yaml:"root"`
+	MaxDepth       int      `yaml:"max_depth"`
+	MaxCgroups     int      `yaml:"max_cgroups"`
+	Include        []string `yaml:"include"`
+	RuntimeSockets []string `yaml:"runtime_sockets"`
+}
+
+// SystemdConfig systemd单元采集配置
+type SystemdConfig struct {
+	Units         []string `yaml:"units"` // 不带后缀时视为.service
+	IncludeFailed bool     `yaml:"include_failed"`
+}
+
+// HardwareConfig 硬件清单采集配置
+type HardwareConfig struct {
+	Interval int `yaml:"interval"` // 刷新间隔(秒)
 }
 
 // LoggingConfig 日志配置
@@ -95,6 +277,8 @@ type AgentAggregatorConfig struct {
 	Enabled bool `yaml:"enabled"`
 	// 聚合服务器地址
 	URL string `yaml:"url"`
+	// 按优先级排列的聚合服务器地址，配置后替代url
+	URLs []string `yaml:"urls"`
 	// 认证令牌
 	AuthToken string `yaml:"auth_token"`
 	// 心跳超时时间(秒)
@@ -109,6 +293,32 @@ type AgentAggregatorConfig struct {
 	Timeout int `yaml:"timeout"`
 }
 
+// SpoolConfig 上报失败数据的本地缓冲配置
+type SpoolConfig struct {
+	// 是否启用本地缓冲，上报失败的数据在恢复后补发
+	Enabled bool `yaml:"enabled"`
+	// 缓冲目录
+	Dir string `yaml:"dir"`
+	// 缓冲数据的总大小上限(MB)
+	MaxSize int `yaml:"max_size"`
+	// 缓冲数据的最长保留时间(秒)
+	MaxAge int `yaml:"max_age"`
+	// 每次上报成功后最多补发的条数
+	ReplayBatch int `yaml:"replay_batch"`
+}
+
+// BatchConfig 批量上报配置
+type BatchConfig struct {
+	// 是否启用批量上报，启用后多次采集的结果合并为一个请求发送
+	Enabled bool `yaml:"enabled"`
+	// 每批最多的样本数
+	MaxSamples int `yaml:"max_samples"`
+	// 样本的最长等待时间(毫秒)
+	MaxDelay int `yaml:"max_delay"`
+	// 是否对样本做增量编码
+	Delta bool `yaml:"delta"`
+}
+
 // ServerConfig 主控端配置结构
 type ServerConfig struct {
 	// 运行环境，可选值: development(dev)或production(prod)
@@ -120,6 +330,7 @@ type ServerConfig struct {
 	Alerting   AlertingConfig         `yaml:"alerting"`
 	Logging    LoggingConfig          `yaml:"logging"`
 	Aggregator AggregatorClientConfig `yaml:"aggregator"`
+	ClockSkew  ClockSkewConfig        `yaml:"clock_skew"`
 }
 
 // HTTPServerConfig HTTP服务器配置
@@ -165,6 +376,12 @@ type DiscoveryConfig struct {
 	AutoRemoveExpired bool `yaml:"auto_remove_expired"`
 }
 
+// ClockSkewConfig 节点时钟偏差检测配置
+type ClockSkewConfig struct {
+	Threshold float64 `yaml:"threshold"` // 偏差阈值(秒)，超过时标记节点，默认5
+	Restamp   bool    `yaml:"restamp"`   // 是否用接收时间替换偏差超过阈值的节点上报的时间戳
+}
+
 // AlertingConfig 告警配置
 type AlertingConfig struct {
 	Enabled       bool            `yaml:"enabled"`
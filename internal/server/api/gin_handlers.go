@@ -1,11 +1,13 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -423,7 +425,7 @@ func (h *MetricsHandler) HandleGetNodeTokenGin(c *gin.Context) {
 // HandleMetricsSubmitGin godoc
 //
 //	@Summary		上报节点指标
-//	@Description	接收并处理节点上报的监控指标数据
+//	@Description	接收并处理节点上报的监控指标数据，支持单个样本和批量样本；批量样本中任一样本无效时整批拒绝，details中返回各样本的错误
 //	@Tags			metrics
 //	@Accept			json
 //	@Produce		json
@@ -431,6 +433,9 @@ func (h *MetricsHandler) HandleGetNodeTokenGin(c *gin.Context) {
 //	@Param			X-Encrypted		header		string	false	"是否加密(true/false)"
 //	@Param			X-Compressed	header		string	false	"压缩格式(gzip)"
 //	@Param			X-Aggregator-ID	header		string	false	"聚合服务器ID"
+//	@Param			X-Replayed		header		string	false	"是否为补发的历史数据(true/false)"
+//	@Param			X-Batch			header		string	false	"是否为批量数据(true/false)，批量数据的格式见utils.MetricsBatch"
+//	@Param			X-Sent-At		header		string	false	"单条指标的发送时间(RFC3339)，计算时钟偏差时扣除样本在节点中缓冲的时间"
 //	@Param			metrics			body		object	true	"指标数据"
 //	@Success		200				{object}	object{message=string,time=string,success=bool}
 //	@Failure		400				{object}	object{error=string,message=string,success=bool}
@@ -488,6 +493,16 @@ func (h *MetricsHandler) HandleMetricsSubmitGin(c *gin.Context) {
 		return
 	}
 
+	// 代理补发的历史数据，时间戳早于接收时间属于正常情况
+	replayed := c.GetHeader("X-Replayed") == "true"
+	receivedTime := time.Now()
+
+	// 批量数据包含多个样本，全部校验通过后才写入
+	if c.GetHeader("X-Batch") == "true" {
+		h.handleMetricsBatch(c, nodeID, processedData, receivedTime, replayed, startProcessing)
+		return
+	}
+
 	// 解析处理后的数据
 	var metricsData map[string]interface{}
 	if err := json.Unmarshal(processedData, &metricsData); err != nil {
@@ -498,12 +513,8 @@ func (h *MetricsHandler) HandleMetricsSubmitGin(c *gin.Context) {
 		return
 	}
 
-	// 添加接收时间戳
-	receivedAt := time.Now().Unix()
-	metricsData["received_at"] = receivedAt
-	h.logger.Debug("添加接收时间戳",
-		zap.String("node_id", nodeID),
-		zap.Int64("timestamp", receivedAt))
+	sentAt := singleSampleSentAt(c.GetHeader("X-Sent-At"), metricsData)
+	h.prepareMetrics(nodeID, metricsData, receivedTime, sampleAge(metricsData, sentAt), replayed)
 
 	// 记录关键指标（如果存在）
 	if cpu, ok := metricsData["cpu"].(map[string]interface{}); ok {
@@ -547,19 +558,92 @@ func (h *MetricsHandler) HandleMetricsSubmitGin(c *gin.Context) {
 	})
 }
 
-// HandleWebSocketGin godoc
+// handleMetricsBatch 处理批量上报的数据，任一样本校验失败时整批拒绝并返回各样本的错误
+func (h *MetricsHandler) handleMetricsBatch(c *gin.Context, nodeID string, data []byte, receivedTime time.Time, replayed bool, startProcessing time.Time) {
+	batch, samples, sampleErrors, err := utils.DecodeMetricsBatch(data)
+	if err != nil {
+		h.logger.Error("批量数据解析失败",
+			zap.String("node_id", nodeID),
+			zap.Error(err))
+		RespondWithError(c, http.StatusBadRequest, err, "解析批量数据失败")
+		return
+	}
+	if len(sampleErrors) > 0 {
+		h.logger.Warn("批量数据校验失败，整批未写入",
+			zap.String("node_id", nodeID),
+			zap.Int("samples", len(samples)),
+			zap.Int("invalid", len(sampleErrors)))
+		RespondWithValidationError(c, fmt.Sprintf("%d个样本中有%d个无效，整批未写入", len(samples), len(sampleErrors)), sampleErrors)
+		return
+	}
+
+	if err := h.storeDecodedBatch(nodeID, batch, samples, receivedTime, replayed); err != nil {
+		RespondWithError(c, http.StatusInternalServerError, err, "存储指标数据失败")
+		return
+	}
+
+	totalTime := time.Since(startProcessing)
+	RespondWithSuccess(c, http.StatusOK, gin.H{
+		"message": "批量指标数据上报成功",
+		"samples": len(samples),
+		"time":    totalTime.String(),
+	})
+}
+
+// HandleGetNodeInventoryGin godoc
+//
+//	@Summary		获取节点硬件清单
+//	@Description	获取节点最近一次上报的硬件清单，包括DMI信息、CPU拓扑、NUMA节点、块设备和网卡
+//	@Tags			nodes
+//	@Accept			json
+//	@Produce		json
+//	@Param			node_id	path		string				true	"节点ID"
+//	@Success		200		{object}	Response{data=object}	"成功"
+//	@Failure		404		{object}	Response			"节点尚未上报硬件清单"
+//	@Router			/api/v1/nodes/{node_id}/inventory [get]
+func (h *MetricsHandler) HandleGetNodeInventoryGin(c *gin.Context) {
+	nodeID := c.Param("node_id")
+	if nodeID == "" {
+		RespondWithError(c, http.StatusBadRequest, nil, "缺少节点ID")
+		return
+	}
+
+	h.inventoryMu.RLock()
+	inventory, ok := h.inventories[nodeID]
+	h.inventoryMu.RUnlock()
+	if !ok {
+		RespondWithError(c, http.StatusNotFound, nil, "节点尚未上报硬件清单")
+		return
+	}
+
+	RespondWithSuccess(c, http.StatusOK, inventory)
+}
+
+// HandleGetClockSkewGin godoc
 //
-//	@Summary		WebSocket连接
-//	@Description	建立WebSocket连接，用于实时监控节点指标
-//	@Tags			websocket
+//	@Summary		获取节点时钟偏差
+//	@Description	获取各节点最近一次上报的时间戳与接收时间的偏差及时间同步状态
+//	@Tags			nodes
 //	@Accept			json
 //	@Produce		json
-//	@Param			node_id	query		string	false	"节点ID"
-//	@Success		200		{object}	object{message=string,success=bool}
-//	@Router			/api/v1/ws/nodes [get]
-func (h *MetricsHandler) HandleWebSocketGin(c *gin.Context) {
-	// WebSocket处理逻辑
-	c.JSON(http.StatusOK, gin.H{"message": "WebSocket端点"})
+//	@Param			skewed	query		bool								false	"只返回偏差超过阈值的节点"
+//	@Success		200		{object}	Response{data=[]ClockSkewStatus}	"成功"
+//	@Router			/api/v1/nodes/clock [get]
+func (h *MetricsHandler) HandleGetClockSkewGin(c *gin.Context) {
+	skewedOnly := c.Query("skewed") == "true"
+
+	h.clockMu.RLock()
+	statuses := make([]ClockSkewStatus, 0, len(h.clockStatuses))
+	for _, status := range h.clockStatuses {
+		if skewedOnly && !status.Skewed {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	h.clockMu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].NodeID < statuses[j].NodeID })
+	RespondWithSuccess(c, http.StatusOK, statuses)
 }
 
 // HandleGetGroupsGin 分组相关处理函数
@@ -682,20 +766,73 @@ func (h *MetricsHandler) HandleGetServicesGin(c *gin.Context) {
 	RespondWithSuccess(c, http.StatusOK, gin.H{"message": "获取服务列表"})
 }
 
+// serviceRequest 创建或更新服务的请求
+type serviceRequest struct {
+	Name            string         `json:"name"`
+	Description     string         `json:"description"`
+	CriticalMetrics map[string]any `json:"critical_metrics"`
+	SystemdUnits    []string       `json:"systemd_units"` // 服务依赖的systemd单元，随节点配置下发给服务节点
+}
+
+// validate 验证服务请求
+func (r *serviceRequest) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("服务名称不能为空")
+	}
+	for _, unit := range r.SystemdUnits {
+		if unit == "" {
+			return fmt.Errorf("systemd单元必须是非空字符串")
+		}
+	}
+	return nil
+}
+
+// apply 将请求内容写入服务实体
+func (r *serviceRequest) apply(service *repository.Service) {
+	service.Name = r.Name
+	service.Description = sql.NullString{String: r.Description, Valid: r.Description != ""}
+	service.CriticalMetrics = r.CriticalMetrics
+	service.SystemdUnits = r.SystemdUnits
+}
+
 // HandleCreateServiceGin godoc
 //
 //	@Summary		创建服务
-//	@Description	创建新的服务
+//	@Description	创建新的服务，systemd_units声明服务依赖的systemd单元，会随节点配置下发给服务节点
 //	@Tags			services
 //	@Accept			json
 //	@Produce		json
-//	@Param			service	body		object	true	"服务信息"
-//	@Success		201		{object}	object{message=string,success=bool}
-//	@Failure		400		{object}	object{error=string,message=string,success=bool}
+//	@Param			service	body		serviceRequest	true	"服务信息"
+//	@Success		201		{object}	Response{data=repository.Service}
+//	@Failure		400		{object}	Response	"请求格式错误"
+//	@Failure		500		{object}	Response	"服务器错误"
 //	@Router			/api/v1/services [post]
 func (h *MetricsHandler) HandleCreateServiceGin(c *gin.Context) {
-	// 创建服务逻辑
-	RespondWithSuccess(c, http.StatusCreated, gin.H{"message": "创建服务成功"})
+	if h.serviceRepo == nil {
+		h.logger.Error("服务仓库未配置")
+		RespondWithError(c, http.StatusInternalServerError, nil, "系统配置错误，服务仓库未初始化")
+		return
+	}
+
+	var req serviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondWithError(c, http.StatusBadRequest, err, "请求格式错误")
+		return
+	}
+	if err := req.validate(); err != nil {
+		RespondWithError(c, http.StatusBadRequest, err, "服务信息验证失败")
+		return
+	}
+
+	service := &repository.Service{ID: fmt.Sprintf("service-%d", time.Now().UnixNano())}
+	req.apply(service)
+	if err := h.serviceRepo.Create(c.Request.Context(), service); err != nil {
+		h.logger.Error("创建服务失败", zap.String("name", req.Name), zap.Error(err))
+		RespondWithError(c, http.StatusInternalServerError, err, "创建服务失败")
+		return
+	}
+
+	RespondWithSuccess(c, http.StatusCreated, service)
 }
 
 // HandleGetServiceGin godoc
@@ -719,21 +856,77 @@ func (h *MetricsHandler) HandleGetServiceGin(c *gin.Context) {
 // HandleUpdateServiceGin godoc
 //
 //	@Summary		更新服务
-//	@Description	更新指定服务的信息
+//	@Description	更新指定服务的信息，systemd_units变化后会推送给已通过WebSocket连接的服务节点
 //	@Tags			services
 //	@Accept			json
 //	@Produce		json
-//	@Param			service_id	path		string	true	"服务ID"
-//	@Param			service		body		object	true	"服务更新信息"
-//	@Success		200			{object}	object{service_id=string,message=string,success=bool}
-//	@Failure		400			{object}	object{error=string,message=string,success=bool}
-//	@Failure		404			{object}	object{error=string,message=string,success=bool}
+//	@Param			service_id	path		string			true	"服务ID"
+//	@Param			service		body		serviceRequest	true	"服务更新信息"
+//	@Success		200			{object}	Response{data=repository.Service}
+//	@Failure		400			{object}	Response	"请求格式错误"
+//	@Failure		404			{object}	Response	"服务不存在"
+//	@Failure		500			{object}	Response	"服务器错误"
 //	@Router			/api/v1/services/{service_id} [put]
 func (h *MetricsHandler) HandleUpdateServiceGin(c *gin.Context) {
-	// 获取服务ID
+	if h.serviceRepo == nil {
+		h.logger.Error("服务仓库未配置")
+		RespondWithError(c, http.StatusInternalServerError, nil, "系统配置错误，服务仓库未初始化")
+		return
+	}
+
 	serviceID := c.Param("service_id")
-	// 更新服务逻辑
-	RespondWithSuccess(c, http.StatusOK, gin.H{"service_id": serviceID, "message": "更新服务成功"})
+	var req serviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondWithError(c, http.StatusBadRequest, err, "请求格式错误")
+		return
+	}
+	if err := req.validate(); err != nil {
+		RespondWithError(c, http.StatusBadRequest, err, "服务信息验证失败")
+		return
+	}
+
+	ctx := c.Request.Context()
+	service, err := h.serviceRepo.GetByID(ctx, serviceID)
+	if err != nil {
+		h.logger.Error("获取服务信息失败", zap.String("service_id", serviceID), zap.Error(err))
+		RespondWithError(c, http.StatusInternalServerError, err, "获取服务信息失败")
+		return
+	}
+	if service == nil {
+		RespondWithError(c, http.StatusNotFound, nil, "服务不存在")
+		return
+	}
+
+	req.apply(service)
+	if err := h.serviceRepo.Update(ctx, service); err != nil {
+		h.logger.Error("更新服务失败", zap.String("service_id", serviceID), zap.Error(err))
+		RespondWithError(c, http.StatusInternalServerError, err, "更新服务失败")
+		return
+	}
+
+	h.pushServiceNodesConfig(ctx, serviceID)
+
+	RespondWithSuccess(c, http.StatusOK, service)
+}
+
+// pushServiceNodesConfig 服务更新后向其已连接的节点推送合并了服务信息的配置
+func (h *MetricsHandler) pushServiceNodesConfig(ctx context.Context, serviceID string) {
+	if h.nodeRepo == nil {
+		return
+	}
+
+	nodes, err := h.nodeRepo.GetByServiceID(ctx, serviceID)
+	if err != nil {
+		h.logger.Warn("获取服务节点失败", zap.String("service_id", serviceID), zap.Error(err))
+		return
+	}
+	for _, node := range nodes {
+		config := node.Configuration
+		if len(config) == 0 {
+			config = h.getDefaultNodeConfiguration(node)
+		}
+		h.pushNodeConfig(node.ID, h.withServiceConfig(ctx, node, config))
+	}
 }
 
 // HandleDeleteServiceGin godoc
@@ -1050,7 +1243,7 @@ func (h *MetricsHandler) HandleGetNodeConfigurationGin(c *gin.Context) {
 		config = h.getDefaultNodeConfiguration(node)
 	}
 
-	RespondWithSuccess(c, http.StatusOK, config)
+	RespondWithSuccess(c, http.StatusOK, h.withServiceConfig(ctx, node, config))
 }
 
 // HandleUpdateNodeConfigurationGin 更新节点配置
@@ -1140,6 +1333,9 @@ func (h *MetricsHandler) HandleUpdateNodeConfigurationGin(c *gin.Context) {
 		return
 	}
 
+	// 节点通过WebSocket连接时立即推送新配置
+	h.pushNodeConfig(nodeID, h.withServiceConfig(ctx, node, configData))
+
 	RespondWithSuccess(c, http.StatusOK, configData)
 }
 
@@ -1184,6 +1380,8 @@ func (h *MetricsHandler) getDefaultNodeConfiguration(node *repository.Node) map[
 			"processes":    []string{},
 			"include_args": false,
 		},
+		// 主动探测，字段与代理本地配置的probes相同(间隔和超时单位为毫秒)
+		"probes": []any{},
 	}
 
 	// 根据节点类型调整配置
@@ -1191,11 +1389,48 @@ func (h *MetricsHandler) getDefaultNodeConfiguration(node *repository.Node) map[
 		// 固定服务节点可能需要更频繁的监控
 		config["collection_interval"] = 30
 		config["report_interval"] = 60
+		// 服务依赖的systemd单元，失败的单元会与主机指标一起上报
+		config["systemd_units"] = []string{}
 	}
 
 	return config
 }
 
+// withServiceConfig 在下发的节点配置中合并节点所属服务声明的systemd单元
+// 服务信息放在service字段中，代理将其中的单元与节点配置的systemd_units一起采集
+func (h *MetricsHandler) withServiceConfig(ctx context.Context, node *repository.Node, config map[string]any) map[string]any {
+	if h.serviceRepo == nil || node == nil || !node.ServiceID.Valid || node.ServiceID.String == "" {
+		return config
+	}
+
+	service, err := h.serviceRepo.GetByID(ctx, node.ServiceID.String)
+	if err != nil {
+		h.logger.Warn("获取节点所属服务失败",
+			zap.String("node_id", node.ID),
+			zap.String("service_id", node.ServiceID.String),
+			zap.Error(err))
+		return config
+	}
+	if service == nil {
+		return config
+	}
+
+	units := service.SystemdUnits
+	if units == nil {
+		units = []string{}
+	}
+	merged := make(map[string]any, len(config)+1)
+	for key, value := range config {
+		merged[key] = value
+	}
+	merged["service"] = map[string]any{
+		"id":            service.ID,
+		"name":          service.Name,
+		"systemd_units": units,
+	}
+	return merged
+}
+
 // 验证节点配置有效性
 func (h *MetricsHandler) validateNodeConfiguration(config map[string]any) error {
 	// 检查必需字段
@@ -1233,5 +1468,72 @@ func (h *MetricsHandler) validateNodeConfiguration(config map[string]any) error
 		return fmt.Errorf("监控指标必须是字符串数组")
 	}
 
+	// 验证systemd单元列表（可选）
+	if units, exists := config["systemd_units"]; exists {
+		list, ok := units.([]any)
+		if !ok {
+			return fmt.Errorf("systemd单元必须是字符串数组")
+		}
+		for _, unit := range list {
+			if name, ok := unit.(string); !ok || name == "" {
+				return fmt.Errorf("systemd单元必须是非空字符串")
+			}
+		}
+	}
+
+	// 验证主动探测（可选）
+	if probes, exists := config["probes"]; exists {
+		if err := validateProbes(probes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 验证主动探测配置
+func validateProbes(raw any) error {
+	probes, ok := raw.([]any)
+	if !ok {
+		return fmt.Errorf("探测配置必须是数组")
+	}
+
+	names := make(map[string]bool, len(probes))
+	for i, item := range probes {
+		probe, ok := item.(map[string]any)
+		if !ok {
+			return fmt.Errorf("第%d个探测配置必须是对象", i+1)
+		}
+
+		target, _ := probe["target"].(string)
+		if target == "" {
+			return fmt.Errorf("第%d个探测缺少目标", i+1)
+		}
+
+		probeType, _ := probe["type"].(string)
+		switch probeType {
+		case "http", "tcp", "dns", "icmp":
+		default:
+			return fmt.Errorf("第%d个探测的类型 %q 不受支持", i+1, probeType)
+		}
+
+		name, _ := probe["name"].(string)
+		if name == "" {
+			name = probeType + ":" + target
+		}
+		if names[name] {
+			return fmt.Errorf("探测名称 %s 重复", name)
+		}
+		names[name] = true
+
+		for _, field := range []string{"interval", "timeout"} {
+			if value, exists := probe[field]; exists {
+				if ms, ok := value.(float64); !ok || ms < 0 {
+					return fmt.Errorf("探测 %s 的%s必须是非负数(毫秒)", name, field)
+				}
+			}
+		}
+	}
+
 	return nil
 }
@@ -1,8 +1,14 @@
 package api
 
 import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
 	"time"
 
+	"github.com/gorilla/websocket"
+
 	"github.com/syslens/syslens-api/internal/common/utils"
 	"github.com/syslens/syslens-api/internal/config"
 	"github.com/syslens/syslens-api/internal/server/repository"
@@ -11,13 +17,28 @@ import (
 
 // MetricsHandler 处理指标相关的API请求
 type MetricsHandler struct {
-	storage        MetricsStorage            // 指标存储接口
-	securityConfig *config.SecurityConfig    // 安全配置
-	encryptionSvc  *utils.EncryptionService  // 加密服务
-	logger         *zap.Logger               // 日志记录器
-	nodeRepo       repository.NodeRepository // 节点仓库接口
+	storage        MetricsStorage               // 指标存储接口
+	securityConfig *config.SecurityConfig       // 安全配置
+	encryptionSvc  *utils.EncryptionService     // 加密服务
+	logger         *zap.Logger                  // 日志记录器
+	nodeRepo       repository.NodeRepository    // 节点仓库接口
+	serviceRepo    repository.ServiceRepository // 服务仓库接口，用于下发服务声明的systemd单元
+
+	inventoryMu sync.RWMutex
+	inventories map[string]json.RawMessage // 各节点最近上报的硬件清单
+
+	clockSkewConfig config.ClockSkewConfig
+	clockMu         sync.RWMutex
+	clockStatuses   map[string]ClockSkewStatus // 各节点最近一次上报的时钟偏差
+
+	wsUpgrader websocket.Upgrader
+	wsMu       sync.RWMutex
+	wsConns    map[string]*nodeConnection // 节点ID -> WebSocket连接
 }
 
+// 默认的时钟偏差阈值(秒)
+const defaultClockSkewThreshold = 5
+
 // MetricsStorage 定义了指标存储接口
 type MetricsStorage interface {
 	StoreMetrics(nodeID string, metrics interface{}) error
@@ -26,6 +47,11 @@ type MetricsStorage interface {
 	GetLatestMetrics(nodeID string) (interface{}, error)
 }
 
+// MetricsBatchStorage 支持批量写入的指标存储，一批样本在一次操作中写入，原子性取决于具体存储
+type MetricsBatchStorage interface {
+	StoreMetricsBatch(nodeID string, metrics []interface{}) error
+}
+
 // NewMetricsHandler 创建新的指标处理器
 func NewMetricsHandler(storage MetricsStorage) *MetricsHandler {
 	return &MetricsHandler{
@@ -41,7 +67,16 @@ func NewMetricsHandler(storage MetricsStorage) *MetricsHandler {
 				Algorithm: "gzip",
 			},
 		},
-		logger: zap.NewNop(), // 默认使用空日志记录器
+		logger:          zap.NewNop(), // 默认使用空日志记录器
+		inventories:     make(map[string]json.RawMessage),
+		clockSkewConfig: config.ClockSkewConfig{Threshold: defaultClockSkewThreshold},
+		clockStatuses:   make(map[string]ClockSkewStatus),
+		wsUpgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return true // 连接方为节点代理而非浏览器，通过节点令牌认证
+			},
+		},
+		wsConns: make(map[string]*nodeConnection),
 	}
 }
 
@@ -68,6 +103,175 @@ func (h *MetricsHandler) WithNodeRepository(repo repository.NodeRepository) {
 	h.nodeRepo = repo
 }
 
+// WithServiceRepository 设置服务仓库
+func (h *MetricsHandler) WithServiceRepository(repo repository.ServiceRepository) {
+	h.serviceRepo = repo
+}
+
+// WithClockSkewConfig 设置时钟偏差检测配置，阈值未设置时使用默认值
+func (h *MetricsHandler) WithClockSkewConfig(cfg *config.ClockSkewConfig) {
+	if cfg != nil {
+		h.clockSkewConfig = *cfg
+		if h.clockSkewConfig.Threshold <= 0 {
+			h.clockSkewConfig.Threshold = defaultClockSkewThreshold
+		}
+	}
+}
+
+// prepareMetrics 在写入前处理单个样本：保存硬件清单、添加接收时间并检查时钟偏差
+// age为样本在代理中缓冲的时间，批量上报时不为0
+func (h *MetricsHandler) prepareMetrics(nodeID string, metricsData map[string]interface{}, receivedTime time.Time, age time.Duration, replayed bool) {
+	// 硬件清单只在首次上报和硬件变化时附带，单独保存最新版本；补发的旧清单不覆盖当前清单
+	if inventory, ok := metricsData["inventory"]; ok && inventory != nil && !replayed {
+		if raw, err := json.Marshal(inventory); err == nil {
+			h.inventoryMu.Lock()
+			h.inventories[nodeID] = raw
+			h.inventoryMu.Unlock()
+			h.logger.Info("节点硬件清单已更新", zap.String("node_id", nodeID))
+		}
+	}
+
+	// 添加接收时间戳
+	receivedAt := receivedTime.Unix()
+	metricsData["received_at"] = receivedAt
+	h.logger.Debug("添加接收时间戳",
+		zap.String("node_id", nodeID),
+		zap.Int64("timestamp", receivedAt))
+
+	// 检查节点时钟偏差，补发的数据按原始时间戳写入，不参与检测
+	if replayed {
+		metricsData["replayed"] = true
+		h.logger.Info("接收到补发的历史指标",
+			zap.String("node_id", nodeID),
+			zap.Any("timestamp", metricsData["timestamp"]))
+	} else {
+		h.checkClockSkew(nodeID, metricsData, receivedTime, age)
+	}
+}
+
+// storeMetricsBatch 写入一批样本，存储支持批量写入时在一次操作中写入，否则逐个写入
+func (h *MetricsHandler) storeMetricsBatch(nodeID string, metrics []interface{}) error {
+	if batchStorage, ok := h.storage.(MetricsBatchStorage); ok {
+		return batchStorage.StoreMetricsBatch(nodeID, metrics)
+	}
+	for _, m := range metrics {
+		if err := h.storage.StoreMetrics(nodeID, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeDecodedBatch 处理并写入已通过校验的批量样本
+func (h *MetricsHandler) storeDecodedBatch(nodeID string, batch *utils.MetricsBatch, samples []map[string]interface{}, receivedTime time.Time, replayed bool) error {
+	metrics := make([]interface{}, len(samples))
+	for i, sample := range samples {
+		h.prepareMetrics(nodeID, sample, receivedTime, sampleAge(sample, batch.SentAt), replayed)
+		metrics[i] = sample
+	}
+
+	startStoring := time.Now()
+	if err := h.storeMetricsBatch(nodeID, metrics); err != nil {
+		h.logger.Error("存储批量指标数据失败",
+			zap.String("node_id", nodeID),
+			zap.Int("samples", len(metrics)),
+			zap.Error(err))
+		return err
+	}
+	h.logger.Info("批量指标数据存储成功",
+		zap.String("node_id", nodeID),
+		zap.Int("samples", len(metrics)),
+		zap.Bool("delta", batch.Delta),
+		zap.Duration("time", time.Since(startStoring)))
+	return nil
+}
+
+// sampleAge 返回样本在代理中缓冲的时间，即代理的发送时间与样本时间戳之差，计算时钟偏差时扣除
+// 两者都取自代理的时钟，差值不受时钟偏差影响；发送时间未知时返回0
+func sampleAge(sample map[string]interface{}, sentAt time.Time) time.Duration {
+	if sentAt.IsZero() {
+		return 0
+	}
+	ts, ok := sample["timestamp"].(string)
+	if !ok {
+		return 0
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return 0
+	}
+	return sentAt.Sub(timestamp)
+}
+
+// singleSampleSentAt 返回单条指标的代理发送时间，直接上报时取自X-Sent-At头部，
+// 经聚合服务器转发时取自聚合服务器保留的agent_sent_at，均未提供时返回零值
+func singleSampleSentAt(header string, metricsData map[string]interface{}) time.Time {
+	if forwarded, ok := metricsData["agent_sent_at"].(string); ok {
+		header = forwarded
+	}
+	sentAt, err := time.Parse(time.RFC3339Nano, header)
+	if err != nil {
+		return time.Time{}
+	}
+	return sentAt
+}
+
+// checkClockSkew 计算节点上报的时间戳与接收时间的偏差，记录在指标的clock_skew_seconds中
+// 偏差超过阈值时标记节点，开启restamp时用接收时间替换时间戳，原始时间戳保存在original_timestamp中
+// age为样本在代理中缓冲的时间，参考时间相应提前
+func (h *MetricsHandler) checkClockSkew(nodeID string, metricsData map[string]interface{}, receivedAt time.Time, age time.Duration) {
+	ts, ok := metricsData["timestamp"].(string)
+	if !ok {
+		return
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return
+	}
+
+	// 经聚合服务器转发的数据以聚合服务器的接收时间为准，避免计入转发延迟
+	reference := receivedAt
+	if aggregatorReceivedAt, ok := metricsData["aggregator_received_at"].(float64); ok && aggregatorReceivedAt > 0 {
+		reference = time.Unix(int64(aggregatorReceivedAt), 0)
+	}
+	reference = reference.Add(-age)
+
+	skew := timestamp.Sub(reference).Seconds()
+	status := ClockSkewStatus{
+		NodeID:         nodeID,
+		SkewSeconds:    skew,
+		Skewed:         math.Abs(skew) > h.clockSkewConfig.Threshold,
+		AgentTimestamp: ts,
+		ReceivedAt:     reference.Format(time.RFC3339Nano),
+		TimeSync:       metricsData["time_sync"],
+	}
+	metricsData["clock_skew_seconds"] = skew
+	metricsData["clock_skewed"] = status.Skewed
+
+	if status.Skewed && h.clockSkewConfig.Restamp {
+		metricsData["original_timestamp"] = ts
+		metricsData["timestamp"] = reference.Format(time.RFC3339Nano)
+		status.Restamped = true
+	}
+
+	h.clockMu.Lock()
+	previous, seen := h.clockStatuses[nodeID]
+	h.clockStatuses[nodeID] = status
+	h.clockMu.Unlock()
+
+	if status.Skewed && (!seen || !previous.Skewed) {
+		h.logger.Warn("节点时钟偏差超过阈值",
+			zap.String("node_id", nodeID),
+			zap.Float64("skew_seconds", skew),
+			zap.Float64("threshold", h.clockSkewConfig.Threshold),
+			zap.Bool("restamp", h.clockSkewConfig.Restamp))
+	} else if !status.Skewed && seen && previous.Skewed {
+		h.logger.Info("节点时钟偏差已恢复",
+			zap.String("node_id", nodeID),
+			zap.Float64("skew_seconds", skew))
+	}
+}
+
 // processData 处理数据：解密和解压缩
 func (h *MetricsHandler) processData(data []byte, isEncrypted, isCompressed bool) ([]byte, error) {
 	processedData := data
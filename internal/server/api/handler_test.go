@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/syslens/syslens-api/internal/common/utils"
+	"github.com/syslens/syslens-api/internal/config"
+)
+
+// recordingStorage 记录写入的指标
+type recordingStorage struct {
+	mu      sync.Mutex
+	metrics []map[string]interface{}
+}
+
+func (s *recordingStorage) StoreMetrics(nodeID string, metrics interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = append(s.metrics, metrics.(map[string]interface{}))
+	return nil
+}
+
+func (s *recordingStorage) GetNodeMetrics(nodeID string, start, end time.Time) ([]interface{}, error) {
+	return nil, nil
+}
+
+func (s *recordingStorage) GetAllNodes() ([]string, error) {
+	return nil, nil
+}
+
+func (s *recordingStorage) GetLatestMetrics(nodeID string) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *recordingStorage) last() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.metrics) == 0 {
+		return nil
+	}
+	return s.metrics[len(s.metrics)-1]
+}
+
+// TestCheckClockSkew 测试时钟偏差的计算、阈值判断、时间戳替换以及缓冲时间的扣除
+func TestCheckClockSkew(t *testing.T) {
+	received := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	format := func(t time.Time) string { return t.Format(time.RFC3339Nano) }
+
+	tests := []struct {
+		name        string
+		timestamp   time.Time
+		age         time.Duration
+		aggregated  time.Time
+		wantSkew    float64
+		wantSkewed  bool
+		wantStamped bool
+	}{
+		{name: "时钟一致", timestamp: received.Add(-time.Second), wantSkew: -1},
+		{name: "节点时钟偏快", timestamp: received.Add(10 * time.Second), wantSkew: 10, wantSkewed: true, wantStamped: true},
+		{name: "缓冲延迟被扣除", timestamp: received.Add(-30 * time.Second), age: 30 * time.Second, wantSkew: 0},
+		{name: "未扣除缓冲延迟", timestamp: received.Add(-30 * time.Second), wantSkew: -30, wantSkewed: true, wantStamped: true},
+		{name: "以聚合服务器接收时间为准", timestamp: received.Add(-20 * time.Second), aggregated: received.Add(-20 * time.Second), wantSkew: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewMetricsHandler(&recordingStorage{})
+			h.WithClockSkewConfig(&config.ClockSkewConfig{Threshold: 5, Restamp: true})
+
+			metrics := map[string]interface{}{"timestamp": format(tt.timestamp)}
+			if !tt.aggregated.IsZero() {
+				metrics["aggregator_received_at"] = float64(tt.aggregated.Unix())
+			}
+			h.checkClockSkew("node-1", metrics, received, tt.age)
+
+			if skew := metrics["clock_skew_seconds"].(float64); skew != tt.wantSkew {
+				t.Errorf("偏差为 %v，期望 %v", skew, tt.wantSkew)
+			}
+			if skewed := metrics["clock_skewed"].(bool); skewed != tt.wantSkewed {
+				t.Errorf("偏差标记为 %v，期望 %v", skewed, tt.wantSkewed)
+			}
+			_, stamped := metrics["original_timestamp"]
+			if stamped != tt.wantStamped {
+				t.Errorf("时间戳替换为 %v，期望 %v: %+v", stamped, tt.wantStamped, metrics)
+			}
+			if !stamped && metrics["timestamp"] != format(tt.timestamp) {
+				t.Errorf("未超过阈值时不应修改时间戳: %v", metrics["timestamp"])
+			}
+			if status := h.clockStatuses["node-1"]; status.Skewed != tt.wantSkewed || status.Restamped != tt.wantStamped {
+				t.Errorf("节点时钟状态异常: %+v", status)
+			}
+		})
+	}
+}
+
+// TestSingleSampleClockSkew 测试单条上报按代理的发送时间扣除缓冲延迟
+func TestSingleSampleClockSkew(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 样本在代理中因重试延迟了一分钟才发送
+	delayed := func() (map[string]interface{}, time.Time) {
+		sentAt := time.Now()
+		return map[string]interface{}{
+			"timestamp": sentAt.Add(-time.Minute).Format(time.RFC3339Nano),
+			"hostname":  "host-1",
+		}, sentAt
+	}
+
+	newHandler := func() (*MetricsHandler, *recordingStorage, *gin.Engine) {
+		store := &recordingStorage{}
+		h := NewMetricsHandler(store)
+		h.WithClockSkewConfig(&config.ClockSkewConfig{Threshold: 5, Restamp: true})
+		router := gin.New()
+		router.POST("/api/v1/nodes/:node_id/metrics", h.HandleMetricsSubmitGin)
+		return h, store, router
+	}
+
+	post := func(router *gin.Engine, metrics map[string]interface{}, sentAt string) {
+		body, _ := json.Marshal(metrics)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/nodes/node-1/metrics", bytes.NewReader(body))
+		if sentAt != "" {
+			req.Header.Set("X-Sent-At", sentAt)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("上报失败: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	t.Run("HTTP携带发送时间", func(t *testing.T) {
+		_, store, router := newHandler()
+		metrics, sentAt := delayed()
+		post(router, metrics, sentAt.Format(time.RFC3339Nano))
+		if stored := store.last(); stored["clock_skewed"] != false || stored["original_timestamp"] != nil {
+			t.Errorf("延迟发送的样本不应被判定为时钟偏差: %+v", stored)
+		}
+	})
+
+	t.Run("HTTP未携带发送时间", func(t *testing.T) {
+		_, store, router := newHandler()
+		metrics, _ := delayed()
+		post(router, metrics, "")
+		if stored := store.last(); stored["clock_skewed"] != true {
+			t.Errorf("未提供发送时间时按接收时间计算偏差: %+v", stored)
+		}
+	})
+
+	t.Run("经聚合服务器转发", func(t *testing.T) {
+		_, store, router := newHandler()
+		metrics, sentAt := delayed()
+		metrics["aggregator_received_at"] = sentAt.Unix()
+		metrics["agent_sent_at"] = sentAt.Format(time.RFC3339Nano)
+		post(router, metrics, "")
+		if stored := store.last(); stored["clock_skewed"] != false {
+			t.Errorf("应使用聚合服务器保留的发送时间: %+v", stored)
+		}
+	})
+
+	t.Run("WebSocket携带发送时间", func(t *testing.T) {
+		h, store, _ := newHandler()
+		metrics, sentAt := delayed()
+		data, _ := json.Marshal(metrics)
+		msg := &utils.WebSocketMessage{Type: utils.WebSocketMessageMetrics, ID: 1, Data: data, SentAt: &sentAt}
+		if _, err := h.ingestWebSocketMetrics("node-1", msg); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		if stored := store.last(); stored["clock_skewed"] != false || stored["original_timestamp"] != nil {
+			t.Errorf("延迟发送的样本不应被判定为时钟偏差: %+v", stored)
+		}
+	})
+}
@@ -53,3 +53,17 @@ type StatusUpdateRequest struct {
 	Status string `json:"status" example:"offline"`
 	Reason string `json:"reason,omitempty" example:"系统维护中"`
 }
+
+// ClockSkewStatus 节点时钟偏差状态
+type ClockSkewStatus struct {
+	NodeID string `json:"node_id" example:"node-123456"`
+	// 节点时间戳减去接收时间(秒)，正数表示节点时钟偏快
+	SkewSeconds float64 `json:"skew_seconds" example:"0.35"`
+	// 偏差是否超过阈值
+	Skewed bool `json:"skewed" example:"false"`
+	// 是否已用接收时间替换节点上报的时间戳
+	Restamped      bool        `json:"restamped" example:"false"`
+	AgentTimestamp string      `json:"agent_timestamp" example:"2023-06-01T15:30:45Z"`
+	ReceivedAt     string      `json:"received_at" example:"2023-06-01T15:30:45Z"`
+	TimeSync       interface{} `json:"time_sync,omitempty"` // 节点上报的时间同步状态
+}
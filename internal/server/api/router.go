@@ -69,6 +69,9 @@ func setupNodeRoutes(rg *gin.RouterGroup, handler *MetricsHandler) {
 		// 获取节点配置（只需token）
 		nodes.GET("/configuration", handler.HandleGetNodeConfigurationGin)
 
+		// 获取节点时钟偏差
+		nodes.GET("/clock", handler.HandleGetClockSkewGin)
+
 		// 特定节点的操作
 		nodeGroup := nodes.Group("/:node_id")
 		{
@@ -80,6 +83,9 @@ func setupNodeRoutes(rg *gin.RouterGroup, handler *MetricsHandler) {
 
 			// 更新节点配置
 			nodeGroup.PUT("/configuration", handler.HandleUpdateNodeConfigurationGin)
+
+			// 获取节点硬件清单
+			nodeGroup.GET("/inventory", handler.HandleGetNodeInventoryGin)
 		}
 	}
 
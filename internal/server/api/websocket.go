@@ -0,0 +1,279 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/syslens/syslens-api/internal/common/utils"
+)
+
+const (
+	// 超过该时间未收到代理的任何消息(包括ping)时断开连接
+	wsReadTimeout = 90 * time.Second
+	// 单条消息的写入超时
+	wsWriteTimeout = 10 * time.Second
+	// 单条消息的最大长度，批量数据可能较大
+	wsMaxMessageSize = 32 * 1024 * 1024
+)
+
+// nodeConnection 节点的WebSocket连接，写入需要串行
+type nodeConnection struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// send 发送一条消息
+func (nc *nodeConnection) send(msg utils.WebSocketMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	nc.writeMu.Lock()
+	defer nc.writeMu.Unlock()
+	nc.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return nc.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// HandleWebSocketGin godoc
+//
+//	@Summary		节点WebSocket通道
+//	@Description	节点代理建立长连接，通过同一通道上报指标(metrics，主控端以ack确认)、请求配置(command: get_config)并接收配置推送(config)
+//	@Description	配置了节点仓库时需要在Authorization头部提供节点令牌；同一节点的新连接会替换旧连接
+//	@Tags			websocket
+//	@Param			node_id			query		string	true	"节点ID"
+//	@Param			Authorization	header		string	false	"节点令牌"
+//	@Success		101				{string}	string	"切换到WebSocket协议"
+//	@Failure		400				{object}	Response	"缺少节点ID"
+//	@Failure		401				{object}	Response	"认证失败"
+//	@Router			/api/v1/ws/nodes [get]
+func (h *MetricsHandler) HandleWebSocketGin(c *gin.Context) {
+	nodeID := c.Query("node_id")
+	if nodeID == "" {
+		RespondWithError(c, http.StatusBadRequest, nil, "缺少节点ID")
+		return
+	}
+
+	// 验证节点令牌
+	if h.nodeRepo != nil {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !h.validateNodeAuthentication(c, nodeID, token) {
+			return // validateNodeAuthentication已设置错误响应
+		}
+	}
+
+	conn, err := h.wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// 升级失败时Upgrader已返回错误响应
+		h.logger.Error("升级WebSocket连接失败",
+			zap.String("node_id", nodeID),
+			zap.Error(err))
+		return
+	}
+
+	nc := &nodeConnection{conn: conn}
+	h.wsMu.Lock()
+	if old, ok := h.wsConns[nodeID]; ok {
+		old.conn.Close()
+	}
+	h.wsConns[nodeID] = nc
+	h.wsMu.Unlock()
+
+	h.logger.Info("节点WebSocket连接已建立",
+		zap.String("node_id", nodeID),
+		zap.String("ip", c.ClientIP()))
+
+	h.serveWebSocket(nodeID, nc)
+}
+
+// serveWebSocket 读取并处理节点发送的消息，直到连接断开
+func (h *MetricsHandler) serveWebSocket(nodeID string, nc *nodeConnection) {
+	conn := nc.conn
+	defer func() {
+		conn.Close()
+		h.wsMu.Lock()
+		if h.wsConns[nodeID] == nc {
+			delete(h.wsConns, nodeID)
+		}
+		h.wsMu.Unlock()
+		h.logger.Info("节点WebSocket连接已断开", zap.String("node_id", nodeID))
+	}()
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	// 代理定期发送ping，收到后延长读取超时并回复pong
+	conn.SetPingHandler(func(appData string) error {
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		err := conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(wsWriteTimeout))
+		if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+			return err
+		}
+		return nil
+	})
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				h.logger.Warn("读取WebSocket消息失败",
+					zap.String("node_id", nodeID),
+					zap.Error(err))
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+
+		// 代理启用加密或压缩时以二进制消息发送处理后的数据
+		if messageType == websocket.BinaryMessage {
+			data, err = h.processData(data, h.securityConfig.Encryption.Enabled, h.securityConfig.Compression.Enabled)
+			if err != nil {
+				h.logger.Error("WebSocket数据处理失败",
+					zap.String("node_id", nodeID),
+					zap.Error(err))
+				continue
+			}
+		}
+
+		var msg utils.WebSocketMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			h.logger.Error("解析WebSocket消息失败",
+				zap.String("node_id", nodeID),
+				zap.Error(err))
+			continue
+		}
+		h.handleWebSocketMessage(nodeID, nc, &msg)
+	}
+}
+
+// handleWebSocketMessage 处理一条节点消息
+func (h *MetricsHandler) handleWebSocketMessage(nodeID string, nc *nodeConnection, msg *utils.WebSocketMessage) {
+	switch msg.Type {
+	case utils.WebSocketMessageMetrics:
+		ack := utils.WebSocketMessage{Type: utils.WebSocketMessageAck, ID: msg.ID}
+		if details, err := h.ingestWebSocketMetrics(nodeID, msg); err != nil {
+			ack.Error = err.Error()
+			ack.Details = details
+		}
+		if err := nc.send(ack); err != nil {
+			h.logger.Warn("发送指标确认失败",
+				zap.String("node_id", nodeID),
+				zap.Error(err))
+		}
+	case utils.WebSocketMessageCommand:
+		h.logger.Info("收到命令",
+			zap.String("node_id", nodeID),
+			zap.String("command", msg.Command))
+		switch msg.Command {
+		case utils.WebSocketCommandGetConfig:
+			if err := h.sendNodeConfig(context.Background(), nodeID, nc); err != nil {
+				h.logger.Warn("下发节点配置失败",
+					zap.String("node_id", nodeID),
+					zap.Error(err))
+			}
+		default:
+			h.logger.Warn("未知命令", zap.String("command", msg.Command))
+		}
+	default:
+		h.logger.Warn("未知消息类型", zap.String("type", msg.Type))
+	}
+}
+
+// ingestWebSocketMetrics 按HTTP上报相同的流程处理并写入指标，批量数据的样本校验错误在details中返回
+func (h *MetricsHandler) ingestWebSocketMetrics(nodeID string, msg *utils.WebSocketMessage) ([]utils.BatchSampleError, error) {
+	receivedTime := time.Now()
+
+	if msg.Batch {
+		batch, samples, sampleErrors, err := utils.DecodeMetricsBatch(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		if len(sampleErrors) > 0 {
+			return sampleErrors, fmt.Errorf("%d个样本中有%d个无效，整批未写入", len(samples), len(sampleErrors))
+		}
+		return nil, h.storeDecodedBatch(nodeID, batch, samples, receivedTime, msg.Replayed)
+	}
+
+	var metricsData map[string]interface{}
+	if err := json.Unmarshal(msg.Data, &metricsData); err != nil {
+		return nil, fmt.Errorf("解析指标数据失败: %w", err)
+	}
+	if metricsData == nil {
+		return nil, errors.New("指标数据为空")
+	}
+
+	var sentAt time.Time
+	if msg.SentAt != nil {
+		sentAt = *msg.SentAt
+	}
+	h.prepareMetrics(nodeID, metricsData, receivedTime, sampleAge(metricsData, sentAt), msg.Replayed)
+	if err := h.storage.StoreMetrics(nodeID, metricsData); err != nil {
+		h.logger.Error("存储指标数据失败",
+			zap.String("node_id", nodeID),
+			zap.Error(err))
+		return nil, fmt.Errorf("存储指标数据失败: %w", err)
+	}
+	return nil, nil
+}
+
+// sendNodeConfig 通过WebSocket下发节点当前的配置，未设置配置时下发默认配置
+func (h *MetricsHandler) sendNodeConfig(ctx context.Context, nodeID string, nc *nodeConnection) error {
+	if h.nodeRepo == nil {
+		return errors.New("节点仓库未初始化")
+	}
+
+	node, err := h.nodeRepo.GetByID(ctx, nodeID)
+	if err != nil {
+		return fmt.Errorf("获取节点信息失败: %w", err)
+	}
+	if node == nil {
+		return fmt.Errorf("节点不存在: %s", nodeID)
+	}
+
+	config := node.Configuration
+	if len(config) == 0 {
+		config = h.getDefaultNodeConfiguration(node)
+	}
+	return h.pushConfig(nc, h.withServiceConfig(ctx, node, config))
+}
+
+// pushNodeConfig 节点配置更新后推送给已连接的节点，节点未连接时返回false
+func (h *MetricsHandler) pushNodeConfig(nodeID string, config map[string]any) bool {
+	h.wsMu.RLock()
+	nc, ok := h.wsConns[nodeID]
+	h.wsMu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	if err := h.pushConfig(nc, config); err != nil {
+		h.logger.Warn("推送节点配置失败",
+			zap.String("node_id", nodeID),
+			zap.Error(err))
+		return false
+	}
+	h.logger.Info("节点配置已推送", zap.String("node_id", nodeID))
+	return true
+}
+
+// pushConfig 发送配置消息
+func (h *MetricsHandler) pushConfig(nc *nodeConnection, config map[string]any) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("序列化节点配置失败: %w", err)
+	}
+	return nc.send(utils.WebSocketMessage{
+		Type:    utils.WebSocketMessageConfig,
+		Data:    data,
+		Version: time.Now().Unix(),
+	})
+}
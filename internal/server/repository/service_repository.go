@@ -17,6 +17,7 @@ type Service struct {
 	Name            string         `json:"name"`
 	Description     sql.NullString `json:"description,omitempty"`
 	CriticalMetrics map[string]any `json:"critical_metrics,omitempty"`
+	SystemdUnits    []string       `json:"systemd_units,omitempty"` // 服务依赖的systemd单元，随节点配置下发给服务节点
 	CreatedAt       time.Time      `json:"created_time"`
 	UpdatedAt       time.Time      `json:"updated_time"`
 }
@@ -61,12 +62,16 @@ func (r *PostgresServiceRepository) Create(ctx context.Context, service *Service
 	if err != nil {
 		return fmt.Errorf("序列化服务关键指标失败: %w", err)
 	}
+	systemdUnitsJSON, err := json.Marshal(service.SystemdUnits)
+	if err != nil {
+		return fmt.Errorf("序列化服务systemd单元失败: %w", err)
+	}
 
 	query := `
 		INSERT INTO services (
-			id, name, description, critical_metrics
+			id, name, description, critical_metrics, systemd_units
 		) VALUES (
-			$1, $2, $3, $4
+			$1, $2, $3, $4, $5
 		) RETURNING created_time, updated_time
 	`
 
@@ -77,6 +82,7 @@ func (r *PostgresServiceRepository) Create(ctx context.Context, service *Service
 		service.Name,
 		service.Description,
 		criticalMetricsJSON,
+		systemdUnitsJSON,
 	).Scan(&service.CreatedAt, &service.UpdatedAt)
 
 	if err != nil {
@@ -90,19 +96,21 @@ func (r *PostgresServiceRepository) Create(ctx context.Context, service *Service
 func (r *PostgresServiceRepository) GetByID(ctx context.Context, id string) (*Service, error) {
 	query := `
 		SELECT 
-			id, name, description, critical_metrics, created_time, updated_time
+			id, name, description, critical_metrics, systemd_units, created_time, updated_time
 		FROM services
 		WHERE id = $1
 	`
 
 	var service Service
 	var criticalMetricsJSON []byte
+	var systemdUnitsJSON []byte
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&service.ID,
 		&service.Name,
 		&service.Description,
 		&criticalMetricsJSON,
+		&systemdUnitsJSON,
 		&service.CreatedAt,
 		&service.UpdatedAt,
 	)
@@ -120,6 +128,11 @@ func (r *PostgresServiceRepository) GetByID(ctx context.Context, id string) (*Se
 			return nil, fmt.Errorf("解析服务关键指标失败: %w", err)
 		}
 	}
+	if len(systemdUnitsJSON) > 0 {
+		if err := json.Unmarshal(systemdUnitsJSON, &service.SystemdUnits); err != nil {
+			return nil, fmt.Errorf("解析服务systemd单元失败: %w", err)
+		}
+	}
 
 	return &service, nil
 }
@@ -128,19 +141,21 @@ func (r *PostgresServiceRepository) GetByID(ctx context.Context, id string) (*Se
 func (r *PostgresServiceRepository) GetByName(ctx context.Context, name string) (*Service, error) {
 	query := `
 		SELECT 
-			id, name, description, critical_metrics, created_time, updated_time
+			id, name, description, critical_metrics, systemd_units, created_time, updated_time
 		FROM services
 		WHERE name = $1
 	`
 
 	var service Service
 	var criticalMetricsJSON []byte
+	var systemdUnitsJSON []byte
 
 	err := r.db.QueryRowContext(ctx, query, name).Scan(
 		&service.ID,
 		&service.Name,
 		&service.Description,
 		&criticalMetricsJSON,
+		&systemdUnitsJSON,
 		&service.CreatedAt,
 		&service.UpdatedAt,
 	)
@@ -158,6 +173,11 @@ func (r *PostgresServiceRepository) GetByName(ctx context.Context, name string)
 			return nil, fmt.Errorf("解析服务关键指标失败: %w", err)
 		}
 	}
+	if len(systemdUnitsJSON) > 0 {
+		if err := json.Unmarshal(systemdUnitsJSON, &service.SystemdUnits); err != nil {
+			return nil, fmt.Errorf("解析服务systemd单元失败: %w", err)
+		}
+	}
 
 	return &service, nil
 }
@@ -166,7 +186,7 @@ func (r *PostgresServiceRepository) GetByName(ctx context.Context, name string)
 func (r *PostgresServiceRepository) GetAll(ctx context.Context) ([]*Service, error) {
 	query := `
 		SELECT 
-			id, name, description, critical_metrics, created_time, updated_time
+			id, name, description, critical_metrics, systemd_units, created_time, updated_time
 		FROM services
 		ORDER BY name
 	`
@@ -187,13 +207,18 @@ func (r *PostgresServiceRepository) Update(ctx context.Context, service *Service
 	if err != nil {
 		return fmt.Errorf("序列化服务关键指标失败: %w", err)
 	}
+	systemdUnitsJSON, err := json.Marshal(service.SystemdUnits)
+	if err != nil {
+		return fmt.Errorf("序列化服务systemd单元失败: %w", err)
+	}
 
 	query := `
 		UPDATE services
 		SET 
 			name = $2,
 			description = $3,
-			critical_metrics = $4
+			critical_metrics = $4,
+			systemd_units = $5
 		WHERE id = $1
 		RETURNING updated_time
 	`
@@ -205,6 +230,7 @@ func (r *PostgresServiceRepository) Update(ctx context.Context, service *Service
 		service.Name,
 		service.Description,
 		criticalMetricsJSON,
+		systemdUnitsJSON,
 	).Scan(&service.UpdatedAt)
 
 	if err != nil {
@@ -269,12 +295,14 @@ func (r *PostgresServiceRepository) scanServices(rows *sql.Rows) ([]*Service, er
 	for rows.Next() {
 		var service Service
 		var criticalMetricsJSON []byte
+		var systemdUnitsJSON []byte
 
 		err := rows.Scan(
 			&service.ID,
 			&service.Name,
 			&service.Description,
 			&criticalMetricsJSON,
+			&systemdUnitsJSON,
 			&service.CreatedAt,
 			&service.UpdatedAt,
 		)
@@ -289,6 +317,11 @@ func (r *PostgresServiceRepository) scanServices(rows *sql.Rows) ([]*Service, er
 				return nil, fmt.Errorf("解析服务关键指标失败: %w", err)
 			}
 		}
+		if len(systemdUnitsJSON) > 0 {
+			if err := json.Unmarshal(systemdUnitsJSON, &service.SystemdUnits); err != nil {
+				return nil, fmt.Errorf("解析服务systemd单元失败: %w", err)
+			}
+		}
 
 		services = append(services, &service)
 	}
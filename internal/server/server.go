@@ -10,7 +10,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/syslens/syslens-api/internal/config"
 	"github.com/syslens/syslens-api/internal/server/api"
 	"github.com/syslens/syslens-api/internal/server/storage"
@@ -61,16 +60,6 @@ type ControlPlaneServer struct {
 
 	// 等待组，用于等待所有goroutine完成
 	wg sync.WaitGroup
-
-	// WebSocket连接管理
-	connections struct {
-		sync.RWMutex
-		// 节点ID -> WebSocket连接
-		data map[string]*websocket.Conn
-	}
-
-	// WebSocket升级器
-	upgrader websocket.Upgrader
 }
 
 // NodeInfo 节点信息
@@ -156,11 +145,6 @@ func NewControlPlaneServer(cfg *config.ServerConfig, logger *zap.Logger) (*Contr
 		ctx:    ctx,
 		cancel: cancel,
 		logger: logger,
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // 在生产环境中应该检查来源
-			},
-		},
 	}
 
 	// 初始化节点管理
@@ -193,9 +177,6 @@ func NewControlPlaneServer(cfg *config.ServerConfig, logger *zap.Logger) (*Contr
 		WriteTimeout: time.Second * 30,
 	}
 
-	// 初始化WebSocket连接管理
-	s.connections.data = make(map[string]*websocket.Conn)
-
 	return s, nil
 }
 
@@ -285,6 +266,9 @@ func (s *ControlPlaneServer) initRouter() {
 	// 应用安全配置
 	metricsHandler.WithSecurityConfig(&s.config.Security)
 
+	// 应用时钟偏差检测配置
+	metricsHandler.WithClockSkewConfig(&s.config.ClockSkew)
+
 	// 设置日志记录器
 	metricsHandler.WithLogger(s.logger)
 
@@ -769,184 +753,3 @@ func (s *ControlPlaneServer) handleCreateService(w http.ResponseWriter, r *http.
 		"service": s.services.data[serviceID],
 	})
 }
-
-// handleWebSocket 处理WebSocket连接
-func (s *ControlPlaneServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	// 升级HTTP连接为WebSocket连接
-	conn, err := s.upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		s.logger.Error("升级WebSocket连接失败", zap.Error(err))
-		return
-	}
-
-	// 获取节点ID
-	nodeID := r.URL.Query().Get("node_id")
-	if nodeID == "" {
-		s.logger.Error("缺少节点ID")
-		conn.Close()
-		return
-	}
-
-	// 保存连接
-	s.connections.Lock()
-	s.connections.data[nodeID] = conn
-	s.connections.Unlock()
-
-	// 处理连接
-	go s.handleWebSocketConnection(nodeID, conn)
-}
-
-// handleWebSocketConnection 处理WebSocket连接
-func (s *ControlPlaneServer) handleWebSocketConnection(nodeID string, conn *websocket.Conn) {
-	defer func() {
-		conn.Close()
-		s.connections.Lock()
-		delete(s.connections.data, nodeID)
-		s.connections.Unlock()
-	}()
-
-	// 设置读取超时
-	conn.SetReadDeadline(time.Now().Add(time.Second * 30))
-
-	for {
-		// 读取消息
-		messageType, data, err := conn.ReadMessage()
-		if err != nil {
-			s.logger.Error("读取WebSocket消息失败", zap.Error(err))
-			return
-		}
-
-		// 处理消息
-		switch messageType {
-		case websocket.TextMessage:
-			s.processWebSocketData(nodeID, data)
-		case websocket.PingMessage:
-			// 发送pong响应
-			if err := conn.WriteMessage(websocket.PongMessage, nil); err != nil {
-				s.logger.Error("发送pong响应失败", zap.Error(err))
-				return
-			}
-		}
-	}
-}
-
-// processWebSocketData 处理WebSocket数据
-func (s *ControlPlaneServer) processWebSocketData(nodeID string, data []byte) {
-	// 解析数据
-	var message map[string]interface{}
-	if err := json.Unmarshal(data, &message); err != nil {
-		s.logger.Error("解析WebSocket数据失败", zap.Error(err))
-		return
-	}
-
-	// 处理不同类型的消息
-	messageType, ok := message["type"].(string)
-	if !ok {
-		s.logger.Error("WebSocket消息类型无效")
-		return
-	}
-
-	switch messageType {
-	case "metrics":
-		// 处理指标数据
-		if metrics, ok := message["data"].(map[string]interface{}); ok {
-			// 存储指标数据
-			if err := s.storage.StoreMetrics(nodeID, metrics); err != nil {
-				s.logger.Error("存储指标数据失败", zap.Error(err))
-			}
-		}
-	case "command":
-		// 处理命令
-		if command, ok := message["command"].(string); ok {
-			s.logger.Info("收到命令", zap.String("node_id", nodeID), zap.String("command", command))
-			// 处理不同类型的命令
-			switch command {
-			case "get_config":
-				// 获取节点配置
-				s.sendNodeConfig(nodeID)
-			default:
-				s.logger.Warn("未知命令", zap.String("command", command))
-			}
-		}
-	default:
-		s.logger.Warn("未知消息类型", zap.String("type", messageType))
-	}
-}
-
-// sendNodeConfig 发送节点配置
-func (s *ControlPlaneServer) sendNodeConfig(nodeID string) {
-	// 获取节点配置
-	s.nodes.RLock()
-	node, ok := s.nodes.data[nodeID]
-	s.nodes.RUnlock()
-
-	if !ok {
-		s.logger.Error("节点不存在", zap.String("node_id", nodeID))
-		return
-	}
-
-	// 构建配置
-	config := map[string]interface{}{
-		"node_id": node.ID,
-		"labels":  node.Labels,
-		"type":    node.Type,
-	}
-
-	// 如果节点属于分组，添加分组配置
-	if node.GroupID != "" {
-		s.groups.RLock()
-		if group, ok := s.groups.data[node.GroupID]; ok {
-			config["group"] = map[string]interface{}{
-				"id":   group.ID,
-				"name": group.Name,
-				"type": group.Type,
-			}
-		}
-		s.groups.RUnlock()
-	}
-
-	// 如果节点是固定服务节点，添加服务配置
-	if node.ServiceID != "" {
-		s.services.RLock()
-		if service, ok := s.services.data[node.ServiceID]; ok {
-			config["service"] = map[string]interface{}{
-				"id":   service.ID,
-				"name": service.Name,
-				"type": service.Type,
-			}
-		}
-		s.services.RUnlock()
-	}
-
-	// 构建消息
-	message := map[string]interface{}{
-		"type":    "config",
-		"data":    config,
-		"version": time.Now().Unix(),
-	}
-
-	// 序列化消息
-	data, err := json.Marshal(message)
-	if err != nil {
-		s.logger.Error("序列化配置消息失败", zap.Error(err))
-		return
-	}
-
-	// 获取WebSocket连接
-	s.connections.RLock()
-	conn, ok := s.connections.data[nodeID]
-	s.connections.RUnlock()
-
-	if !ok {
-		s.logger.Error("节点WebSocket连接不存在", zap.String("node_id", nodeID))
-		return
-	}
-
-	// 发送配置
-	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-		s.logger.Error("发送节点配置失败", zap.Error(err))
-		return
-	}
-
-	s.logger.Info("节点配置已发送", zap.String("node_id", nodeID))
-}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
@@ -9,15 +10,18 @@ import (
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
 )
 
 // InfluxDBStorage 提供基于InfluxDB的指标存储实现
 type InfluxDBStorage struct {
 	client   influxdb2.Client
 	writeAPI api.WriteAPI
-	queryAPI api.QueryAPI
-	org      string
-	bucket   string
+	// 同步写入API，批量数据在一次请求中写入并直接返回错误
+	blockingWriteAPI api.WriteAPIBlocking
+	queryAPI         api.QueryAPI
+	org              string
+	bucket           string
 }
 
 // NewInfluxDBStorage 创建新的InfluxDB存储实例
@@ -37,11 +41,12 @@ func NewInfluxDBStorage(url, token, org, bucket string) *InfluxDBStorage {
 
 	// 返回存储实例
 	return &InfluxDBStorage{
-		client:   client,
-		writeAPI: writeAPI,
-		queryAPI: queryAPI,
-		org:      org,
-		bucket:   bucket,
+		client:           client,
+		writeAPI:         writeAPI,
+		blockingWriteAPI: client.WriteAPIBlocking(org, bucket),
+		queryAPI:         queryAPI,
+		org:              org,
+		bucket:           bucket,
 	}
 }
 
@@ -76,6 +81,34 @@ func ensureInfluxDBResources(client influxdb2.Client, orgName, bucketName string
 	return nil
 }
 
+// StoreMetricsBatch 存储一批指标数据
+// 先为所有样本生成数据点，任一样本格式无效时不写入；数据点在一次请求中同步写入，
+// 请求失败时整批未写入，但InfluxDB在字段类型冲突等情况下可能只写入部分数据点
+func (s *InfluxDBStorage) StoreMetricsBatch(nodeID string, metrics []interface{}) error {
+	var points []*write.Point
+	for i, m := range metrics {
+		metricsMap, ok := m.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("第%d个样本格式无效: 期望map[string]interface{}, 实际为%T", i, m)
+		}
+		samplePoints, _ := buildMetricsPoints(nodeID, metricsMap)
+		points = append(points, samplePoints...)
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.blockingWriteAPI.WritePoint(ctx, points...); err != nil {
+		log.Printf("[错误] InfluxDB批量写入失败 - 节点: %s, 样本数: %d, 错误: %v", nodeID, len(metrics), err)
+		return fmt.Errorf("InfluxDB写入错误: %w", err)
+	}
+
+	log.Printf("[信息] InfluxDB批量写入成功 - 节点: %s, 样本数: %d, 数据点: %d", nodeID, len(metrics), len(points))
+	return nil
+}
+
 // StoreMetrics 存储节点指标数据
 func (s *InfluxDBStorage) StoreMetrics(nodeID string, metrics interface{}) error {
 	// 转换metrics为map
@@ -84,10 +117,166 @@ func (s *InfluxDBStorage) StoreMetrics(nodeID string, metrics interface{}) error
 		return fmt.Errorf("无效的指标格式: 期望map[string]interface{}, 实际为%T", metrics)
 	}
 
-	// 提取时间戳，默认为当前时间
+	points, timestamp := buildMetricsPoints(nodeID, metricsMap)
+	for _, p := range points {
+		s.writeAPI.WritePoint(p)
+	}
+
+	// 异步提交
+	s.writeAPI.Flush()
+
+	// 记录详细的写入信息
+	var metricsTypes []string
+	pointCounts := make(map[string]int)
+
+	if metricsMap["cpu"] != nil {
+		metricsTypes = append(metricsTypes, "cpu")
+		pointCounts["cpu"] = 1
+		if perCPU, ok := metricsMap["per_cpu"].(map[string]interface{}); ok {
+			pointCounts["cpu"] += len(perCPU)
+		}
+	}
+	if metricsMap["memory"] != nil {
+		metricsTypes = append(metricsTypes, "memory")
+		pointCounts["memory"] = 1
+	}
+	if pressureMap, ok := metricsMap["pressure"].(map[string]interface{}); ok && len(pressureMap) > 0 {
+		metricsTypes = append(metricsTypes, "pressure")
+		pointCounts["pressure"] = len(pressureMap)
+	}
+	if metricsMap["vmstat"] != nil {
+		metricsTypes = append(metricsTypes, "vmstat")
+		pointCounts["vmstat"] = 1
+	}
+	if diskMap, ok := metricsMap["disk"].(map[string]interface{}); ok {
+		metricsTypes = append(metricsTypes, "disk")
+		if partitions, hasParts := diskMap["partitions"].([]interface{}); hasParts {
+			pointCounts["disk"] = len(partitions)
+		} else {
+			pointCounts["disk"] = 1
+		}
+	}
+	if diskIOMap, ok := metricsMap["disk_io"].(map[string]interface{}); ok && len(diskIOMap) > 0 {
+		metricsTypes = append(metricsTypes, "disk_io")
+		pointCounts["disk_io"] = len(diskIOMap)
+	}
+	if cgroupList, ok := metricsMap["cgroups"].([]interface{}); ok && len(cgroupList) > 0 {
+		metricsTypes = append(metricsTypes, "cgroup")
+		pointCounts["cgroup"] = len(cgroupList)
+	}
+	if unitList, ok := metricsMap["systemd"].([]interface{}); ok && len(unitList) > 0 {
+		metricsTypes = append(metricsTypes, "systemd_unit")
+		pointCounts["systemd_unit"] = len(unitList)
+	}
+	if sensorList, ok := metricsMap["sensors"].([]interface{}); ok && len(sensorList) > 0 {
+		metricsTypes = append(metricsTypes, "sensor")
+		pointCounts["sensor"] = len(sensorList)
+	}
+	if customMap, ok := metricsMap["custom"].(map[string]interface{}); ok && len(customMap) > 0 {
+		metricsTypes = append(metricsTypes, "custom")
+		for _, item := range customMap {
+			pointCounts["plugin"]++
+			if result, ok := item.(map[string]interface{}); ok {
+				if metricList, ok := result["metrics"].([]interface{}); ok {
+					pointCounts["custom"] += len(metricList)
+				}
+			}
+		}
+	}
+	if probeMap, ok := metricsMap["probes"].(map[string]interface{}); ok && len(probeMap) > 0 {
+		metricsTypes = append(metricsTypes, "probe")
+		pointCounts["probe"] = len(probeMap)
+	}
+	if logMap, ok := metricsMap["logs"].(map[string]interface{}); ok && len(logMap) > 0 {
+		metricsTypes = append(metricsTypes, "log")
+		for _, item := range logMap {
+			pointCounts["log_file"]++
+			if logInfo, ok := item.(map[string]interface{}); ok {
+				if counts, ok := logInfo["counts"].(map[string]interface{}); ok {
+					pointCounts["log_pattern"] += len(counts)
+				}
+			}
+		}
+	}
+	if certList, ok := metricsMap["certificates"].([]interface{}); ok && len(certList) > 0 {
+		metricsTypes = append(metricsTypes, "certificate")
+		pointCounts["certificate"] = len(certList)
+	}
+	if metricsMap["clock_skew_seconds"] != nil {
+		metricsTypes = append(metricsTypes, "clock")
+		pointCounts["clock"] = 1
+	}
+	if metricsMap["integrity"] != nil {
+		metricsTypes = append(metricsTypes, "file_integrity")
+		pointCounts["file_integrity"] = 1
+	}
+	if eventList, ok := metricsMap["events"].([]interface{}); ok && len(eventList) > 0 {
+		metricsTypes = append(metricsTypes, "event")
+		pointCounts["event"] = len(eventList)
+	}
+	if networkMap, ok := metricsMap["network"].(map[string]interface{}); ok {
+		metricsTypes = append(metricsTypes, "network")
+		pointCounts["network"] = 1
+		if interfaces, hasIfaces := networkMap["interfaces"].(map[string]interface{}); hasIfaces {
+			pointCounts["network_interfaces"] = len(interfaces)
+		}
+	}
+
+	log.Printf("[信息] InfluxDB写入开始 - 节点: %s, 时间戳: %s, 指标类型: %v",
+		nodeID,
+		timestamp.Format(time.RFC3339),
+		strings.Join(metricsTypes, ", "))
+
+	// 记录写入的点数
+	totalPoints := 0
+	for _, count := range pointCounts {
+		totalPoints += count
+	}
+
+	log.Printf("[详细] InfluxDB写入点数统计 - 节点: %s, 总点数: %d, 详情: %v",
+		nodeID,
+		totalPoints,
+		pointCounts)
+
+	// 捕获异步写入错误
+	errChan := make(chan error, 1)
+
+	go func() {
+		for err := range s.writeAPI.Errors() {
+			errChan <- err
+			log.Printf("[错误] InfluxDB写入失败 - 节点: %s, 错误: %v", nodeID, err)
+		}
+	}()
+
+	// 等待一小段时间，让异步错误有机会被捕获
+	select {
+	case err := <-errChan:
+		return fmt.Errorf("InfluxDB写入错误: %w", err)
+	case <-time.After(100 * time.Millisecond):
+		// 继续执行
+	}
+
+	log.Printf("[信息] InfluxDB写入成功 - 节点: %s, 数据点: %d, 指标类型: %v",
+		nodeID,
+		totalPoints,
+		strings.Join(metricsTypes, ", "))
+
+	return nil
+}
+
+// buildMetricsPoints 将一个样本转换为InfluxDB数据点，同时返回样本的时间戳
+func buildMetricsPoints(nodeID string, metricsMap map[string]interface{}) ([]*write.Point, time.Time) {
+	var points []*write.Point
+
+	// 提取时间戳，默认为当前时间；经JSON解码的时间戳为RFC3339字符串
 	timestamp := time.Now()
-	if ts, ok := metricsMap["timestamp"].(time.Time); ok {
+	switch ts := metricsMap["timestamp"].(type) {
+	case time.Time:
 		timestamp = ts
+	case string:
+		if parsed, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			timestamp = parsed
+		}
 	}
 
 	// 提取主机名和平台信息作为标签
@@ -103,16 +292,41 @@ func (s *InfluxDBStorage) StoreMetrics(nodeID string, metrics interface{}) error
 		tags["platform"] = platform
 	}
 
-	// 创建CPU指标点
-	if cpu, ok := metricsMap["cpu"].(map[string]interface{}); ok {
-		for key, value := range cpu {
-			p := influxdb2.NewPoint(
-				"cpu",
-				tags,
-				map[string]interface{}{key: value},
-				timestamp,
-			)
-			s.writeAPI.WritePoint(p)
+	// 创建CPU指标点，总体数据使用cpu=total标签
+	if cpu, ok := metricsMap["cpu"].(map[string]interface{}); ok && len(cpu) > 0 {
+		cpuTags := make(map[string]string)
+		for k, v := range tags {
+			cpuTags[k] = v
+		}
+		cpuTags["cpu"] = "total"
+
+		p := influxdb2.NewPoint(
+			"cpu",
+			cpuTags,
+			cpu,
+			timestamp,
+		)
+		points = append(points, p)
+	}
+
+	// 创建每个CPU核心的指标点
+	if perCPU, ok := metricsMap["per_cpu"].(map[string]interface{}); ok {
+		for core, info := range perCPU {
+			if coreInfo, ok := info.(map[string]interface{}); ok {
+				coreTags := make(map[string]string)
+				for k, v := range tags {
+					coreTags[k] = v
+				}
+				coreTags["cpu"] = core
+
+				p := influxdb2.NewPoint(
+					"cpu",
+					coreTags,
+					coreInfo,
+					timestamp,
+				)
+				points = append(points, p)
+			}
 		}
 	}
 
@@ -124,7 +338,39 @@ func (s *InfluxDBStorage) StoreMetrics(nodeID string, metrics interface{}) error
 			memory,
 			timestamp,
 		)
-		s.writeAPI.WritePoint(p)
+		points = append(points, p)
+	}
+
+	// 创建资源压力指标点
+	if pressure, ok := metricsMap["pressure"].(map[string]interface{}); ok {
+		for resource, info := range pressure {
+			if pressureInfo, ok := info.(map[string]interface{}); ok {
+				pressureTags := make(map[string]string)
+				for k, v := range tags {
+					pressureTags[k] = v
+				}
+				pressureTags["resource"] = resource
+
+				p := influxdb2.NewPoint(
+					"pressure",
+					pressureTags,
+					pressureInfo,
+					timestamp,
+				)
+				points = append(points, p)
+			}
+		}
+	}
+
+	// 创建vmstat指标点
+	if vmstat, ok := metricsMap["vmstat"].(map[string]interface{}); ok && len(vmstat) > 0 {
+		p := influxdb2.NewPoint(
+			"vmstat",
+			tags,
+			vmstat,
+			timestamp,
+		)
+		points = append(points, p)
 	}
 
 	// 创建磁盘指标点
@@ -143,19 +389,387 @@ func (s *InfluxDBStorage) StoreMetrics(nodeID string, metrics interface{}) error
 					diskInfo,
 					timestamp,
 				)
-				s.writeAPI.WritePoint(p)
+				points = append(points, p)
+			}
+		}
+	}
+
+	// 创建磁盘I/O指标点
+	if diskIO, ok := metricsMap["disk_io"].(map[string]interface{}); ok {
+		for device, info := range diskIO {
+			if ioInfo, ok := info.(map[string]interface{}); ok {
+				ioTags := make(map[string]string)
+				for k, v := range tags {
+					ioTags[k] = v
+				}
+				ioTags["device"] = device
+
+				p := influxdb2.NewPoint(
+					"disk_io",
+					ioTags,
+					ioInfo,
+					timestamp,
+				)
+				points = append(points, p)
+			}
+		}
+	}
+
+	// 创建cgroup指标点，cgroup标签作为InfluxDB标签便于按容器或服务筛选
+	if cgroups, ok := metricsMap["cgroups"].([]interface{}); ok {
+		for _, item := range cgroups {
+			cgroupInfo, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			cgroupTags := make(map[string]string)
+			for k, v := range tags {
+				cgroupTags[k] = v
+			}
+			if labels, ok := cgroupInfo["labels"].(map[string]interface{}); ok {
+				for k, v := range labels {
+					if value, ok := v.(string); ok && value != "" {
+						cgroupTags[k] = value
+					}
+				}
+			}
+			if name, ok := cgroupInfo["name"].(string); ok {
+				cgroupTags["name"] = name
+			}
+
+			fields := make(map[string]interface{})
+			for k, v := range cgroupInfo {
+				if k != "labels" && k != "name" && k != "path" {
+					fields[k] = v
+				}
+			}
+
+			p := influxdb2.NewPoint(
+				"cgroup",
+				cgroupTags,
+				fields,
+				timestamp,
+			)
+			points = append(points, p)
+		}
+	}
+
+	// 创建systemd单元指标点，单元名称作为标签
+	if units, ok := metricsMap["systemd"].([]interface{}); ok {
+		for _, item := range units {
+			unitInfo, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := unitInfo["name"].(string)
+			if name == "" {
+				continue
+			}
+
+			unitTags := make(map[string]string)
+			for k, v := range tags {
+				unitTags[k] = v
+			}
+			unitTags["unit"] = name
+
+			fields := make(map[string]interface{})
+			for _, key := range []string{"load_state", "active_state", "sub_state", "result", "n_restarts",
+				"memory_current", "cpu_percent", "tasks_current", "declared"} {
+				if value, ok := unitInfo[key]; ok {
+					fields[key] = value
+				}
+			}
+			if activeState, ok := unitInfo["active_state"].(string); ok {
+				fields["active"] = activeState == "active"
+				fields["failed"] = activeState == "failed"
+			}
+
+			p := influxdb2.NewPoint(
+				"systemd_unit",
+				unitTags,
+				fields,
+				timestamp,
+			)
+			points = append(points, p)
+		}
+	}
+
+	// 创建传感器指标点，芯片和传感器名称作为标签
+	if sensors, ok := metricsMap["sensors"].([]interface{}); ok {
+		for _, item := range sensors {
+			sensorInfo, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			sensorTags := make(map[string]string)
+			for k, v := range tags {
+				sensorTags[k] = v
+			}
+			if labels, ok := sensorInfo["labels"].(map[string]interface{}); ok {
+				for k, v := range labels {
+					if value, ok := v.(string); ok && value != "" {
+						sensorTags[k] = value
+					}
+				}
+			}
+			for _, key := range []string{"chip", "sensor", "kind", "source"} {
+				if value, ok := sensorInfo[key].(string); ok {
+					sensorTags[key] = value
+				}
+			}
+
+			fields := make(map[string]interface{})
+			for _, key := range []string{"value", "min", "max", "critical", "alarm"} {
+				if value, ok := sensorInfo[key]; ok {
+					fields[key] = value
+				}
+			}
+
+			p := influxdb2.NewPoint(
+				"sensor",
+				sensorTags,
+				fields,
+				timestamp,
+			)
+			points = append(points, p)
+		}
+	}
+
+	// 创建自定义插件指标点，每个指标一个点，插件标签和指标标签作为InfluxDB标签
+	if custom, ok := metricsMap["custom"].(map[string]interface{}); ok {
+		for pluginName, item := range custom {
+			result, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			pluginTags := make(map[string]string)
+			for k, v := range tags {
+				pluginTags[k] = v
+			}
+			if labels, ok := result["labels"].(map[string]interface{}); ok {
+				for k, v := range labels {
+					if value, ok := v.(string); ok && value != "" {
+						pluginTags[k] = value
+					}
+				}
+			}
+			pluginTags["plugin"] = pluginName
+
+			// 插件执行状态
+			statusFields := make(map[string]interface{})
+			for _, key := range []string{"status", "exit_code", "duration_ms", "error"} {
+				if value, ok := result[key]; ok {
+					statusFields[key] = value
+				}
+			}
+			points = append(points, influxdb2.NewPoint("plugin", pluginTags, statusFields, timestamp))
+
+			metricList, _ := result["metrics"].([]interface{})
+			for _, m := range metricList {
+				metric, ok := m.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := metric["name"].(string)
+				value, ok := metric["value"]
+				if name == "" || !ok {
+					continue
+				}
+
+				metricTags := make(map[string]string, len(pluginTags)+1)
+				for k, v := range pluginTags {
+					metricTags[k] = v
+				}
+				if labels, ok := metric["labels"].(map[string]interface{}); ok {
+					for k, v := range labels {
+						if labelValue, ok := v.(string); ok && labelValue != "" {
+							metricTags[k] = labelValue
+						}
+					}
+				}
+				metricTags["metric"] = name
+				if unit, ok := metric["unit"].(string); ok && unit != "" {
+					metricTags["unit"] = unit
+				}
+
+				p := influxdb2.NewPoint(
+					"custom",
+					metricTags,
+					map[string]interface{}{"value": value},
+					timestamp,
+				)
+				points = append(points, p)
 			}
 		}
 	}
 
+	// 创建主动探测指标点，探测名称、类型和目标作为标签
+	if probes, ok := metricsMap["probes"].(map[string]interface{}); ok {
+		for probeName, item := range probes {
+			result, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			probeTags := make(map[string]string)
+			for k, v := range tags {
+				probeTags[k] = v
+			}
+			if labels, ok := result["labels"].(map[string]interface{}); ok {
+				for k, v := range labels {
+					if value, ok := v.(string); ok && value != "" {
+						probeTags[k] = value
+					}
+				}
+			}
+			probeTags["probe"] = probeName
+			for _, key := range []string{"type", "target"} {
+				if value, ok := result[key].(string); ok {
+					probeTags[key] = value
+				}
+			}
+
+			fields := make(map[string]interface{})
+			for _, key := range []string{"success", "latency_ms", "error", "status_code", "cert_days_left", "packets_sent", "packets_recv", "packet_loss"} {
+				if value, ok := result[key]; ok {
+					fields[key] = value
+				}
+			}
+
+			p := influxdb2.NewPoint(
+				"probe",
+				probeTags,
+				fields,
+				timestamp,
+			)
+			points = append(points, p)
+		}
+	}
+
+	// 创建日志计数指标点，每个模式一个点，可用于"每分钟5xx行数超过50"等告警规则
+	if logs, ok := metricsMap["logs"].(map[string]interface{}); ok {
+		for watchName, item := range logs {
+			logInfo, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			logTags := make(map[string]string)
+			for k, v := range tags {
+				logTags[k] = v
+			}
+			if labels, ok := logInfo["labels"].(map[string]interface{}); ok {
+				for k, v := range labels {
+					if value, ok := v.(string); ok && value != "" {
+						logTags[k] = value
+					}
+				}
+			}
+			logTags["watch"] = watchName
+			if path, ok := logInfo["path"].(string); ok {
+				logTags["path"] = path
+			}
+
+			intervalSeconds, _ := logInfo["interval_seconds"].(float64)
+			counts, _ := logInfo["counts"].(map[string]interface{})
+			for pattern, value := range counts {
+				count, ok := value.(float64)
+				if !ok {
+					continue
+				}
+				patternTags := make(map[string]string, len(logTags)+1)
+				for k, v := range logTags {
+					patternTags[k] = v
+				}
+				patternTags["pattern"] = pattern
+
+				fields := map[string]interface{}{"count": count}
+				if intervalSeconds > 0 {
+					fields["per_minute"] = count / intervalSeconds * 60
+				}
+				points = append(points, influxdb2.NewPoint("log_pattern", patternTags, fields, timestamp))
+			}
+
+			fields := make(map[string]interface{})
+			for _, key := range []string{"lines", "bytes", "interval_seconds", "rotated", "truncated", "error"} {
+				if value, ok := logInfo[key]; ok {
+					fields[key] = value
+				}
+			}
+			if samples, ok := logInfo["samples"].([]interface{}); ok {
+				fields["samples"] = len(samples)
+			}
+			points = append(points, influxdb2.NewPoint("log_file", logTags, fields, timestamp))
+		}
+	}
+
+	// 创建证书指标点，可用于"证书剩余有效期小于14天"等告警规则
+	if certificates, ok := metricsMap["certificates"].([]interface{}); ok {
+		for _, item := range certificates {
+			certInfo, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			certTags := make(map[string]string)
+			for k, v := range tags {
+				certTags[k] = v
+			}
+			for _, key := range []string{"source", "path", "subject", "issuer"} {
+				if value, ok := certInfo[key].(string); ok && value != "" {
+					certTags[key] = value
+				}
+			}
+			if index, ok := certInfo["index"].(float64); ok {
+				certTags["index"] = fmt.Sprintf("%d", int(index))
+			}
+
+			fields := make(map[string]interface{})
+			if errMsg, ok := certInfo["error"].(string); ok && errMsg != "" {
+				fields["error"] = errMsg
+			} else {
+				for _, key := range []string{"days_left", "is_ca", "serial_number", "fingerprint"} {
+					if value, ok := certInfo[key]; ok {
+						fields[key] = value
+					}
+				}
+				if notAfter, ok := certInfo["not_after"].(string); ok {
+					if t, err := time.Parse(time.RFC3339, notAfter); err == nil {
+						fields["not_after"] = t.Unix()
+					}
+				}
+				if sans, ok := certInfo["sans"].([]interface{}); ok {
+					names := make([]string, 0, len(sans))
+					for _, san := range sans {
+						if name, ok := san.(string); ok {
+							names = append(names, name)
+						}
+					}
+					fields["sans"] = strings.Join(names, ",")
+				}
+			}
+
+			p := influxdb2.NewPoint(
+				"certificate",
+				certTags,
+				fields,
+				timestamp,
+			)
+			points = append(points, p)
+		}
+	}
+
 	// 创建网络指标点
 	if network, ok := metricsMap["network"].(map[string]interface{}); ok {
 		// 总体网络统计
 		netStats := make(map[string]interface{})
 
-		// 提取除interfaces外的字段
+		// 提取除interfaces及TCP统计外的字段
 		for key, value := range network {
-			if key != "interfaces" {
+			if key != "interfaces" && key != "tcp" && key != "tcp_states" {
 				netStats[key] = value
 			}
 		}
@@ -168,7 +782,34 @@ func (s *InfluxDBStorage) StoreMetrics(nodeID string, metrics interface{}) error
 				netStats,
 				timestamp,
 			)
-			s.writeAPI.WritePoint(p)
+			points = append(points, p)
+		}
+
+		// 创建TCP协议栈指标点
+		if tcpStats, ok := network["tcp"].(map[string]interface{}); ok && len(tcpStats) > 0 {
+			p := influxdb2.NewPoint(
+				"tcp",
+				tags,
+				tcpStats,
+				timestamp,
+			)
+			points = append(points, p)
+		}
+
+		// 创建TCP连接状态分布指标点，字段名为小写的状态名
+		if states, ok := network["tcp_states"].(map[string]interface{}); ok && len(states) > 0 {
+			stateFields := make(map[string]interface{}, len(states))
+			for state, count := range states {
+				stateFields[strings.ToLower(state)] = count
+			}
+
+			p := influxdb2.NewPoint(
+				"tcp_states",
+				tags,
+				stateFields,
+				timestamp,
+			)
+			points = append(points, p)
 		}
 
 		// 创建每个接口的网络指标点
@@ -187,83 +828,104 @@ func (s *InfluxDBStorage) StoreMetrics(nodeID string, metrics interface{}) error
 						ifaceInfo,
 						timestamp,
 					)
-					s.writeAPI.WritePoint(p)
+					points = append(points, p)
 				}
 			}
 		}
 	}
 
-	// 异步提交
-	s.writeAPI.Flush()
+	// 创建时钟偏差点，包括主控端计算的偏差及节点上报的时间同步状态，
+	// 使用接收时间写入，偏差过大的节点也能按实际时间查询
+	if skew, ok := metricsMap["clock_skew_seconds"].(float64); ok {
+		fields := map[string]interface{}{"skew_seconds": skew}
+		if skewed, ok := metricsMap["clock_skewed"].(bool); ok {
+			fields["skewed"] = skewed
+		}
+		_, restamped := metricsMap["original_timestamp"]
+		fields["restamped"] = restamped
 
-	// 记录详细的写入信息
-	var metricsTypes []string
-	pointCounts := make(map[string]int)
+		clockTags := make(map[string]string)
+		for k, v := range tags {
+			clockTags[k] = v
+		}
+		if timeSync, ok := metricsMap["time_sync"].(map[string]interface{}); ok {
+			if source, ok := timeSync["source"].(string); ok {
+				clockTags["source"] = source
+			}
+			for _, key := range []string{"synchronized", "ntp_enabled", "offset_ms", "stratum", "server", "root_delay_ms", "root_dispersion_ms", "leap_status"} {
+				if value, ok := timeSync[key]; ok {
+					fields[key] = value
+				}
+			}
+		}
 
-	if metricsMap["cpu"] != nil {
-		metricsTypes = append(metricsTypes, "cpu")
-		pointCounts["cpu"] = 1
-	}
-	if metricsMap["memory"] != nil {
-		metricsTypes = append(metricsTypes, "memory")
-		pointCounts["memory"] = 1
-	}
-	if diskMap, ok := metricsMap["disk"].(map[string]interface{}); ok {
-		metricsTypes = append(metricsTypes, "disk")
-		if partitions, hasParts := diskMap["partitions"].([]interface{}); hasParts {
-			pointCounts["disk"] = len(partitions)
-		} else {
-			pointCounts["disk"] = 1
+		clockTime := time.Now()
+		if receivedAt, ok := metricsMap["received_at"].(int64); ok {
+			clockTime = time.Unix(receivedAt, 0)
 		}
+		points = append(points, influxdb2.NewPoint("clock", clockTags, fields, clockTime))
 	}
-	if networkMap, ok := metricsMap["network"].(map[string]interface{}); ok {
-		metricsTypes = append(metricsTypes, "network")
-		pointCounts["network"] = 1
-		if interfaces, hasIfaces := networkMap["interfaces"].(map[string]interface{}); hasIfaces {
-			pointCounts["network_interfaces"] = len(interfaces)
+
+	// 创建文件完整性监控汇总点，具体的变化以事件形式写入event
+	if integrity, ok := metricsMap["integrity"].(map[string]interface{}); ok {
+		fields := make(map[string]interface{})
+		for _, key := range []string{"files", "added", "removed", "modified", "errors", "scan_duration_ms"} {
+			if value, ok := integrity[key]; ok {
+				fields[key] = value
+			}
 		}
+		points = append(points, influxdb2.NewPoint("file_integrity", tags, fields, timestamp))
 	}
 
-	log.Printf("[信息] InfluxDB写入开始 - 节点: %s, 时间戳: %s, 指标类型: %v",
-		nodeID,
-		timestamp.Format(time.RFC3339),
-		strings.Join(metricsTypes, ", "))
+	// 创建事件点，事件使用自身的发生时间
+	if events, ok := metricsMap["events"].([]interface{}); ok {
+		for _, item := range events {
+			event, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
 
-	// 记录写入的点数
-	totalPoints := 0
-	for _, count := range pointCounts {
-		totalPoints += count
-	}
+			eventTags := make(map[string]string)
+			for k, v := range tags {
+				eventTags[k] = v
+			}
+			for _, key := range []string{"type", "severity", "source"} {
+				if value, ok := event[key].(string); ok {
+					eventTags[key] = value
+				}
+			}
+			// 文件完整性等与文件相关的事件以路径作为标签，告警规则可以针对特定文件
+			if details, ok := event["details"].(map[string]interface{}); ok {
+				if path, ok := details["path"].(string); ok && path != "" {
+					eventTags["path"] = path
+				}
+			}
 
-	log.Printf("[详细] InfluxDB写入点数统计 - 节点: %s, 总点数: %d, 详情: %v",
-		nodeID,
-		totalPoints,
-		pointCounts)
+			fields := map[string]interface{}{"message": event["message"]}
+			if details, ok := event["details"]; ok {
+				if detailsJSON, err := json.Marshal(details); err == nil {
+					fields["details"] = string(detailsJSON)
+				}
+			}
 
-	// 捕获异步写入错误
-	errChan := make(chan error, 1)
+			eventTime := timestamp
+			if ts, ok := event["timestamp"].(string); ok {
+				if parsed, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+					eventTime = parsed
+				}
+			}
 
-	go func() {
-		for err := range s.writeAPI.Errors() {
-			errChan <- err
-			log.Printf("[错误] InfluxDB写入失败 - 节点: %s, 错误: %v", nodeID, err)
+			p := influxdb2.NewPoint(
+				"event",
+				eventTags,
+				fields,
+				eventTime,
+			)
+			points = append(points, p)
 		}
-	}()
-
-	// 等待一小段时间，让异步错误有机会被捕获
-	select {
-	case err := <-errChan:
-		return fmt.Errorf("InfluxDB写入错误: %w", err)
-	case <-time.After(100 * time.Millisecond):
-		// 继续执行
 	}
 
-	log.Printf("[信息] InfluxDB写入成功 - 节点: %s, 数据点: %d, 指标类型: %v",
-		nodeID,
-		totalPoints,
-		strings.Join(metricsTypes, ", "))
-
-	return nil
+	return points, timestamp
 }
 
 // GetNodeMetrics 获取指定节点在时间范围内的指标
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
@@ -32,30 +33,61 @@ func NewMemoryStorage(maxItems int) *MemoryStorage {
 }
 
 // StoreMetrics 存储节点指标数据
+// 按指标自带的时间戳排序，补发的历史数据插入到对应的位置
 func (s *MemoryStorage) StoreMetrics(nodeID string, metrics interface{}) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.insert(nodeID, metrics)
+	return nil
+}
+
+// StoreMetricsBatch 在同一次加锁中存储一批指标，读取方不会看到写入一半的批次
+func (s *MemoryStorage) StoreMetricsBatch(nodeID string, metrics []interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, m := range metrics {
+		s.insert(nodeID, m)
+	}
+	return nil
+}
+
+// insert 按时间戳插入一条指标，调用方需持有写锁
+func (s *MemoryStorage) insert(nodeID string, metrics interface{}) {
 	// 创建条目
 	entry := MetricsEntry{
-		Timestamp: time.Now(),
+		Timestamp: metricsTimestamp(metrics),
 		Data:      metrics,
 	}
 
-	// 如果节点不存在，初始化切片
-	if _, exists := s.data[nodeID]; !exists {
-		s.data[nodeID] = []MetricsEntry{}
-	}
-
-	// 添加新条目
-	s.data[nodeID] = append(s.data[nodeID], entry)
+	// 按时间戳插入，通常是追加到末尾
+	entries := s.data[nodeID]
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Timestamp.After(entry.Timestamp) })
+	entries = append(entries, MetricsEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
 
 	// 如果超出最大存储量，移除最旧的条目
-	if len(s.data[nodeID]) > s.maxItems {
-		s.data[nodeID] = s.data[nodeID][len(s.data[nodeID])-s.maxItems:]
+	if len(entries) > s.maxItems {
+		entries = entries[len(entries)-s.maxItems:]
 	}
+	s.data[nodeID] = entries
+}
 
-	return nil
+// metricsTimestamp 提取指标的采集时间，缺失或无法解析时使用当前时间
+func metricsTimestamp(metrics interface{}) time.Time {
+	if metricsMap, ok := metrics.(map[string]interface{}); ok {
+		switch ts := metricsMap["timestamp"].(type) {
+		case time.Time:
+			return ts
+		case string:
+			if parsed, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				return parsed
+			}
+		}
+	}
+	return time.Now()
 }
 
 // GetNodeMetrics 获取指定节点在时间范围内的指标
@@ -146,6 +146,11 @@ const (
 		deleted BOOLEAN NOT NULL DEFAULT FALSE
 	);
 	`
+
+	// 服务依赖的systemd单元，随节点配置下发给服务节点
+	addServiceSystemdUnitsColumn = `
+	ALTER TABLE services ADD COLUMN IF NOT EXISTS systemd_units JSONB;
+	`
 )
 
 // 数据库迁移列表
@@ -158,6 +163,7 @@ var migrationSchemas = []string{
 	createUserSessionsTable,
 	createAlertRulesTable,
 	createNotificationsTable,
+	addServiceSystemdUnitsColumn,
 }
 
 // MigrateDatabase 执行数据库迁移
@@ -264,7 +270,7 @@ func (p *PostgresDB) VerifyTableColumns(ctx context.Context) error {
 		},
 		{
 			tableName: "services",
-			columns:   []string{"id", "name", "description", "type", "critical_metrics", "created_time", "updated_time", "created_user", "updated_user", "deleted"},
+			columns:   []string{"id", "name", "description", "type", "critical_metrics", "systemd_units", "created_time", "updated_time", "created_user", "updated_user", "deleted"},
 		},
 		{
 			tableName: "service_nodes",